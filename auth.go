@@ -0,0 +1,341 @@
+package log
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	//ReadScope allows a key to query messages
+	ReadScope = "read"
+	//WriteScope allows a key to post messages
+	WriteScope = "write"
+)
+
+//APIKey is a token together with what it may be used for.
+//Empty Services or Levels allow every service or level.
+type APIKey struct {
+	Name     string   `json:"name"`
+	Token    string   `json:"token"`
+	Scopes   []string `json:"scopes"`
+	Services []string `json:"services"`
+	Levels   []string `json:"levels"`
+}
+
+type apiKeysFile struct {
+	Keys []*APIKey `json:"keys"`
+}
+
+//APIKeys are the keys that the server accepts, they are read from a JSON file that can be reloaded
+type APIKeys struct {
+	path  string
+	mutex sync.RWMutex
+	// keys by the hash of their token, so looking them up doesn't leak the tokens through timing
+	keys map[[sha256.Size]byte]*APIKey
+}
+
+type apiKeyContextKey struct{}
+
+//LoadAPIKeys reads the keys from the JSON file at path
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	keys := &APIKeys{path: path}
+	return keys, keys.Reload()
+}
+
+//Reload reads the file again, the previous keys stay in use if it is invalid
+func (k *APIKeys) Reload() error {
+	bytes, err := ioutil.ReadFile(k.path)
+	if err != nil {
+		return err
+	}
+	file := &apiKeysFile{}
+	if err := json.Unmarshal(bytes, file); err != nil {
+		return fmt.Errorf("%v: %v", k.path, err)
+	}
+
+	keys := map[[sha256.Size]byte]*APIKey{}
+	for i, key := range file.Keys {
+		if err := key.validate(); err != nil {
+			return fmt.Errorf("%v: key %v: %v", k.path, i+1, err)
+		}
+		hash := sha256.Sum256([]byte(key.Token))
+		if _, ok := keys[hash]; ok {
+			return fmt.Errorf("%v: key %v: the token is used by another key", k.path, i+1)
+		}
+		keys[hash] = key
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.keys = keys
+	return nil
+}
+
+func (k *APIKeys) lookup(token string) *APIKey {
+	if token == "" {
+		return nil
+	}
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.keys[sha256.Sum256([]byte(token))]
+}
+
+func (k *APIKey) validate() error {
+	if k.Token == "" {
+		return fmt.Errorf("token is missing")
+	}
+	if len(k.Scopes) == 0 {
+		return fmt.Errorf("scopes are missing")
+	}
+	for _, scope := range k.Scopes {
+		if scope != ReadScope && scope != WriteScope {
+			return fmt.Errorf("unknown scope %q, use %v or %v", scope, ReadScope, WriteScope)
+		}
+	}
+	return nil
+}
+
+func (k *APIKey) hasScope(scope string) bool {
+	return contains(k.Scopes, scope)
+}
+
+//allows reports if the key may access the messages of the service on the level,
+//a nil key (the server doesn't authenticate requests) allows everything
+func (k *APIKey) allows(service, level string) bool {
+	return k.allowsService(service) && (k == nil || len(k.Levels) == 0 || contains(k.Levels, level))
+}
+
+//allowsService reports if the key may access at least some levels of the service
+func (k *APIKey) allowsService(service string) bool {
+	return k == nil || len(k.Services) == 0 || contains(k.Services, service)
+}
+
+//allowsQuery reports if the service and level of a query are allowed, empty ones are filled in by the server
+//and the messages of the response are filtered instead
+func (k *APIKey) allowsQuery(service, level string) bool {
+	if service == "" {
+		return true
+	}
+	if level == "" {
+		return k.allowsService(service)
+	}
+	return k.allows(service, level)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//authError rejects a request, status is the http status to reject it with
+type authError struct {
+	status  int
+	message string
+}
+
+func (e *authError) Error() string {
+	return e.message
+}
+
+var (
+	errUnknownKey         = &authError{http.StatusUnauthorized, "the api key is missing or unknown"}
+	errMissingScope       = &authError{http.StatusForbidden, "the api key doesn't have the scope"}
+	errServiceNotAllowed  = &authError{http.StatusForbidden, "the api key doesn't allow the service of the client certificate"}
+	errMissingCertificate = &authError{http.StatusUnauthorized, "the client certificate is missing"}
+)
+
+//authorize only passes requests on to the handler that carry a key with the scope,
+//a verified client certificate restricts the key to the service named by its common name.
+//The key is added to the request context for the handler to check the services and levels against.
+func (s *Server) authorize(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := s.authenticate(bearerToken(r.Header.Get("Authorization")), r.TLS, scope)
+		if err != nil {
+			if err == errUnknownKey {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="log"`)
+			}
+			w.WriteHeader(err.status)
+			return
+		}
+		if key != nil {
+			r = r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key))
+		}
+		handler(w, r)
+	}
+}
+
+//authenticate returns the key of the token with the restrictions of the client certificate of the connection,
+//nil if the server neither authenticates requests nor was given a certificate
+func (s *Server) authenticate(token string, state *tls.ConnectionState, scope string) (*APIKey, *authError) {
+	var key *APIKey
+	if s.APIKeys != nil {
+		key = s.APIKeys.lookup(token)
+		if key == nil {
+			return nil, errUnknownKey
+		}
+		if !key.hasScope(scope) {
+			return nil, errMissingScope
+		}
+	}
+
+	if service, ok := certificateService(state); ok {
+		if !key.allowsService(service) {
+			return nil, errServiceNotAllowed
+		}
+		key = key.restrictedTo(service)
+	} else if s.RequireClientCertificates {
+		return nil, errMissingCertificate
+	}
+	return key, nil
+}
+
+//certificateService is the common name of the verified client certificate of the connection
+func certificateService(state *tls.ConnectionState) (string, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return state.VerifiedChains[0][0].Subject.CommonName, true
+}
+
+//restrictedTo returns a copy of the key that only allows the service, a nil key becomes a key for reading and writing the service
+func (k *APIKey) restrictedTo(service string) *APIKey {
+	restricted := &APIKey{Name: service, Scopes: []string{ReadScope, WriteScope}}
+	if k != nil {
+		restricted = &APIKey{Name: k.Name, Scopes: k.Scopes, Levels: k.Levels}
+	}
+	restricted.Services = []string{service}
+	return restricted
+}
+
+//requestKey returns the key that the request was authorized with, nil if the server doesn't authenticate requests
+func requestKey(r *http.Request) *APIKey {
+	key, _ := r.Context().Value(apiKeyContextKey{}).(*APIKey)
+	return key
+}
+
+//bearerToken is the token of the value of an authorization header
+func bearerToken(authorization string) string {
+	if len(authorization) < len("Bearer ") || !strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(authorization[len("Bearer "):])
+}
+
+func (k *APIKey) filterServiceMessages(service string, messages []*ServiceMessage) []*ServiceMessage {
+	if k == nil {
+		return messages
+	}
+	filtered := []*ServiceMessage{}
+	for _, message := range messages {
+		if k.allows(service, message.Level) {
+			filtered = append(filtered, message)
+		} else {
+			pools.ServiceMessages.Put(message)
+		}
+	}
+	return filtered
+}
+
+func (k *APIKey) filterCompleteMessages(messages []*CompleteMessage) []*CompleteMessage {
+	if k == nil {
+		return messages
+	}
+	filtered := []*CompleteMessage{}
+	for _, message := range messages {
+		if k.allows(message.Service, message.Level) {
+			filtered = append(filtered, message)
+		} else {
+			pools.CompleteMessages.Put(message)
+		}
+	}
+	return filtered
+}
+
+//filterServiceInfos leaves out the services and levels the key may not see, the statistics of the services only count the remaining levels
+func (k *APIKey) filterServiceInfos(services []*ServiceInfo) []*ServiceInfo {
+	if k == nil {
+		return services
+	}
+	filtered := []*ServiceInfo{}
+	for _, service := range services {
+		info := &ServiceInfo{Name: service.Name, Levels: k.filterLevelInfos(service.Name, service.Levels)}
+		if len(info.Levels) == 0 {
+			continue
+		}
+		for _, level := range info.Levels {
+			addLevelStatistics(info, level)
+		}
+		filtered = append(filtered, info)
+	}
+	return filtered
+}
+
+func (k *APIKey) filterLevelInfos(service string, levels []*LevelInfo) []*LevelInfo {
+	if k == nil {
+		return levels
+	}
+	filtered := []*LevelInfo{}
+	for _, level := range levels {
+		if k.allows(service, level.Name) {
+			filtered = append(filtered, level)
+		}
+	}
+	return filtered
+}
+
+func (k *APIKey) filterHistogramSeries(series []*HistogramSeries) []*HistogramSeries {
+	if k == nil {
+		return series
+	}
+	filtered := []*HistogramSeries{}
+	for _, s := range series {
+		if k.allows(s.Service, s.Level) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+//filterSearchGroups leaves out the messages the key may not see, groups without remaining matches are dropped
+func (k *APIKey) filterSearchGroups(groups []*SearchGroup) []*SearchGroup {
+	if k == nil {
+		return groups
+	}
+	filtered := []*SearchGroup{}
+	for _, group := range groups {
+		matches := map[int32]bool{}
+		for _, match := range group.Matches {
+			matches[match] = true
+		}
+		kept := &SearchGroup{}
+		for i, message := range group.Messages {
+			if !k.allows(message.Service, message.Level) {
+				pools.CompleteMessages.Put(message)
+				continue
+			}
+			if matches[int32(i)] {
+				kept.Matches = append(kept.Matches, int32(len(kept.Messages)))
+			}
+			kept.Messages = append(kept.Messages, message)
+		}
+		if len(kept.Matches) == 0 {
+			for _, message := range kept.Messages {
+				pools.CompleteMessages.Put(message)
+			}
+			continue
+		}
+		filtered = append(filtered, kept)
+	}
+	return filtered
+}
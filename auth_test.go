@@ -0,0 +1,92 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAPIKeys(t *testing.T) {
+	Convey("APIKeys", t, func() {
+		file, _ := ioutil.TempFile("", "api-keys")
+		defer os.Remove(file.Name())
+		write := func(content string) {
+			ioutil.WriteFile(file.Name(), []byte(content), 0600)
+		}
+		write(`{"keys": [
+			{"name": "reader", "token": "r", "scopes": ["read"]},
+			{"name": "api", "token": "w", "scopes": ["write"], "services": ["api"], "levels": ["error"]}
+		]}`)
+
+		keys, err := LoadAPIKeys(file.Name())
+		So(err, ShouldBeNil)
+		So(keys.lookup("r").Name, ShouldEqual, "reader")
+		So(keys.lookup("w").Name, ShouldEqual, "api")
+		So(keys.lookup("x"), ShouldBeNil)
+		So(keys.lookup(""), ShouldBeNil)
+
+		Convey("reloads the file", func() {
+			write(`{"keys": [{"name": "new", "token": "n", "scopes": ["read", "write"]}]}`)
+			So(keys.Reload(), ShouldBeNil)
+			So(keys.lookup("r"), ShouldBeNil)
+			So(keys.lookup("n").Name, ShouldEqual, "new")
+		})
+
+		Convey("keeps the previous keys if the file is invalid", func() {
+			for _, content := range []string{
+				`{"keys": [`,
+				`{"keys": [{"token": "n"}]}`,
+				`{"keys": [{"token": "n", "scopes": ["admin"]}]}`,
+				`{"keys": [{"scopes": ["read"]}]}`,
+				`{"keys": [{"token": "n", "scopes": ["read"]}, {"token": "n", "scopes": ["write"]}]}`,
+			} {
+				write(content)
+				So(keys.Reload(), ShouldNotBeNil)
+			}
+			So(keys.lookup("r").Name, ShouldEqual, "reader")
+		})
+	})
+
+	Convey("APIKey", t, func() {
+		key := &APIKey{Scopes: []string{ReadScope}, Services: []string{"api"}, Levels: []string{"error"}}
+
+		Convey("allows the services and levels it lists", func() {
+			So(key.allows("api", "error"), ShouldBeTrue)
+			So(key.allows("api", "standard"), ShouldBeFalse)
+			So(key.allows("db", "error"), ShouldBeFalse)
+			So(key.allowsQuery("", ""), ShouldBeTrue)
+			So(key.allowsQuery("api", ""), ShouldBeTrue)
+			So(key.allowsQuery("db", ""), ShouldBeFalse)
+			So((&APIKey{}).allows("db", "any"), ShouldBeTrue)
+			So((*APIKey)(nil).allows("db", "any"), ShouldBeTrue)
+		})
+
+		Convey("filters search groups and keeps the matches pointing at their messages", func() {
+			message := func(level, text string) *CompleteMessage {
+				return &CompleteMessage{Service: "api", Level: level, Message: &Message{Text: text}}
+			}
+			groups := key.filterSearchGroups([]*SearchGroup{
+				&SearchGroup{
+					Messages: []*CompleteMessage{message("standard", "a"), message("error", "b"), message("standard", "c"), message("error", "d")},
+					Matches:  []int32{1, 2, 3},
+				},
+				&SearchGroup{
+					Messages: []*CompleteMessage{message("error", "e"), message("standard", "f")},
+					Matches:  []int32{1},
+				},
+			})
+			So(len(groups), ShouldEqual, 1)
+			So(groups[0].Messages[0].Message.Text, ShouldEqual, "b")
+			So(groups[0].Messages[1].Message.Text, ShouldEqual, "d")
+			So(groups[0].Matches, ShouldResemble, []int32{0, 1})
+		})
+	})
+
+	Convey("bearerToken", t, func() {
+		So(bearerToken(""), ShouldEqual, "")
+		So(bearerToken("bearer abc"), ShouldEqual, "abc")
+		So(bearerToken("Basic abc"), ShouldEqual, "")
+	})
+}
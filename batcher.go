@@ -0,0 +1,174 @@
+package log
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIngestWindow     = time.Second
+	defaultMaxBatchMessages = 1000
+)
+
+//batcher collects the messages of the listeners into a block per service and level,
+//the blocks are flushed once per window or as soon as they hold maxMessages messages
+type batcher struct {
+	window      time.Duration
+	maxMessages int
+	flush       func(blocks []*Block)
+	mutex       sync.Mutex
+	blocks      map[string]*Block
+	// set by shutdown, messages that arrive later are dropped
+	closed bool
+	// the flushes of full blocks that add started
+	flushing sync.WaitGroup
+	stopped  chan struct{}
+	done     chan struct{}
+}
+
+func newBatcher(window time.Duration, maxMessages int, flush func(blocks []*Block)) *batcher {
+	b := &batcher{
+		window:      window,
+		maxMessages: maxMessages,
+		flush:       flush,
+		blocks:      map[string]*Block{},
+		stopped:     make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+//add the message to the block of its service and level, after shutdown the message is dropped
+func (b *batcher) add(service, level string, message *Message) {
+	if b == nil {
+		return
+	}
+	key := WriterKeyFor(service, level)
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return
+	}
+	block, ok := b.blocks[key]
+	if !ok {
+		block = &Block{Service: service, Level: level}
+		b.blocks[key] = block
+	}
+	block.Messages = append(block.Messages, message)
+	full := b.maxMessages > 0 && len(block.Messages) >= b.maxMessages
+	if full {
+		delete(b.blocks, key)
+		b.flushing.Add(1)
+	}
+	b.mutex.Unlock()
+
+	if full {
+		defer b.flushing.Done()
+		b.flush([]*Block{sealBlock(block)})
+	}
+}
+
+func (b *batcher) run() {
+	ticker := time.NewTicker(b.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flushAll()
+		case <-b.stopped:
+			b.flushAll()
+			close(b.done)
+			return
+		}
+	}
+}
+
+func (b *batcher) flushAll() {
+	b.mutex.Lock()
+	collected := b.blocks
+	b.blocks = map[string]*Block{}
+	b.mutex.Unlock()
+
+	if len(collected) == 0 {
+		return
+	}
+	b.flush(sealBlocks(collected))
+}
+
+//shutdown flushes the remaining blocks and stops the batcher
+func (b *batcher) shutdown() {
+	b.mutex.Lock()
+	b.closed = true
+	b.mutex.Unlock()
+	b.flushing.Wait()
+	close(b.stopped)
+	<-b.done
+}
+
+//sealBlock sorts the messages of the block by time, they arrive in the order the senders sent them,
+//and sets the time range of the block to the one of its messages
+func sealBlock(block *Block) *Block {
+	sort.SliceStable(block.Messages, func(i, j int) bool {
+		return block.Messages[i].Timestamp < block.Messages[j].Timestamp
+	})
+	block.StartTime = block.Messages[0].Timestamp
+	block.EndTime = block.Messages[len(block.Messages)-1].Timestamp
+	return block
+}
+
+//sealBlocks seals the blocks of the map, ordered by their keys
+func sealBlocks(blocksByKey map[string]*Block) []*Block {
+	keys := []string{}
+	for key := range blocksByKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	blocks := []*Block{}
+	for _, key := range keys {
+		blocks = append(blocks, sealBlock(blocksByKey[key]))
+	}
+	return blocks
+}
+
+//listenerBatcher is the batcher of the listeners, it is started with the first listener
+func (s *Server) listenerBatcher() *batcher {
+	s.batcherOnce.Do(func() {
+		window := s.IngestWindow
+		if window <= 0 {
+			window = defaultIngestWindow
+		}
+		s.batcher = newBatcher(window, defaultMaxBatchMessages, s.ingestListenerBlocks)
+	})
+	return s.batcher
+}
+
+//ingestListenerBlocks applies the rate limits to every service on its own, the listeners can't ask their senders to retry,
+//so the blocks of services that are over their limits are dropped
+func (s *Server) ingestListenerBlocks(blocks []*Block) {
+	if s.RateLimits == nil {
+		s.ingest(blocks)
+		return
+	}
+	byService := map[string][]*Block{}
+	for _, block := range blocks {
+		byService[block.Service] = append(byService[block.Service], block)
+	}
+	accepted := []*Block{}
+	for _, block := range blocks {
+		serviceBlocks, ok := byService[block.Service]
+		if !ok {
+			continue
+		}
+		delete(byService, block.Service)
+		if _, ok := s.RateLimits.admit(requestSizes(serviceBlocks)); !ok {
+			for _, dropped := range serviceBlocks {
+				metrics.droppedMessages.add(float64(len(dropped.Messages)), dropped.Service)
+			}
+			continue
+		}
+		accepted = append(accepted, serviceBlocks...)
+	}
+	s.ingest(accepted)
+}
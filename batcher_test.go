@@ -0,0 +1,90 @@
+package log
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBatcher(t *testing.T) {
+	Convey("batcher", t, func() {
+		mutex := sync.Mutex{}
+		flushed := [][]*Block{}
+		flush := func(blocks []*Block) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			flushed = append(flushed, blocks)
+		}
+
+		Convey("collects the messages into sorted blocks per service and level", func() {
+			b := newBatcher(time.Hour, 0, flush)
+			b.add("api", "standard", &Message{Text: "Foo", Timestamp: 20})
+			b.add("api", "standard", &Message{Text: "Bar", Timestamp: 10})
+			b.add("api", "error", &Message{Text: "Baz", Timestamp: 15})
+			b.shutdown()
+
+			So(len(flushed), ShouldEqual, 1)
+			So(len(flushed[0]), ShouldEqual, 2)
+			So(flushed[0][0].Level, ShouldEqual, "error")
+			standard := flushed[0][1]
+			So(standard.StartTime, ShouldEqual, 10)
+			So(standard.EndTime, ShouldEqual, 20)
+			So(standard.Messages[0].Text, ShouldEqual, "Bar")
+			So(standard.Valid(), ShouldBeTrue)
+		})
+
+		Convey("flushes full blocks right away", func() {
+			b := newBatcher(time.Hour, 2, flush)
+			b.add("api", "standard", &Message{Text: "Foo", Timestamp: 1})
+			b.add("api", "standard", &Message{Text: "Bar", Timestamp: 2})
+			So(len(flushed), ShouldEqual, 1)
+			b.add("api", "standard", &Message{Text: "Baz", Timestamp: 3})
+			b.shutdown()
+			So(len(flushed), ShouldEqual, 2)
+		})
+
+		Convey("flushes once per window", func() {
+			b := newBatcher(10*time.Millisecond, 0, flush)
+			b.add("api", "standard", &Message{Text: "Foo", Timestamp: 1})
+			time.Sleep(50 * time.Millisecond)
+			mutex.Lock()
+			So(len(flushed), ShouldEqual, 1)
+			mutex.Unlock()
+			b.shutdown()
+		})
+
+		Convey("drops the messages that arrive after shutdown", func() {
+			b := newBatcher(time.Hour, 0, flush)
+			b.shutdown()
+			b.add("api", "standard", &Message{Text: "Late", Timestamp: 1})
+			So(flushed, ShouldBeEmpty)
+
+			var stopped *batcher
+			So(func() { stopped.add("api", "standard", &Message{Text: "Late", Timestamp: 1}) }, ShouldNotPanic)
+		})
+	})
+}
+
+func TestIngestListenerBlocks(t *testing.T) {
+	Convey("ingestListenerBlocks drops the blocks of services over their limits", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		s.RateLimits.serviceLimits = map[string]RateLimit{"noisy": RateLimit{MessagesPerSecond: 1}}
+		s.RateLimits.admit(map[string]requestSize{"noisy": requestSize{messages: 1}})
+
+		before := metrics.droppedMessages.value("noisy")
+		s.ingestListenerBlocks([]*Block{
+			&Block{Service: "noisy", Level: "standard", StartTime: 1, EndTime: 2, Messages: []*Message{&Message{Timestamp: 1}, &Message{Timestamp: 2}}},
+			&Block{Service: "quiet", Level: "standard", StartTime: 1, EndTime: 1, Messages: []*Message{&Message{Text: "Foo", Timestamp: 1}}},
+		})
+		time.Sleep(10 * time.Millisecond)
+
+		So(metrics.droppedMessages.value("noisy")-before, ShouldEqual, 2)
+		So(len(s.Reader.GetServiceLevelMessagesInTimeRange(0, 10, "quiet", "standard")), ShouldEqual, 1)
+	})
+	os.RemoveAll(pathPrefix)
+}
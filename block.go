@@ -56,13 +56,33 @@ func (b *Block) Valid() bool {
 	if len(b.Messages) == 0 {
 		return false
 	}
-	if b.Service == "" || b.Level == "" {
+	if !ValidName(b.Service) || !ValidName(b.Level) {
 		return false
 	}
 	return true
 
 }
 
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+//ValidName checks that a service or level name is safe to use as a directory name,
+//names without a slash also keep the keys of WriterKeyFor unique
+func ValidName(name string) bool {
+	return name != "." && name != ".." && namePattern.MatchString(name)
+}
+
+var invalidNameCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+//sanitizeName replaces the characters that ValidName doesn't allow with underscores,
+//it returns "" for names that are still invalid like "" or ".."
+func sanitizeName(name string) string {
+	name = invalidNameCharacters.ReplaceAllString(name, "_")
+	if !ValidName(name) {
+		return ""
+	}
+	return name
+}
+
 // ReadFromFile uses the start_time and end_time of itself to read the appropriate file and fill itself with the stored info
 func (b *Block) ReadFromFile() (err error) {
 	byteArray, err := ioutil.ReadFile(b.path() + "/" + b.fileName())
@@ -36,3 +36,19 @@ func TestReduceToTimeRange(t *testing.T) {
 		So(block.EndTime, ShouldEqual, 17000)
 	})
 }
+
+func TestBlockValid(t *testing.T) {
+	Convey("Valid", t, func() {
+		block := &Block{StartTime: 1, EndTime: 2, Service: "api-v2.eu_1", Level: "error", Messages: []*Message{{Timestamp: 1}}}
+		So(block.Valid(), ShouldBeTrue)
+
+		for _, name := range []string{"", ".", "..", "../x", "a/b", "a b", "a\\b"} {
+			invalid := block.Copy()
+			invalid.Service = name
+			So(invalid.Valid(), ShouldBeFalse)
+			invalid = block.Copy()
+			invalid.Level = name
+			So(invalid.Valid(), ShouldBeFalse)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"sync"
+	"sync/atomic"
 )
 
 var cacheMessageCountLimit = 1000000
@@ -12,7 +13,8 @@ type Cache struct {
 	mutex           sync.Mutex
 	inChannel       chan *Block
 	shutdownChannel chan struct{}
-	messageCounter  int
+	// only changed by the goroutine that listens for blocks, accessed atomically so it can be read for the metrics
+	messageCounter int64
 }
 
 //NewCache ...
@@ -38,14 +40,17 @@ func (c *Cache) AddBlock(b *Block) {
 	c.InChannel() <- b
 }
 
-//GetBlock for service and level
+//GetBlock for service and level, the returned block doesn't share its message slice with the cached blocks
 func (c *Cache) GetBlock(startTime, endTime int64, service, level string) *Block {
 	blocks := []*Block{}
+	c.mutex.Lock()
 	for _, block := range c.blocks[service][level] {
 		if block.IsInTimeRange(startTime, endTime) {
-			blocks = append(blocks, block)
+			// the cached blocks are reduced and merged below, so work on copies of them
+			blocks = append(blocks, block.Copy())
 		}
 	}
+	c.mutex.Unlock()
 	if len(blocks) == 0 {
 		return nil
 	}
@@ -59,6 +64,7 @@ func (c *Cache) GetBlock(startTime, endTime int64, service, level string) *Block
 	}
 
 	mergedBlock := blocks[0].Copy()
+	mergedBlock.Messages = append([]*Message{}, mergedBlock.Messages...)
 	for i := 1; i < len(blocks); i++ {
 		mergedBlock.Merge(blocks[i])
 	}
@@ -68,6 +74,8 @@ func (c *Cache) GetBlock(startTime, endTime int64, service, level string) *Block
 
 //GetLevels for a given service
 func (c *Cache) GetLevels(service string) (levels []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	for level := range c.blocks[service] {
 		levels = append(levels, level)
 	}
@@ -76,6 +84,8 @@ func (c *Cache) GetLevels(service string) (levels []string) {
 
 //GetServices that have messages in the cache
 func (c *Cache) GetServices() (services []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	for serviceName := range c.blocks {
 		services = append(services, serviceName)
 	}
@@ -100,7 +110,9 @@ loop:
 }
 
 func (c *Cache) handleAddBlock(b *Block) {
-	c.messageCounter += len(b.Messages)
+	atomic.AddInt64(&c.messageCounter, int64(len(b.Messages)))
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
 	//make sure the inner map is initialized as well
 	if c.blocks[b.Service] == nil {
@@ -112,15 +124,17 @@ func (c *Cache) handleAddBlock(b *Block) {
 	c.cleanCache()
 }
 
+//cleanCache evicts the oldest block of every level once the cache is full, the caller holds the mutex
 func (c *Cache) cleanCache() {
-	if c.messageCounter > cacheMessageCountLimit {
+	if atomic.LoadInt64(&c.messageCounter) > int64(cacheMessageCountLimit) {
 		for serviceName, levelToBlockMap := range c.blocks {
 			for level, blocks := range levelToBlockMap {
 				if len(blocks) == 0 {
 					continue
 				}
 				newBlocks := []*Block{}
-				c.messageCounter -= len(blocks[0].Messages)
+				atomic.AddInt64(&c.messageCounter, -int64(len(blocks[0].Messages)))
+				metrics.cacheEvictedMessages.add(float64(len(blocks[0].Messages)))
 				for i := 1; i < len(blocks); i++ {
 					newBlocks = append(newBlocks, blocks[i])
 				}
@@ -129,3 +143,8 @@ func (c *Cache) cleanCache() {
 		}
 	}
 }
+
+//messageCount of the cached blocks
+func (c *Cache) messageCount() int64 {
+	return atomic.LoadInt64(&c.messageCounter)
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//maxBackoffBatches is how many batches the cache holds while backing off, messages beyond them are dropped
+const maxBackoffBatches = 10
+
+//backoff remembers until when the server asked the client to wait with its next push
+type backoff struct {
+	mutex   sync.Mutex
+	until   time.Time
+	dropped int
+	now     func() time.Time
+}
+
+func newBackoff() *backoff {
+	return &backoff{now: time.Now}
+}
+
+//wait extends the backoff to at least the duration from now
+func (b *backoff) wait(duration time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if until := b.now().Add(duration); until.After(b.until) {
+		b.until = until
+	}
+}
+
+func (b *backoff) active() bool {
+	if b == nil {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.now().Before(b.until)
+}
+
+//drop counts a message that didn't fit into the cache while backing off
+func (b *backoff) drop() {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.dropped++
+}
+
+func (b *backoff) droppedAndReset() int {
+	if b == nil {
+		return 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	dropped := b.dropped
+	b.dropped = 0
+	return dropped
+}
+
+//shouldBackOff reports if the server asked the client to retry later
+func shouldBackOff(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+//retryAfter reads the seconds or the date of a Retry-After header, fallback is used without a valid one
+func retryAfter(header string, now time.Time, fallback time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if date.Before(now) {
+			return 0
+		}
+		return date.Sub(now)
+	}
+	return fallback
+}
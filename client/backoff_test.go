@@ -0,0 +1,88 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmorten/log"
+	"github.com/gogo/protobuf/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryAfter(t *testing.T) {
+	Convey("retryAfter", t, func() {
+		now := time.Date(2018, 5, 17, 14, 30, 0, 0, time.UTC)
+		So(retryAfter("120", now, time.Second), ShouldEqual, 2*time.Minute)
+		So(retryAfter("Thu, 17 May 2018 14:31:00 GMT", now, time.Second), ShouldEqual, time.Minute)
+		So(retryAfter("Thu, 17 May 2018 14:29:00 GMT", now, time.Second), ShouldEqual, 0)
+		So(retryAfter("", now, time.Second), ShouldEqual, time.Second)
+		So(retryAfter("soon", now, time.Second), ShouldEqual, time.Second)
+	})
+}
+
+func TestClientBackoff(t *testing.T) {
+	Convey("Client backoff", t, func() {
+		mutex := sync.Mutex{}
+		rejections := 1
+		texts := []string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if rejections > 0 {
+				rejections--
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			bytes, _ := ioutil.ReadAll(r.Body)
+			request := &log.PostRequest{}
+			proto.Unmarshal(bytes, request)
+			for _, block := range request.Blocks {
+				for _, message := range block.Messages {
+					texts = append(texts, message.Text)
+				}
+			}
+		}))
+		defer server.Close()
+
+		config := NewConfig()
+		config.URL = server.URL
+		config.SyncTime = time.Hour
+		config.MaxBatchMessages = 2
+		client, err := NewClientWithConfig(*config)
+		So(err, ShouldBeNil)
+		client.Log("first")
+		client.Log("second")
+		client.waitForRequests()
+
+		Convey("keeps the rejected messages until the server accepts them again", func() {
+			So(client.backoff.active(), ShouldBeTrue)
+			client.Log("third")
+			client.Commit()
+			So(texts, ShouldBeEmpty)
+			messageCount, _ := client.Cache.Size()
+			So(messageCount, ShouldEqual, 3)
+
+			client.backoff.now = func() time.Time { return time.Now().Add(time.Minute) }
+			client.Shutdown()
+			So(texts, ShouldResemble, []string{"first", "second", "third"})
+		})
+
+		Convey("drops messages once the cache holds the backlog", func() {
+			for i := 0; i < 2*maxBackoffBatches; i++ {
+				client.Log("more")
+			}
+			messageCount, _ := client.Cache.Size()
+			So(messageCount, ShouldEqual, 2*maxBackoffBatches)
+
+			client.backoff.now = func() time.Time { return time.Now().Add(time.Minute) }
+			client.Shutdown()
+			So(len(texts), ShouldEqual, 2*maxBackoffBatches+1)
+			So(texts, ShouldContain, "dropped 2 messages while the server asked to back off")
+		})
+	})
+}
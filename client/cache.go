@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/alexmorten/log"
+	"github.com/gogo/protobuf/proto"
 )
 
 //Cache for client,
@@ -11,7 +12,9 @@ import (
 // tend to happen in bunches, with mixed log levels
 type Cache struct {
 	sync.Mutex
-	messages map[string][]*log.Message
+	messages     map[string][]*log.Message
+	messageCount int
+	byteCount    int
 }
 
 //NewCache with defaults
@@ -27,6 +30,29 @@ func (c *Cache) AddMessage(level string, message *log.Message) {
 	defer c.Unlock()
 
 	c.messages[level] = append(c.messages[level], message)
+	c.messageCount++
+	c.byteCount += proto.Size(message)
+}
+
+//Size returns the number of cached messages and their encoded size in bytes
+func (c *Cache) Size() (messageCount, byteCount int) {
+	c.Lock()
+	defer c.Unlock()
+	return c.messageCount, c.byteCount
+}
+
+//Requeue puts messages that couldn't be sent back into the cache, in front of the messages that were added since
+func (c *Cache) Requeue(messagesMap map[string][]*log.Message) {
+	c.Lock()
+	defer c.Unlock()
+
+	for level, messages := range messagesMap {
+		c.messages[level] = append(append([]*log.Message{}, messages...), c.messages[level]...)
+		c.messageCount += len(messages)
+		for _, message := range messages {
+			c.byteCount += proto.Size(message)
+		}
+	}
 }
 
 //GetCachedMessagesAndReset gets the cache and resets it to an empty cache
@@ -35,5 +61,7 @@ func (c *Cache) GetCachedMessagesAndReset() map[string][]*log.Message {
 	defer c.Unlock()
 	messages := c.messages
 	c.messages = make(map[string][]*log.Message)
+	c.messageCount = 0
+	c.byteCount = 0
 	return messages
 }
@@ -33,13 +33,30 @@ func TestCache(t *testing.T) {
 		cache.AddMessage("level2", m3)
 		cache.AddMessage("level3", m4)
 
+		messageCount, byteCount := cache.Size()
+		So(messageCount, ShouldEqual, 4)
+		So(byteCount, ShouldBeGreaterThan, 0)
+
 		messagesMap := cache.GetCachedMessagesAndReset()
 
 		So(messagesMap, ShouldNotBeEmpty)
 		So(cache.messages, ShouldBeEmpty)
+		messageCount, byteCount = cache.Size()
+		So(messageCount, ShouldEqual, 0)
+		So(byteCount, ShouldEqual, 0)
 		So(messagesMap["level1"], ShouldResemble, []*log.Message{m1, m2})
 		So(messagesMap["level2"], ShouldResemble, []*log.Message{m3})
 		So(messagesMap["level3"], ShouldResemble, []*log.Message{m4})
 
+		Convey("requeued messages are put in front of the new ones", func() {
+			cache.AddMessage("level1", m3)
+			cache.Requeue(messagesMap)
+
+			messageCount, _ := cache.Size()
+			So(messageCount, ShouldEqual, 5)
+			requeued := cache.GetCachedMessagesAndReset()
+			So(requeued["level1"], ShouldResemble, []*log.Message{m1, m2, m3})
+			So(requeued["level3"], ShouldResemble, []*log.Message{m4})
+		})
 	})
 }
@@ -2,8 +2,12 @@ package client
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alexmorten/log"
@@ -15,68 +19,155 @@ type Client struct {
 	Config          *Config
 	Cache           *Cache
 	shutdownChannel chan struct{}
+	sampler         *sampler
+	// one slot per request that is allowed to run at the same time
+	requestSlots chan struct{}
+	httpClient   *http.Client
+	backoff      *backoff
+	// sends the blocks instead of the http client if the config has a GRPCAddr
+	grpc *grpcTransport
 }
 
 //NewClient with default config
 func NewClient() *Client {
-	c := &Client{
-		Config:          NewConfig(),
-		Cache:           NewCache(),
-		shutdownChannel: make(chan struct{}),
-	}
-	go c.pushMessagesPeriodically()
+	// the default config has no certificate files, so the client can't fail
+	c, _ := newClient(NewConfig())
 	return c
 }
 
-//NewClientWithConfig with given config
-func NewClientWithConfig(config Config) *Client {
+//NewClientWithConfig with given config, it fails if the certificate files of the config can't be loaded
+//or the client can't connect to its GRPCAddr
+func NewClientWithConfig(config Config) (*Client, error) {
+	return newClient(&config)
+}
+
+func newClient(config *Config) (*Client, error) {
+	maxInFlightRequests := config.MaxInFlightRequests
+	if maxInFlightRequests < 1 {
+		maxInFlightRequests = 1
+	}
 	c := &Client{
-		Config:          &config,
+		Config:          config,
 		Cache:           NewCache(),
 		shutdownChannel: make(chan struct{}),
+		sampler:         newSampler(),
+		requestSlots:    make(chan struct{}, maxInFlightRequests),
+		httpClient:      http.DefaultClient,
+		backoff:         newBackoff(),
+	}
+	if config.GRPCAddr != "" {
+		transport, err := newGRPCTransport(config)
+		if err != nil {
+			return nil, err
+		}
+		c.grpc = transport
+	} else {
+		transport, err := log.ClientTLSTransport(config.CAFile, config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if transport != nil {
+			c.httpClient = &http.Client{Transport: transport}
+		}
 	}
 	go c.pushMessagesPeriodically()
-	return c
+	return c, nil
 }
 
 //LogMessage writes log message on any given level
 func (c *Client) LogMessage(level, message string) {
-	if level == "" || message == "" {
-		panic("both level and message should be set when writing a message")
-	}
-	m := &log.Message{
-		Text:      message,
-		Timestamp: time.Now().Unix(),
-	}
-	c.Cache.AddMessage(level, m)
+	c.addMessage(level, message, nil)
+}
+
+//LogMessageContext writes log message on any given level,
+// the trace, span and request ids found in ctx are stored as message fields
+func (c *Client) LogMessageContext(ctx context.Context, level, message string) {
+	c.addMessage(level, message, c.Config.FieldsFromContext(ctx))
 }
 
 //Log standard Message
 func (c *Client) Log(messageArgs ...interface{}) {
-	c.LogMessage("standard", constructMessage(messageArgs...))
+	c.LogMessage("standard", FormatMessage(messageArgs...))
 }
 
 //LogError Message
 func (c *Client) LogError(messageArgs ...interface{}) {
-	c.LogMessage("error", constructMessage(messageArgs...))
+	c.LogMessage("error", FormatMessage(messageArgs...))
 }
 
 //LogWarn standard Message
 func (c *Client) LogWarn(messageArgs ...interface{}) {
-	c.LogMessage("warning", constructMessage(messageArgs...))
+	c.LogMessage("warning", FormatMessage(messageArgs...))
+}
+
+//LogContext standard Message with the ids found in ctx
+func (c *Client) LogContext(ctx context.Context, messageArgs ...interface{}) {
+	c.LogMessageContext(ctx, "standard", FormatMessage(messageArgs...))
 }
 
-//Commit the cache
+//LogErrorContext Message with the ids found in ctx
+func (c *Client) LogErrorContext(ctx context.Context, messageArgs ...interface{}) {
+	c.LogMessageContext(ctx, "error", FormatMessage(messageArgs...))
+}
+
+//LogWarnContext Message with the ids found in ctx
+func (c *Client) LogWarnContext(ctx context.Context, messageArgs ...interface{}) {
+	c.LogMessageContext(ctx, "warning", FormatMessage(messageArgs...))
+}
+
+//Commit the cache, unless the server asked the client to back off
 func (c *Client) Commit() {
 	c.pushMessages()
 }
 
+func (c *Client) addMessage(level, message string, fields map[string]string) {
+	if level == "" || message == "" {
+		panic("both level and message should be set when writing a message")
+	}
+	if c.sampler != nil && !c.sampler.allow(level, c.Config.SamplingRules) {
+		return
+	}
+	backingOff := c.backoff.active()
+	if backingOff && c.backlogFull() {
+		c.backoff.drop()
+		return
+	}
+	m := &log.Message{
+		Text:      message,
+		Timestamp: time.Now().Unix(),
+		Fields:    fields,
+	}
+	c.Cache.AddMessage(level, m)
+	if !backingOff && c.batchFull() {
+		c.pushMessagesInBackground()
+	}
+}
+
+func (c *Client) batchFull() bool {
+	messageCount, byteCount := c.Cache.Size()
+	if c.Config.MaxBatchMessages > 0 && messageCount >= c.Config.MaxBatchMessages {
+		return true
+	}
+	return c.Config.MaxBatchBytes > 0 && byteCount >= c.Config.MaxBatchBytes
+}
+
+//backlogFull reports if the cache holds maxBackoffBatches batches
+func (c *Client) backlogFull() bool {
+	messageCount, byteCount := c.Cache.Size()
+	if c.Config.MaxBatchMessages > 0 && messageCount >= c.Config.MaxBatchMessages*maxBackoffBatches {
+		return true
+	}
+	return c.Config.MaxBatchBytes > 0 && byteCount >= c.Config.MaxBatchBytes*maxBackoffBatches
+}
+
 func (c *Client) pushMessagesPeriodically() {
 	ticker := time.NewTicker(c.Config.SyncTime)
 loop:
 	for {
 		select {
 		case <-ticker.C:
+			c.addSamplingSummaries()
+			c.addBackoffSummary()
 			c.pushMessages()
 		case <-c.shutdownChannel:
 			break loop
@@ -86,14 +177,91 @@ loop:
 	ticker.Stop()
 }
 
-//Shutdown the Client
+//Shutdown the Client, the cached messages are sent even if the server asked the client to back off
 func (c *Client) Shutdown() {
 	c.shutdownChannel <- struct{}{}
-	c.Commit()
+	c.addSamplingSummaries()
+	c.addBackoffSummary()
+	c.acquireRequestSlot()
+	c.sendMessages(c.Cache.GetCachedMessagesAndReset(), false)
+	c.releaseRequestSlot()
+	c.waitForRequests()
+	c.grpc.close()
+}
+
+// summaries bypass the sampling rules, they are added straight to the cache
+func (c *Client) addSamplingSummaries() {
+	if c.sampler == nil {
+		return
+	}
+	for level, count := range c.sampler.suppressedAndReset() {
+		c.Cache.AddMessage(level, &log.Message{
+			Text:      fmt.Sprintf("sampling suppressed %v messages on level %v", count, level),
+			Timestamp: time.Now().Unix(),
+			Fields:    map[string]string{SuppressedMessagesField: strconv.Itoa(count)},
+		})
+	}
+}
+
+// the summary of the messages dropped while backing off is added once the backoff is over
+func (c *Client) addBackoffSummary() {
+	if c.backoff.active() {
+		return
+	}
+	if dropped := c.backoff.droppedAndReset(); dropped > 0 {
+		c.Cache.AddMessage("warning", &log.Message{
+			Text:      fmt.Sprintf("dropped %v messages while the server asked to back off", dropped),
+			Timestamp: time.Now().Unix(),
+			Fields:    map[string]string{SuppressedMessagesField: strconv.Itoa(dropped)},
+		})
+	}
 }
 
 func (c *Client) pushMessages() {
+	if c.backoff.active() {
+		return
+	}
+	c.acquireRequestSlot()
+	defer c.releaseRequestSlot()
+	c.sendMessages(c.Cache.GetCachedMessagesAndReset(), true)
+}
+
+// blocks while all request slots are taken, so a slow server slows down logging
+// instead of letting requests pile up
+func (c *Client) pushMessagesInBackground() {
+	c.acquireRequestSlot()
 	messagesMap := c.Cache.GetCachedMessagesAndReset()
+	go func() {
+		defer c.releaseRequestSlot()
+		c.sendMessages(messagesMap, true)
+	}()
+}
+
+func (c *Client) acquireRequestSlot() {
+	if c.requestSlots != nil {
+		c.requestSlots <- struct{}{}
+	}
+}
+
+func (c *Client) releaseRequestSlot() {
+	if c.requestSlots != nil {
+		<-c.requestSlots
+	}
+}
+
+// takes every request slot, which is only possible once all running requests are done
+func (c *Client) waitForRequests() {
+	for i := 0; i < cap(c.requestSlots); i++ {
+		c.acquireRequestSlot()
+	}
+	for i := 0; i < cap(c.requestSlots); i++ {
+		c.releaseRequestSlot()
+	}
+}
+
+//sendMessages posts the messages to the server, if the server asks the client to back off
+//they are put back into the cache when requeue is set
+func (c *Client) sendMessages(messagesMap map[string][]*log.Message, requeue bool) {
 	if len(messagesMap) == 0 {
 		return
 	}
@@ -115,26 +283,91 @@ func (c *Client) pushMessages() {
 	if len(blocks) == 0 {
 		return
 	}
+	var backOff bool
+	var wait time.Duration
+	if c.grpc != nil {
+		backOff, wait = c.grpc.push(blocks, c.Config.SyncTime)
+	} else {
+		backOff, wait = c.postBlocks(blocks)
+	}
+	if backOff {
+		c.backoff.wait(wait)
+		if requeue {
+			c.Cache.Requeue(messagesMap)
+		}
+		c.printError("backing off for", wait)
+	}
+}
+
+//postBlocks posts the blocks to the server, it returns how long to back off if the server asks for it
+func (c *Client) postBlocks(blocks []*log.Block) (bool, time.Duration) {
 	request := &log.PostRequest{
 		Blocks: blocks,
 	}
 	byteArr, err := proto.Marshal(request)
 	if err != nil {
-		fmt.Println(err)
+		c.printError(err)
+	}
+	if c.Config.Gzip {
+		byteArr, err = gzipBytes(byteArr)
+		if err != nil {
+			c.printError(err)
+			return false, 0
+		}
 	}
-	reader := bytes.NewReader(byteArr)
-	resp, err := http.Post(c.Config.URL, "application/proto", reader)
+	httpRequest, err := http.NewRequest(http.MethodPost, messagesURL(c.Config.URL), bytes.NewReader(byteArr))
 	if err != nil {
-		fmt.Println(err)
-		return
+		c.printError(err)
+		return false, 0
+	}
+	httpRequest.Header.Set("Content-Type", "application/proto")
+	if c.Config.Token != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+c.Config.Token)
+	}
+	if c.Config.Gzip {
+		httpRequest.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		c.printError(err)
+		return false, 0
+	}
+	resp.Body.Close()
+	if shouldBackOff(resp.StatusCode) {
+		c.printError(resp.StatusCode, "was returned")
+		return true, retryAfter(resp.Header.Get("Retry-After"), time.Now(), c.Config.SyncTime)
 	}
 	if resp.StatusCode != http.StatusOK {
-		fmt.Println(resp.StatusCode, "was returned") //TODO make this a proper log
+		c.printError(resp.StatusCode, "was returned")
+	}
+	return false, 0
+}
+
+//printError reports an error of the client to the ErrorOutput of its config
+func (c *Client) printError(args ...interface{}) {
+	fmt.Fprintln(c.Config.errorOutput(), args...)
+}
+
+//messagesURL is the endpoint of the server that blocks are posted to
+func messagesURL(serverURL string) string {
+	return strings.TrimSuffix(serverURL, "/") + log.APIPrefix + "/messages"
+}
+
+func gzipBytes(byteArr []byte) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	if _, err := writer.Write(byteArr); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
 	}
+	return buffer.Bytes(), nil
 }
 
+//FormatMessage joins the arguments like the Log functions do,
 // we want spaces between each argument, but no new line
-func constructMessage(args ...interface{}) string {
+func FormatMessage(args ...interface{}) string {
 	message := ""
 	for index, item := range args {
 		message += fmt.Sprint(item)
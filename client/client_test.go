@@ -1,7 +1,18 @@
 package client
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/alexmorten/log"
+	"github.com/gogo/protobuf/proto"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -24,3 +35,181 @@ func TestClient(t *testing.T) {
 		So(messagesMap["standard"][1].Text, ShouldResemble, "Bla Blap")
 	})
 }
+
+func TestClientContext(t *testing.T) {
+	Convey("Client with context", t, func() {
+		cache := NewCache()
+		client := &Client{
+			Cache:           cache,
+			Config:          NewConfig(),
+			shutdownChannel: make(chan struct{}),
+		}
+
+		ctx := WithTraceID(context.Background(), "trace1")
+		ctx = WithRequestID(ctx, "request1")
+		client.LogErrorContext(ctx, "Foo")
+		client.LogContext(context.Background(), "Bar")
+
+		messagesMap := client.Cache.GetCachedMessagesAndReset()
+		So(messagesMap["error"][0].Fields, ShouldResemble, map[string]string{
+			log.TraceIDField:   "trace1",
+			log.RequestIDField: "request1",
+		})
+		So(messagesMap["standard"][0].Fields, ShouldBeNil)
+
+		Convey("uses custom extractors", func() {
+			client.Config.ContextExtractors = map[string]ContextExtractor{
+				log.TraceIDField: func(ctx context.Context) string { return "custom" },
+			}
+			client.LogWarnContext(context.Background(), "Baz")
+
+			messagesMap := client.Cache.GetCachedMessagesAndReset()
+			So(messagesMap["warning"][0].Fields, ShouldResemble, map[string]string{log.TraceIDField: "custom"})
+		})
+	})
+}
+
+func TestClientBatchLimits(t *testing.T) {
+	Convey("Client batch limits", t, func() {
+		mutex := sync.Mutex{}
+		requests := []*log.PostRequest{}
+		authorizations := []string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != log.APIPrefix+"/messages" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			body := r.Body
+			if r.Header.Get("Content-Encoding") == "gzip" {
+				body, _ = gzip.NewReader(r.Body)
+			}
+			bytes, _ := ioutil.ReadAll(body)
+			request := &log.PostRequest{}
+			proto.Unmarshal(bytes, request)
+			mutex.Lock()
+			requests = append(requests, request)
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+			mutex.Unlock()
+		}))
+		defer server.Close()
+
+		config := NewConfig()
+		config.URL = server.URL
+		config.SyncTime = time.Hour
+
+		Convey("pushes once the message limit is reached", func() {
+			config.MaxBatchMessages = 3
+			client, err := NewClientWithConfig(*config)
+			So(err, ShouldBeNil)
+			client.Log("Foo")
+			client.Log("Bar")
+			client.LogError("Baz")
+			client.Shutdown()
+
+			So(len(requests), ShouldEqual, 1)
+			So(len(requests[0].Blocks), ShouldEqual, 2)
+		})
+
+		Convey("pushes once the byte limit is reached", func() {
+			config.MaxBatchBytes = 10
+			client, err := NewClientWithConfig(*config)
+			So(err, ShouldBeNil)
+			client.Log("Some message that is longer than the limit")
+			client.Log("Another one")
+			client.Shutdown()
+
+			So(len(requests), ShouldEqual, 2)
+		})
+
+		Convey("compresses requests", func() {
+			config.Gzip = true
+			client, err := NewClientWithConfig(*config)
+			So(err, ShouldBeNil)
+			client.Log("Foo")
+			client.Shutdown()
+
+			So(len(requests), ShouldEqual, 1)
+			So(requests[0].Blocks[0].Messages[0].Text, ShouldEqual, "Foo")
+		})
+
+		Convey("sends the token as bearer authorization", func() {
+			config.Token = "secret"
+			client, err := NewClientWithConfig(*config)
+			So(err, ShouldBeNil)
+			client.Log("Foo")
+			client.Shutdown()
+
+			So(authorizations, ShouldResemble, []string{"Bearer secret"})
+		})
+
+		Convey("only pushes on commit without reaching a limit", func() {
+			client, err := NewClientWithConfig(*config)
+			So(err, ShouldBeNil)
+			client.Log("Foo")
+			client.Log("Bar")
+			So(len(requests), ShouldEqual, 0)
+			client.Shutdown()
+
+			So(len(requests), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestClientErrorOutput(t *testing.T) {
+	Convey("Client error output", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		output := &bytes.Buffer{}
+		config := NewConfig()
+		config.URL = server.URL
+		config.SyncTime = time.Hour
+		config.ErrorOutput = output
+		client, err := NewClientWithConfig(*config)
+		So(err, ShouldBeNil)
+		client.Log("Foo")
+		client.Shutdown()
+
+		So(output.String(), ShouldEqual, "400 was returned\n")
+	})
+}
+
+func TestClientCertificateErrors(t *testing.T) {
+	Convey("Clients with certificate files that can't be loaded aren't created", t, func() {
+		config := NewConfig()
+		config.CAFile = "missing-ca.pem"
+		client, err := NewClientWithConfig(*config)
+		So(err, ShouldNotBeNil)
+		So(client, ShouldBeNil)
+
+		config.CAFile = ""
+		config.CertFile = "missing-cert.pem"
+		_, err = NewClientWithConfig(*config)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestClientSampling(t *testing.T) {
+	Convey("Client sampling", t, func() {
+		client := &Client{
+			Cache:           NewCache(),
+			Config:          NewConfig(),
+			shutdownChannel: make(chan struct{}),
+			sampler:         newSampler(),
+		}
+		client.Config.SamplingRules = map[string]SamplingRule{AllLevels: SamplingRule{KeepOneIn: 2}}
+
+		for i := 0; i < 4; i++ {
+			client.Log("Foo")
+			client.LogError("Bar")
+		}
+		client.addSamplingSummaries()
+
+		messagesMap := client.Cache.GetCachedMessagesAndReset()
+		So(len(messagesMap["error"]), ShouldEqual, 4)
+		So(len(messagesMap["standard"]), ShouldEqual, 3)
+		So(messagesMap["standard"][2].Fields[SuppressedMessagesField], ShouldEqual, "2")
+	})
+}
@@ -0,0 +1,94 @@
+package clienttest
+
+import (
+	"regexp"
+	"testing"
+)
+
+//FindMessages returns the entries on the level whose text matches the regular expression pattern,
+// an empty level matches every level. An invalid pattern fails the test.
+func FindMessages(t testing.TB, source Source, level, pattern string) []Entry {
+	t.Helper()
+	matches, err := findMessages(source, level, pattern)
+	if err != nil {
+		t.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	return matches
+}
+
+func findMessages(source Source, level, pattern string) (matches []Entry, err error) {
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range source.Entries() {
+		if level != "" && entry.Level != level {
+			continue
+		}
+		if expression.MatchString(entry.Text) {
+			matches = append(matches, entry)
+		}
+	}
+	return
+}
+
+//ExpectMessage fails the test unless a message on the level matches the pattern
+func ExpectMessage(t testing.TB, source Source, level, pattern string) {
+	t.Helper()
+	matches, err := findMessages(source, level, pattern)
+	if err != nil {
+		t.Errorf("invalid pattern %q: %v", pattern, err)
+		return
+	}
+	if len(matches) == 0 {
+		t.Errorf("expected a message on level %q matching %q, got:\n%v", level, pattern, formatEntries(source.Entries()))
+	}
+}
+
+//ExpectNoMessage fails the test if a message on the level matches the pattern
+func ExpectNoMessage(t testing.TB, source Source, level, pattern string) {
+	t.Helper()
+	matches, err := findMessages(source, level, pattern)
+	if err != nil {
+		t.Errorf("invalid pattern %q: %v", pattern, err)
+		return
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no message on level %q matching %q, got:\n%v", level, pattern, formatEntries(matches))
+	}
+}
+
+//ExpectMessageCount fails the test unless exactly count messages were logged on the level
+func ExpectMessageCount(t testing.TB, source Source, level string, count int) {
+	t.Helper()
+	if matches, _ := findMessages(source, level, ""); len(matches) != count {
+		t.Errorf("expected %v messages on level %q, got %v:\n%v", count, level, len(matches), formatEntries(matches))
+	}
+}
+
+//ExpectField fails the test unless a message on the level matching the pattern has the field set to value
+func ExpectField(t testing.TB, source Source, level, pattern, field, value string) {
+	t.Helper()
+	matches, err := findMessages(source, level, pattern)
+	if err != nil {
+		t.Errorf("invalid pattern %q: %v", pattern, err)
+		return
+	}
+	for _, entry := range matches {
+		if entry.Fields[field] == value {
+			return
+		}
+	}
+	t.Errorf("expected a message on level %q matching %q with %v=%v, got:\n%v", level, pattern, field, value, formatEntries(source.Entries()))
+}
+
+func formatEntries(entries []Entry) string {
+	if len(entries) == 0 {
+		return "  no messages"
+	}
+	formatted := ""
+	for _, entry := range entries {
+		formatted += "  " + entry.Level + " : " + entry.Text + "\n"
+	}
+	return formatted
+}
@@ -0,0 +1,100 @@
+package clienttest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alexmorten/log"
+	"github.com/alexmorten/log/client"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeT records failures instead of failing the test
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	Convey("Recorder", t, func() {
+		recorder := NewRecorder()
+		recorder.Config.ServiceName = "test"
+		var logger client.Logger = recorder
+
+		logger.Log("Foo", 1)
+		logger.LogWarn("Bar")
+		logger.LogErrorContext(client.WithTraceID(context.Background(), "abc"), "Baz failed")
+
+		So(recorder.Entries(), ShouldHaveLength, 3)
+		So(recorder.Entries()[0], ShouldResemble, Entry{
+			Service:   "test",
+			Level:     "standard",
+			Text:      "Foo 1",
+			Timestamp: recorder.Entries()[0].Timestamp,
+		})
+
+		Convey("passes matching expectations", func() {
+			fake := &fakeT{}
+			ExpectMessage(fake, recorder, "error", "^Baz")
+			ExpectMessage(fake, recorder, "", "Bar")
+			ExpectNoMessage(fake, recorder, "standard", "Baz")
+			ExpectMessageCount(fake, recorder, "warning", 1)
+			ExpectField(fake, recorder, "error", "Baz", log.TraceIDField, "abc")
+			So(fake.failures, ShouldBeEmpty)
+		})
+
+		Convey("fails expectations that don't match", func() {
+			fake := &fakeT{}
+			ExpectMessage(fake, recorder, "warning", "Baz")
+			ExpectNoMessage(fake, recorder, "error", "Baz")
+			ExpectMessageCount(fake, recorder, "error", 2)
+			ExpectField(fake, recorder, "error", "Baz", log.TraceIDField, "def")
+			So(fake.failures, ShouldHaveLength, 4)
+		})
+
+		Convey("fails expectations with invalid patterns", func() {
+			fake := &fakeT{}
+			So(FindMessages(fake, recorder, "", "(Baz"), ShouldBeEmpty)
+			ExpectMessage(fake, recorder, "error", "(Baz")
+			ExpectNoMessage(fake, recorder, "error", "(Baz")
+			ExpectField(fake, recorder, "error", "(Baz", log.TraceIDField, "abc")
+			So(fake.failures, ShouldHaveLength, 4)
+			So(fake.failures[0], ShouldStartWith, `invalid pattern "(Baz"`)
+		})
+
+		Convey("forgets entries on reset", func() {
+			recorder.Reset()
+			So(recorder.Entries(), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestServer(t *testing.T) {
+	Convey("Server", t, func() {
+		server := NewServer()
+		defer server.Close()
+
+		config := server.ClientConfig()
+		config.ServiceName = "test"
+		config.Gzip = true
+		c, err := client.NewClientWithConfig(config)
+		So(err, ShouldBeNil)
+		c.Log("Foo")
+		c.LogError("Bar")
+		c.Shutdown()
+
+		So(server.Blocks(), ShouldHaveLength, 2)
+		fake := &fakeT{}
+		ExpectMessage(fake, server, "standard", "Foo")
+		ExpectMessage(fake, server, "error", "Bar")
+		So(fake.failures, ShouldBeEmpty)
+		So(FindMessages(fake, server, "", "")[0].Service, ShouldEqual, "test")
+	})
+}
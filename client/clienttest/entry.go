@@ -0,0 +1,34 @@
+//Package clienttest helps testing code that logs through the client package,
+// without running the log server
+package clienttest
+
+import (
+	"github.com/alexmorten/log"
+)
+
+//Entry is one recorded log message
+type Entry struct {
+	Service   string
+	Level     string
+	Text      string
+	Timestamp int64
+	Fields    map[string]string
+}
+
+//Source of recorded entries, implemented by Recorder and Server
+type Source interface {
+	Entries() []Entry
+}
+
+func entriesFromBlock(block *log.Block) (entries []Entry) {
+	for _, message := range block.Messages {
+		entries = append(entries, Entry{
+			Service:   block.Service,
+			Level:     block.Level,
+			Text:      message.Text,
+			Timestamp: message.Timestamp,
+			Fields:    message.Fields,
+		})
+	}
+	return
+}
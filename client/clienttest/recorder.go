@@ -0,0 +1,101 @@
+package clienttest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alexmorten/log/client"
+)
+
+//Recorder keeps log messages in memory instead of sending them to a server,
+// it implements client.Logger
+type Recorder struct {
+	Config  *client.Config
+	mutex   sync.Mutex
+	entries []Entry
+}
+
+var _ client.Logger = &Recorder{}
+
+//NewRecorder with the default client config
+func NewRecorder() *Recorder {
+	return &Recorder{
+		Config: client.NewConfig(),
+	}
+}
+
+//LogMessage records a message on any given level
+func (r *Recorder) LogMessage(level, message string) {
+	r.record(level, message, nil)
+}
+
+//Log records a standard message
+func (r *Recorder) Log(messageArgs ...interface{}) {
+	r.LogMessage("standard", client.FormatMessage(messageArgs...))
+}
+
+//LogWarn records a warning message
+func (r *Recorder) LogWarn(messageArgs ...interface{}) {
+	r.LogMessage("warning", client.FormatMessage(messageArgs...))
+}
+
+//LogError records an error message
+func (r *Recorder) LogError(messageArgs ...interface{}) {
+	r.LogMessage("error", client.FormatMessage(messageArgs...))
+}
+
+//LogMessageContext records a message on any given level with the fields extracted from ctx
+func (r *Recorder) LogMessageContext(ctx context.Context, level, message string) {
+	r.record(level, message, r.Config.FieldsFromContext(ctx))
+}
+
+//LogContext records a standard message with the fields extracted from ctx
+func (r *Recorder) LogContext(ctx context.Context, messageArgs ...interface{}) {
+	r.LogMessageContext(ctx, "standard", client.FormatMessage(messageArgs...))
+}
+
+//LogWarnContext records a warning message with the fields extracted from ctx
+func (r *Recorder) LogWarnContext(ctx context.Context, messageArgs ...interface{}) {
+	r.LogMessageContext(ctx, "warning", client.FormatMessage(messageArgs...))
+}
+
+//LogErrorContext records an error message with the fields extracted from ctx
+func (r *Recorder) LogErrorContext(ctx context.Context, messageArgs ...interface{}) {
+	r.LogMessageContext(ctx, "error", client.FormatMessage(messageArgs...))
+}
+
+//Commit does nothing, messages are recorded immediately
+func (r *Recorder) Commit() {}
+
+//Shutdown does nothing, messages are recorded immediately
+func (r *Recorder) Shutdown() {}
+
+//Entries returns a copy of the recorded entries in the order they were logged
+func (r *Recorder) Entries() []Entry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Entry{}, r.entries...)
+}
+
+//Reset forgets all recorded entries
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries = nil
+}
+
+func (r *Recorder) record(level, message string, fields map[string]string) {
+	if level == "" || message == "" {
+		panic("both level and message should be set when writing a message")
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries = append(r.entries, Entry{
+		Service:   r.Config.ServiceName,
+		Level:     level,
+		Text:      message,
+		Timestamp: time.Now().Unix(),
+		Fields:    fields,
+	})
+}
@@ -0,0 +1,94 @@
+package clienttest
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/alexmorten/log"
+	"github.com/alexmorten/log/client"
+	"github.com/gogo/protobuf/proto"
+)
+
+//Server is a fake log server that records the blocks posted to it
+type Server struct {
+	*httptest.Server
+	mutex  sync.Mutex
+	blocks []*log.Block
+}
+
+//NewServer starts a fake log server, remember to Close it
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handlePost))
+	return s
+}
+
+//ClientConfig returns the default client config pointed at the fake server
+func (s *Server) ClientConfig() client.Config {
+	config := client.NewConfig()
+	config.URL = s.URL
+	return *config
+}
+
+//Blocks returns the blocks that were posted in the order they were received
+func (s *Server) Blocks() []*log.Block {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*log.Block{}, s.blocks...)
+}
+
+//Entries returns the messages of all posted blocks
+func (s *Server) Entries() (entries []Entry) {
+	for _, block := range s.Blocks() {
+		entries = append(entries, entriesFromBlock(block)...)
+	}
+	return
+}
+
+//Reset forgets all posted blocks
+func (s *Server) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.blocks = nil
+}
+
+func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = gzipReader
+	}
+	bytes, err := ioutil.ReadAll(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	postRequest := &log.PostRequest{}
+	if err := proto.Unmarshal(bytes, postRequest); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, block := range postRequest.Blocks {
+		if !block.Valid() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mutex.Lock()
+	s.blocks = append(s.blocks, postRequest.Blocks...)
+	s.mutex.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"io"
 	"os"
 	"time"
 )
@@ -8,8 +9,34 @@ import (
 //Config for Client
 type Config struct {
 	ServiceName string
-	URL         string
-	SyncTime    time.Duration
+	//URL of the server, without the api path
+	URL      string
+	SyncTime time.Duration
+	//GRPCAddr (host:port) pushes the messages over the gRPC service of the server instead of posting them to URL,
+	// with TLS if any of the certificate files are set. Pushes wait for the ack of the previous one.
+	GRPCAddr string
+	//Token is sent to the server as bearer authorization, it needs the write scope for the service
+	Token string
+	//CAFile holds the PEM encoded CAs that the certificate of an HTTPS server is verified against, the system CAs are used without it
+	CAFile string
+	//CertFile and KeyFile are the PEM encoded client certificate for servers that require one, they are read again once they change
+	CertFile, KeyFile string
+	//Gzip compresses the requests to the server
+	Gzip bool
+	//MaxBatchMessages triggers an early push once this many messages are cached, 0 disables the limit
+	MaxBatchMessages int
+	//MaxBatchBytes triggers an early push once the cached messages reach this size, 0 disables the limit
+	MaxBatchBytes int
+	//MaxInFlightRequests limits the concurrent requests to the server,
+	// logging blocks while the limit is reached instead of piling up requests
+	MaxInFlightRequests int
+	//SamplingRules per level restrict how many messages are sent, use AllLevels as key for a default rule.
+	// Suppressed messages are reported in a summary message on their level every SyncTime.
+	SamplingRules map[string]SamplingRule
+	//ContextExtractors map message field names to the extractors that fill them in the Log...Context methods
+	ContextExtractors map[string]ContextExtractor
+	//ErrorOutput receives the errors of the client, os.Stderr when it is nil so they don't mix with the output of the program
+	ErrorOutput io.Writer
 }
 
 //NewConfig struct with defaults
@@ -18,7 +45,21 @@ func NewConfig() *Config {
 		ServiceName: defaultServiceName(),
 		URL:         defaultConfigURL(),
 		SyncTime:    defaultSyncTime(),
+
+		MaxBatchMessages:    defaultMaxBatchMessages(),
+		MaxBatchBytes:       defaultMaxBatchBytes(),
+		MaxInFlightRequests: defaultMaxInFlightRequests(),
+
+		ContextExtractors: defaultContextExtractors(),
+	}
+}
+
+//errorOutput is where the client reports its errors
+func (c *Config) errorOutput() io.Writer {
+	if c.ErrorOutput == nil {
+		return os.Stderr
 	}
+	return c.ErrorOutput
 }
 
 func defaultServiceName() string {
@@ -36,3 +77,15 @@ func defaultConfigURL() string {
 func defaultSyncTime() time.Duration {
 	return time.Second * 30
 }
+
+func defaultMaxBatchMessages() int {
+	return 1000
+}
+
+func defaultMaxBatchBytes() int {
+	return 1024 * 1024
+}
+
+func defaultMaxInFlightRequests() int {
+	return 4
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+
+	"github.com/alexmorten/log"
+)
+
+//ContextExtractor pulls a single value out of a context,
+// an empty string means that the context doesn't carry the value
+type ContextExtractor func(ctx context.Context) string
+
+type contextKey int
+
+const (
+	traceIDKey contextKey = iota
+	spanIDKey
+	requestIDKey
+)
+
+//WithTraceID returns a copy of ctx that carries the trace id
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey, traceID)
+}
+
+//WithSpanID returns a copy of ctx that carries the span id
+func WithSpanID(ctx context.Context, spanID string) context.Context {
+	return context.WithValue(ctx, spanIDKey, spanID)
+}
+
+//WithRequestID returns a copy of ctx that carries the request id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+//TraceIDFromContext returns the trace id set with WithTraceID
+func TraceIDFromContext(ctx context.Context) string {
+	return stringFromContext(ctx, traceIDKey)
+}
+
+//SpanIDFromContext returns the span id set with WithSpanID
+func SpanIDFromContext(ctx context.Context) string {
+	return stringFromContext(ctx, spanIDKey)
+}
+
+//RequestIDFromContext returns the request id set with WithRequestID
+func RequestIDFromContext(ctx context.Context) string {
+	return stringFromContext(ctx, requestIDKey)
+}
+
+func stringFromContext(ctx context.Context, key contextKey) string {
+	value, _ := ctx.Value(key).(string)
+	return value
+}
+
+func defaultContextExtractors() map[string]ContextExtractor {
+	return map[string]ContextExtractor{
+		log.TraceIDField:   TraceIDFromContext,
+		log.SpanIDField:    SpanIDFromContext,
+		log.RequestIDField: RequestIDFromContext,
+	}
+}
+
+//FieldsFromContext runs the ContextExtractors against ctx and collects the values that were found
+func (c *Config) FieldsFromContext(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	var fields map[string]string
+	for field, extractor := range c.ContextExtractors {
+		value := extractor(ctx)
+		if value == "" {
+			continue
+		}
+		if fields == nil {
+			fields = map[string]string{}
+		}
+		fields[field] = value
+	}
+	return fields
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/alexmorten/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+//grpcTransport pushes the blocks over a single Push stream, which is opened again after errors.
+//The server acks every request, so pushes wait for the previous one to be acked.
+type grpcTransport struct {
+	conn        *grpc.ClientConn
+	token       string
+	errorOutput io.Writer
+	mutex       sync.Mutex
+	stream      log.Log_PushClient
+	cancel      context.CancelFunc
+	sequence    uint64
+}
+
+//newGRPCTransport connects to the GRPCAddr of the config, with TLS if any of the certificate files are set
+func newGRPCTransport(config *Config) (*grpcTransport, error) {
+	option := grpc.WithInsecure()
+	if config.CAFile != "" || config.CertFile != "" || config.KeyFile != "" {
+		tlsConfig, err := log.ClientTLSConfig(config.CAFile, config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		option = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+	conn, err := grpc.Dial(config.GRPCAddr, option)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcTransport{conn: conn, token: config.Token, errorOutput: config.errorOutput()}, nil
+}
+
+//push sends the blocks and waits for their ack, it returns how long to back off if the server asks for it
+func (t *grpcTransport) push(blocks []*log.Block, fallback time.Duration) (bool, time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stream == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		if t.token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+t.token)
+		}
+		stream, err := log.NewLogClient(t.conn).Push(ctx)
+		if err != nil {
+			cancel()
+			fmt.Fprintln(t.errorOutput, err)
+			return false, 0
+		}
+		t.stream, t.cancel = stream, cancel
+	}
+
+	t.sequence++
+	err := t.stream.Send(&log.PushRequest{Sequence: t.sequence, Blocks: blocks})
+	if err != nil {
+		t.reset()
+		fmt.Fprintln(t.errorOutput, err)
+		return false, 0
+	}
+	ack, err := t.stream.Recv()
+	if err != nil {
+		t.reset()
+		fmt.Fprintln(t.errorOutput, err)
+		return false, 0
+	}
+
+	switch codes.Code(ack.Code) {
+	case codes.OK:
+		return false, 0
+	case codes.ResourceExhausted, codes.Unavailable:
+		wait := time.Duration(ack.RetryAfterSeconds) * time.Second
+		if wait <= 0 {
+			wait = fallback
+		}
+		return true, wait
+	default:
+		fmt.Fprintln(t.errorOutput, ack.Error, "was returned")
+		return false, 0
+	}
+}
+
+// reset ends the current stream, the next push opens a new one
+func (t *grpcTransport) reset() {
+	t.cancel()
+	t.stream, t.cancel = nil, nil
+}
+
+//close ends the stream and the connection
+func (t *grpcTransport) close() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stream != nil {
+		t.stream.CloseSend()
+		t.reset()
+	}
+	t.conn.Close()
+}
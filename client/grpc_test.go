@@ -0,0 +1,107 @@
+package client
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmorten/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+//fakeLogServer records the pushed requests and acks them with the next of its codes
+type fakeLogServer struct {
+	mutex          sync.Mutex
+	requests       []*log.PushRequest
+	authorizations []string
+	codes          []codes.Code
+}
+
+func (f *fakeLogServer) Push(stream log.Log_PushServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		f.mutex.Lock()
+		f.requests = append(f.requests, request)
+		f.authorizations = append(f.authorizations, md["authorization"]...)
+		ack := &log.PushAck{Sequence: request.Sequence}
+		if len(f.codes) > 0 {
+			ack.Code, ack.RetryAfterSeconds = int32(f.codes[0]), 30
+			f.codes = f.codes[1:]
+		}
+		f.mutex.Unlock()
+		if err := stream.Send(ack); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *fakeLogServer) Query(*log.QueryRequest, log.Log_QueryServer) error { return nil }
+
+func (f *fakeLogServer) Tail(*log.TailRequest, log.Log_TailServer) error { return nil }
+
+func TestGRPCTransport(t *testing.T) {
+	Convey("gRPC transport", t, func() {
+		fake := &fakeLogServer{}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		server := grpc.NewServer()
+		log.RegisterLogServer(server, fake)
+		go server.Serve(listener)
+		defer server.Stop()
+
+		config := NewConfig()
+		config.GRPCAddr = listener.Addr().String()
+		config.SyncTime = time.Hour
+		config.Token = "secret"
+
+		Convey("pushes the messages over one stream", func() {
+			client, err := NewClientWithConfig(*config)
+			So(err, ShouldBeNil)
+			client.Log("Foo")
+			client.Commit()
+			client.LogError("Bar")
+			client.Shutdown()
+
+			So(len(fake.requests), ShouldEqual, 2)
+			So(fake.requests[0].Sequence, ShouldEqual, 1)
+			So(fake.requests[0].Blocks[0].Messages[0].Text, ShouldEqual, "Foo")
+			So(fake.requests[1].Sequence, ShouldEqual, 2)
+			So(fake.requests[1].Blocks[0].Level, ShouldEqual, "error")
+			So(fake.authorizations, ShouldResemble, []string{"Bearer secret", "Bearer secret"})
+		})
+
+		Convey("backs off and requeues when the server is exhausted", func() {
+			fake.codes = []codes.Code{codes.ResourceExhausted}
+			client, err := NewClientWithConfig(*config)
+			So(err, ShouldBeNil)
+			client.Log("Foo")
+			client.Commit()
+
+			So(client.backoff.active(), ShouldBeTrue)
+			messageCount, _ := client.Cache.Size()
+			So(messageCount, ShouldEqual, 1)
+			client.Shutdown()
+			So(len(fake.requests), ShouldEqual, 2)
+		})
+
+		Convey("fails instead of falling back to http when it can't connect", func() {
+			config.CAFile = "missing-ca.pem"
+			client, err := NewClientWithConfig(*config)
+			So(err, ShouldNotBeNil)
+			So(client, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,24 @@
+package client
+
+import (
+	"context"
+)
+
+//Logger is the logging surface of Client.
+// Depend on it instead of *Client to be able to swap in the recorder from the clienttest package in tests.
+type Logger interface {
+	LogMessage(level, message string)
+	Log(messageArgs ...interface{})
+	LogWarn(messageArgs ...interface{})
+	LogError(messageArgs ...interface{})
+
+	LogMessageContext(ctx context.Context, level, message string)
+	LogContext(ctx context.Context, messageArgs ...interface{})
+	LogWarnContext(ctx context.Context, messageArgs ...interface{})
+	LogErrorContext(ctx context.Context, messageArgs ...interface{})
+
+	Commit()
+	Shutdown()
+}
+
+var _ Logger = &Client{}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+//AllLevels is the key in Config.SamplingRules for the rule that applies to every level without its own rule.
+// The "error" level is never sampled by it, errors are only sampled with an explicit rule.
+const AllLevels = "*"
+
+//SuppressedMessagesField holds the number of suppressed messages in sampling summary messages
+const SuppressedMessagesField = "suppressed_messages"
+
+//SamplingRule restricts how many messages of a level are sent to the server
+type SamplingRule struct {
+	//KeepOneIn keeps only every nth message, 0 and 1 keep all messages
+	KeepOneIn int
+	//MessagesPerSecond limits the rate of messages with a token bucket, 0 disables the limit
+	MessagesPerSecond float64
+	//Burst is the number of messages that can be logged at once before MessagesPerSecond kicks in,
+	// defaults to MessagesPerSecond
+	Burst int
+}
+
+//sampler decides which messages are kept and counts the suppressed ones per level
+type sampler struct {
+	mutex  sync.Mutex
+	levels map[string]*levelSampler
+	now    func() time.Time
+}
+
+type levelSampler struct {
+	rule       SamplingRule
+	seen       int
+	tokens     float64
+	lastRefill time.Time
+	suppressed int
+}
+
+func newSampler() *sampler {
+	return &sampler{
+		levels: map[string]*levelSampler{},
+		now:    time.Now,
+	}
+}
+
+//allow checks if a message on the level should be kept according to the rules
+func (s *sampler) allow(level string, rules map[string]SamplingRule) bool {
+	rule, ok := ruleForLevel(level, rules)
+	if !ok {
+		return true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	l := s.levels[level]
+	if l == nil {
+		l = &levelSampler{}
+		s.levels[level] = l
+	}
+	if l.seen == 0 || l.rule != rule {
+		// the rules of the config may change, start over with the new rule but keep the suppressed count for the summary
+		l.rule = rule
+		l.seen = 0
+		l.tokens = rule.burst()
+		l.lastRefill = s.now()
+	}
+
+	if l.allow(s.now()) {
+		return true
+	}
+	l.suppressed++
+	return false
+}
+
+//suppressedAndReset returns the number of suppressed messages per level since the last call
+func (s *sampler) suppressedAndReset() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	suppressed := map[string]int{}
+	for level, l := range s.levels {
+		if l.suppressed > 0 {
+			suppressed[level] = l.suppressed
+			l.suppressed = 0
+		}
+	}
+	return suppressed
+}
+
+func ruleForLevel(level string, rules map[string]SamplingRule) (rule SamplingRule, ok bool) {
+	if rule, ok = rules[level]; ok {
+		return
+	}
+	if level == "error" {
+		return
+	}
+	rule, ok = rules[AllLevels]
+	return
+}
+
+func (l *levelSampler) allow(now time.Time) bool {
+	l.seen++
+	if l.rule.KeepOneIn > 1 && (l.seen-1)%l.rule.KeepOneIn != 0 {
+		return false
+	}
+	if l.rule.MessagesPerSecond <= 0 {
+		return true
+	}
+
+	l.tokens += now.Sub(l.lastRefill).Seconds() * l.rule.MessagesPerSecond
+	if l.tokens > l.rule.burst() {
+		l.tokens = l.rule.burst()
+	}
+	l.lastRefill = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+func (r SamplingRule) burst() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	if r.MessagesPerSecond < 1 {
+		return 1
+	}
+	return r.MessagesPerSecond
+}
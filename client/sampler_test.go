@@ -0,0 +1,70 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSampler(t *testing.T) {
+	Convey("Sampler", t, func() {
+		now := time.Unix(10000, 0)
+		s := newSampler()
+		s.now = func() time.Time { return now }
+
+		Convey("keeps one in n messages", func() {
+			rules := map[string]SamplingRule{"standard": SamplingRule{KeepOneIn: 3}}
+			kept := 0
+			for i := 0; i < 9; i++ {
+				if s.allow("standard", rules) {
+					kept++
+				}
+			}
+			So(kept, ShouldEqual, 3)
+			So(s.suppressedAndReset(), ShouldResemble, map[string]int{"standard": 6})
+			So(s.suppressedAndReset(), ShouldBeEmpty)
+		})
+
+		Convey("limits the rate with a token bucket", func() {
+			rules := map[string]SamplingRule{"standard": SamplingRule{MessagesPerSecond: 2}}
+			So(s.allow("standard", rules), ShouldBeTrue)
+			So(s.allow("standard", rules), ShouldBeTrue)
+			So(s.allow("standard", rules), ShouldBeFalse)
+
+			now = now.Add(500 * time.Millisecond)
+			So(s.allow("standard", rules), ShouldBeTrue)
+			So(s.allow("standard", rules), ShouldBeFalse)
+			So(s.suppressedAndReset(), ShouldResemble, map[string]int{"standard": 2})
+		})
+
+		Convey("applies the default rule to every level but error", func() {
+			rules := map[string]SamplingRule{AllLevels: SamplingRule{KeepOneIn: 2}}
+			So(s.allow("warning", rules), ShouldBeTrue)
+			So(s.allow("warning", rules), ShouldBeFalse)
+			So(s.allow("error", rules), ShouldBeTrue)
+			So(s.allow("error", rules), ShouldBeTrue)
+		})
+
+		Convey("uses the current rule of the level", func() {
+			rules := map[string]SamplingRule{"standard": SamplingRule{KeepOneIn: 3}}
+			So(s.allow("standard", rules), ShouldBeTrue)
+			So(s.allow("standard", rules), ShouldBeFalse)
+
+			rules["standard"] = SamplingRule{KeepOneIn: 1}
+			So(s.allow("standard", rules), ShouldBeTrue)
+			So(s.allow("standard", rules), ShouldBeTrue)
+
+			rules["standard"] = SamplingRule{MessagesPerSecond: 1}
+			So(s.allow("standard", rules), ShouldBeTrue)
+			So(s.allow("standard", rules), ShouldBeFalse)
+			So(s.suppressedAndReset(), ShouldResemble, map[string]int{"standard": 2})
+		})
+
+		Convey("samples errors with an explicit rule", func() {
+			rules := map[string]SamplingRule{"error": SamplingRule{KeepOneIn: 2}}
+			So(s.allow("error", rules), ShouldBeTrue)
+			So(s.allow("error", rules), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alexmorten/log"
+)
+
+// every scroll past the oldest loaded message loads this much more history
+const browsePageDuration = time.Hour
+
+// browser is the state of the interactive log browser, drawing it is left to browse_screen.go
+type browser struct {
+	fetch func(q query) ([]entry, error)
+	now   func() time.Time
+
+	service, level string
+	entries        []entry
+	// the time range that entries were loaded for
+	loadedFrom, loadedTo time.Time
+	formatter            *lineFormatter
+
+	// index of the first visible entry
+	top int
+	// number of visible entries
+	height int
+	follow bool
+	status string
+
+	search    string
+	searching bool
+
+	picker *picker
+}
+
+// picker lets the user choose the service and level to browse
+type picker struct {
+	items    []pickerItem
+	selected int
+}
+
+type pickerItem struct {
+	label, service, level string
+}
+
+func newBrowser(fetch func(q query) ([]entry, error), times *timeFormatter) *browser {
+	return &browser{
+		fetch:     fetch,
+		now:       time.Now,
+		formatter: newLineFormatter(nil, completeQuery, times, &colorizer{}),
+		height:    1,
+	}
+}
+
+func (b *browser) shape() queryShape {
+	return shapeFor(b.service, b.level, "")
+}
+
+func (b *browser) query(from, to time.Time) query {
+	return query{
+		service: b.service,
+		level:   b.level,
+		from:    from,
+		to:      to,
+	}
+}
+
+// reload replaces the entries with the latest page
+func (b *browser) reload() error {
+	to := b.now()
+	from := to.Add(-browsePageDuration)
+	entries, err := b.fetch(b.query(from, to))
+	if err != nil {
+		b.status = err.Error()
+		return err
+	}
+	b.formatter.shape = b.shape()
+	b.formatter.serviceWidth = 0
+	b.formatter.levelWidth = 0
+	b.entries = nil
+	b.loadedFrom = from
+	b.loadedTo = to
+	b.add(entries, false)
+	b.scrollToEnd()
+	b.status = fmt.Sprintf("loaded %v messages", len(entries))
+	return nil
+}
+
+// loadOlder prepends the page before the oldest loaded message, keeping the visible messages in place
+func (b *browser) loadOlder() error {
+	to := b.loadedFrom.Add(-time.Second)
+	from := to.Add(-browsePageDuration)
+	entries, err := b.fetch(b.query(from, to))
+	if err != nil {
+		b.status = err.Error()
+		return err
+	}
+	b.loadedFrom = from
+	b.add(entries, true)
+	b.top += len(entries)
+	if len(entries) == 0 {
+		b.status = "no messages since " + b.formatter.times.format(from.Unix())
+	} else {
+		b.status = fmt.Sprintf("loaded %v older messages", len(entries))
+	}
+	return nil
+}
+
+// loadNewer appends the messages logged since the last load, used for the live tail
+func (b *browser) loadNewer() error {
+	from := b.loadedTo
+	to := b.now()
+	entries, err := b.fetch(b.query(from, to))
+	if err != nil {
+		b.status = err.Error()
+		return err
+	}
+
+	// the first second might have been loaded already, skip the messages we have
+	loaded := 0
+	for i := len(b.entries) - 1; i >= 0 && b.entries[i].Timestamp == from.Unix(); i-- {
+		loaded++
+	}
+	for loaded > 0 && len(entries) > 0 && entries[0].Timestamp == from.Unix() {
+		entries = entries[1:]
+		loaded--
+	}
+
+	atEnd := b.atEnd()
+	b.loadedTo = to
+	b.add(entries, false)
+	if atEnd {
+		b.scrollToEnd()
+	}
+	return nil
+}
+
+func (b *browser) add(entries []entry, prepend bool) {
+	for _, e := range entries {
+		b.formatter.fit(e)
+	}
+	if prepend {
+		b.entries = append(append([]entry{}, entries...), b.entries...)
+	} else {
+		b.entries = append(b.entries, entries...)
+	}
+}
+
+// scroll moves the view by lines, scrolling up past the first message loads older ones
+func (b *browser) scroll(lines int) {
+	if lines < 0 && b.top+lines < 0 {
+		b.loadOlder()
+	}
+	b.top += lines
+	b.clamp()
+}
+
+func (b *browser) scrollToEnd() {
+	b.top = len(b.entries) - b.height
+	b.clamp()
+}
+
+func (b *browser) atEnd() bool {
+	return b.top >= len(b.entries)-b.height
+}
+
+func (b *browser) clamp() {
+	if b.top > len(b.entries)-b.height {
+		b.top = len(b.entries) - b.height
+	}
+	if b.top < 0 {
+		b.top = 0
+	}
+}
+
+func (b *browser) visible() []entry {
+	end := b.top + b.height
+	if end > len(b.entries) {
+		end = len(b.entries)
+	}
+	return b.entries[b.top:end]
+}
+
+func (b *browser) toggleFollow() {
+	b.follow = !b.follow
+	if b.follow {
+		b.loadNewer()
+		b.scrollToEnd()
+	}
+}
+
+// findMatch moves the view to the next message (direction 1) or previous one (direction -1) that contains the search
+func (b *browser) findMatch(direction int) bool {
+	if b.search == "" {
+		return false
+	}
+	for i := b.top + direction; i >= 0 && i < len(b.entries); i += direction {
+		if len(matchPositions(b.entries[i].Text, b.search)) > 0 {
+			b.top = i
+			b.clamp()
+			b.status = ""
+			return true
+		}
+	}
+	b.status = fmt.Sprintf("%q not found", b.search)
+	return false
+}
+
+func (b *browser) openPicker(services []*log.ServiceInfo) {
+	p := &picker{items: pickerItems(services)}
+	for i, item := range p.items {
+		if item.service == b.service && item.level == b.level {
+			p.selected = i
+		}
+	}
+	b.picker = p
+}
+
+// pick browses the selected service and level
+func (b *browser) pick() error {
+	item := b.picker.items[b.picker.selected]
+	b.picker = nil
+	b.service = item.service
+	b.level = item.level
+	return b.reload()
+}
+
+func (b *browser) title() string {
+	switch {
+	case b.service != "" && b.level != "":
+		return b.service + " / " + b.level
+	case b.service != "":
+		return b.service
+	}
+	return "all services"
+}
+
+func (p *picker) move(lines int) {
+	p.selected += lines
+	if p.selected >= len(p.items) {
+		p.selected = len(p.items) - 1
+	}
+	if p.selected < 0 {
+		p.selected = 0
+	}
+}
+
+func pickerItems(services []*log.ServiceInfo) []pickerItem {
+	items := []pickerItem{pickerItem{label: "all services"}}
+	for _, service := range services {
+		items = append(items, pickerItem{label: service.Name, service: service.Name})
+		for _, level := range service.Levels {
+			items = append(items, pickerItem{
+				label:   "  " + level.Name,
+				service: service.Name,
+				level:   level.Name,
+			})
+		}
+	}
+	return items
+}
+
+// matchPositions returns the byte offsets of case insensitive occurrences of search in text
+func matchPositions(text, search string) (positions []int) {
+	if search == "" {
+		return
+	}
+	lowerText := strings.ToLower(text)
+	lowerSearch := strings.ToLower(search)
+	if len(lowerText) != len(text) || len(lowerSearch) != len(search) {
+		// lower casing changed the byte lengths, the offsets would be off
+		lowerText = text
+		lowerSearch = search
+	}
+	offset := 0
+	for {
+		index := strings.Index(lowerText[offset:], lowerSearch)
+		if index < 0 {
+			return
+		}
+		positions = append(positions, offset+index)
+		offset += index + len(lowerSearch)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell"
+)
+
+const browseHelp = "↑↓/PgUp/PgDn scroll  t tail  s services  / search  n/N next/prev  r reload  q quit"
+
+// the live tail asks the server for new messages this often
+const browseTailInterval = 2 * time.Second
+
+var tcellLevelPalette = []tcell.Color{
+	tcell.ColorGreen,
+	tcell.ColorBlue,
+	tcell.ColorPurple,
+	tcell.ColorTeal,
+	tcell.ColorLime,
+	tcell.ColorDodgerBlue,
+	tcell.ColorFuchsia,
+	tcell.ColorAqua,
+}
+
+func runBrowse(args []string) error {
+	flags := flag.NewFlagSet("logcli browse", flag.ContinueOnError)
+	serverFlags := &serverFlags{}
+	serverFlags.register(flags)
+	service := flags.String("service", "", "start browsing the provided service")
+	level := flags.String("level", "", "start browsing the provided level (can only be used together with a service)")
+	outputFlags := &outputFlags{}
+	outputFlags.register(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *level != "" && *service == "" {
+		return fmt.Errorf("you can only use the level flag if you also provide a service")
+	}
+	serverURL, err := serverFlags.connect()
+	if err != nil {
+		return err
+	}
+
+	b := newBrowser(func(q query) ([]entry, error) {
+		return fetchEntries(serverURL, q, nil)
+	}, outputFlags.timeFormatter())
+	b.now = outputFlags.now
+	b.service = *service
+	b.level = *level
+	if err := b.reload(); err != nil {
+		return err
+	}
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err := screen.Init(); err != nil {
+		return err
+	}
+	defer screen.Fini()
+
+	events := make(chan tcell.Event)
+	go func() {
+		for {
+			event := screen.PollEvent()
+			if event == nil {
+				return
+			}
+			events <- event
+		}
+	}()
+	ticker := time.NewTicker(browseTailInterval)
+	defer ticker.Stop()
+
+	for {
+		_, height := screen.Size()
+		b.resize(height - 2)
+		drawBrowser(screen, b)
+
+		select {
+		case event := <-events:
+			if key, ok := event.(*tcell.EventKey); ok {
+				if quit := handleBrowserKey(b, key, serverURL); quit {
+					return nil
+				}
+			}
+		case <-ticker.C:
+			if b.follow {
+				b.loadNewer()
+			}
+		}
+	}
+}
+
+// resize keeps the last message in view when the view was scrolled to the end
+func (b *browser) resize(height int) {
+	if height < 1 {
+		height = 1
+	}
+	atEnd := b.atEnd()
+	b.height = height
+	if atEnd {
+		b.scrollToEnd()
+	}
+	b.clamp()
+}
+
+// handleBrowserKey changes the browser state for the key, it returns true when the browser should close
+func handleBrowserKey(b *browser, key *tcell.EventKey, serverURL string) bool {
+	if key.Key() == tcell.KeyCtrlC {
+		return true
+	}
+
+	if b.picker != nil {
+		switch key.Key() {
+		case tcell.KeyUp:
+			b.picker.move(-1)
+		case tcell.KeyDown:
+			b.picker.move(1)
+		case tcell.KeyEnter:
+			b.pick()
+		case tcell.KeyEscape:
+			b.picker = nil
+		}
+		return false
+	}
+
+	if b.searching {
+		switch key.Key() {
+		case tcell.KeyEnter:
+			b.searching = false
+			b.findMatch(1)
+		case tcell.KeyEscape:
+			b.searching = false
+			b.search = ""
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if len(b.search) > 0 {
+				runes := []rune(b.search)
+				b.search = string(runes[:len(runes)-1])
+			}
+		case tcell.KeyRune:
+			b.search += string(key.Rune())
+		}
+		return false
+	}
+
+	switch key.Key() {
+	case tcell.KeyEscape:
+		return true
+	case tcell.KeyUp:
+		b.scroll(-1)
+	case tcell.KeyDown:
+		b.scroll(1)
+	case tcell.KeyPgUp:
+		b.scroll(-b.height)
+	case tcell.KeyPgDn:
+		b.scroll(b.height)
+	case tcell.KeyHome:
+		b.top = 0
+	case tcell.KeyEnd:
+		b.scrollToEnd()
+	case tcell.KeyRune:
+		switch key.Rune() {
+		case 'q':
+			return true
+		case 'k':
+			b.scroll(-1)
+		case 'j':
+			b.scroll(1)
+		case 'g':
+			b.top = 0
+		case 'G':
+			b.scrollToEnd()
+		case 't':
+			b.toggleFollow()
+		case 'r':
+			b.reload()
+		case '/':
+			b.searching = true
+			b.search = ""
+		case 'n':
+			b.findMatch(1)
+		case 'N':
+			b.findMatch(-1)
+		case 's':
+			services, err := fetchServices(serverURL)
+			if err != nil {
+				b.status = err.Error()
+				return false
+			}
+			b.openPicker(services)
+		}
+	}
+	return false
+}
+
+func drawBrowser(screen tcell.Screen, b *browser) {
+	screen.Clear()
+	width, height := screen.Size()
+	barStyle := tcell.StyleDefault.Reverse(true)
+
+	follow := "off"
+	if b.follow {
+		follow = "on"
+	}
+	title := fmt.Sprintf(" %v | since %v | tail %v | %v messages | %v",
+		b.title(), b.formatter.times.format(b.loadedFrom.Unix()), follow, len(b.entries), b.status)
+	fillLine(screen, 0, width, barStyle)
+	drawText(screen, 0, 0, width, title, barStyle)
+
+	for i, e := range b.visible() {
+		drawEntry(screen, i+1, width, b, e)
+	}
+
+	footer := " " + browseHelp
+	if b.searching {
+		footer = " /" + b.search
+	} else if b.search != "" {
+		footer = fmt.Sprintf(" search: %v | %v", b.search, browseHelp)
+	}
+	fillLine(screen, height-1, width, barStyle)
+	drawText(screen, 0, height-1, width, footer, barStyle)
+	if b.searching {
+		screen.ShowCursor(len([]rune(footer)), height-1)
+	} else {
+		screen.HideCursor()
+	}
+
+	if b.picker != nil {
+		drawPicker(screen, b.picker, width, height)
+	}
+	screen.Show()
+}
+
+func drawEntry(screen tcell.Screen, y, width int, b *browser, e entry) {
+	f := b.formatter
+	dim := tcell.StyleDefault.Dim(true)
+	x := drawText(screen, 0, y, width, f.times.format(e.Timestamp), dim)
+
+	if f.shape == completeQuery {
+		x = drawText(screen, x, y, width, " | "+pad(e.Service, f.serviceWidth), tcell.StyleDefault)
+	}
+	levelStyle := tcell.StyleDefault.Foreground(tcellLevelColor(e.Level))
+	if f.shape != serviceLevelQuery {
+		x = drawText(screen, x, y, width, " | ", tcell.StyleDefault)
+		x = drawText(screen, x, y, width, pad(e.Level, f.levelWidth), levelStyle)
+	}
+	x = drawText(screen, x, y, width, " : ", tcell.StyleDefault)
+
+	textStyle := tcell.StyleDefault
+	if e.Level == "error" {
+		textStyle = levelStyle
+	}
+	start := 0
+	for _, position := range matchPositions(e.Text, b.search) {
+		x = drawText(screen, x, y, width, e.Text[start:position], textStyle)
+		start = position + len(b.search)
+		x = drawText(screen, x, y, width, e.Text[position:start], textStyle.Reverse(true))
+	}
+	x = drawText(screen, x, y, width, e.Text[start:], textStyle)
+
+	if fields := formatFields(e.Fields); fields != "" {
+		drawText(screen, x, y, width, " "+fields, dim)
+	}
+}
+
+func drawPicker(screen tcell.Screen, p *picker, width, height int) {
+	boxWidth := 40
+	boxHeight := len(p.items) + 2
+	if boxHeight > height-2 {
+		boxHeight = height - 2
+	}
+	left := (width - boxWidth) / 2
+	top := (height - boxHeight) / 2
+	style := tcell.StyleDefault.Reverse(true)
+
+	for y := top; y < top+boxHeight; y++ {
+		for x := left; x < left+boxWidth; x++ {
+			screen.SetContent(x, y, ' ', nil, style)
+		}
+	}
+	drawText(screen, left+1, top, left+boxWidth, "choose a service or level", style.Bold(true))
+
+	// scroll the list so the selected item stays visible
+	rows := boxHeight - 2
+	first := 0
+	if p.selected >= rows {
+		first = p.selected - rows + 1
+	}
+	for i := 0; i < rows && first+i < len(p.items); i++ {
+		itemStyle := style
+		if first+i == p.selected {
+			itemStyle = tcell.StyleDefault
+		}
+		drawText(screen, left+1, top+1+i, left+boxWidth-1, pad(p.items[first+i].label, boxWidth-2), itemStyle)
+	}
+}
+
+func fillLine(screen tcell.Screen, y, width int, style tcell.Style) {
+	for x := 0; x < width; x++ {
+		screen.SetContent(x, y, ' ', nil, style)
+	}
+}
+
+// drawText draws the text from x on, cut off at maxX, and returns the x after the text
+func drawText(screen tcell.Screen, x, y, maxX int, text string, style tcell.Style) int {
+	for _, r := range text {
+		if x >= maxX {
+			break
+		}
+		screen.SetContent(x, y, r, nil, style)
+		x++
+	}
+	return x
+}
+
+func tcellLevelColor(level string) tcell.Color {
+	switch level {
+	case "error":
+		return tcell.ColorRed
+	case "warning":
+		return tcell.ColorYellow
+	case "standard", "":
+		return tcell.ColorDefault
+	}
+	return tcellLevelPalette[levelPaletteIndex(level, len(tcellLevelPalette))]
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmorten/log"
+	"github.com/gdamore/tcell"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBrowser(t *testing.T) {
+	Convey("browser", t, func() {
+		now := time.Unix(100000, 0)
+		messages := []entry{}
+		for timestamp := int64(100000 - 3*3600); timestamp <= 100000; timestamp += 600 {
+			messages = append(messages, entry{Timestamp: timestamp, Text: "message"})
+		}
+		queries := []query{}
+		fetch := func(q query) (entries []entry, err error) {
+			queries = append(queries, q)
+			for _, e := range messages {
+				if e.Timestamp >= q.from.Unix() && e.Timestamp <= q.to.Unix() {
+					entries = append(entries, e)
+				}
+			}
+			return
+		}
+
+		b := newBrowser(fetch, newTimeFormatter("unix", true))
+		b.now = func() time.Time { return now }
+		b.resize(3)
+		So(b.reload(), ShouldBeNil)
+
+		Convey("shows the end of the latest page", func() {
+			So(len(b.entries), ShouldEqual, 7)
+			So(b.top, ShouldEqual, 4)
+			So(b.visible()[2].Timestamp, ShouldEqual, 100000)
+		})
+
+		Convey("loads older pages when scrolling up past the first message", func() {
+			b.scroll(-4)
+			So(b.top, ShouldEqual, 0)
+			So(len(queries), ShouldEqual, 1)
+
+			b.scroll(-1)
+			So(len(queries), ShouldEqual, 2)
+			So(queries[1].to.Unix(), ShouldEqual, 100000-3600-1)
+			So(len(b.entries), ShouldEqual, 13)
+			So(b.visible()[0].Timestamp, ShouldEqual, 100000-3600-600)
+		})
+
+		Convey("appends new messages when tailing", func() {
+			messages = append(messages, entry{Timestamp: 100000, Text: "same second"}, entry{Timestamp: 100100, Text: "new"})
+			now = now.Add(200 * time.Second)
+			b.toggleFollow()
+			So(b.follow, ShouldBeTrue)
+			So(len(b.entries), ShouldEqual, 9)
+			So(b.entries[7].Text, ShouldEqual, "same second")
+			So(b.visible()[2].Text, ShouldEqual, "new")
+		})
+
+		Convey("jumps between search matches", func() {
+			b.entries[1].Text = "some Error happened"
+			b.entries[5].Text = "another error"
+			b.search = "error"
+
+			So(b.findMatch(-1), ShouldBeTrue)
+			So(b.top, ShouldEqual, 1)
+			So(b.findMatch(-1), ShouldBeFalse)
+			b.top = 0
+			So(b.findMatch(1), ShouldBeTrue)
+			So(b.top, ShouldEqual, 1)
+		})
+
+		Convey("switches services with the picker", func() {
+			b.openPicker([]*log.ServiceInfo{
+				&log.ServiceInfo{Name: "service", Levels: []*log.LevelInfo{&log.LevelInfo{Name: "error"}}},
+			})
+			So(len(b.picker.items), ShouldEqual, 3)
+			b.picker.move(5)
+			So(b.pick(), ShouldBeNil)
+			So(b.picker, ShouldBeNil)
+			So(queries[len(queries)-1].service, ShouldEqual, "service")
+			So(queries[len(queries)-1].level, ShouldEqual, "error")
+			So(b.title(), ShouldEqual, "service / error")
+		})
+	})
+}
+
+func TestMatchPositions(t *testing.T) {
+	Convey("matchPositions finds case insensitive matches", t, func() {
+		So(matchPositions("Foo foo bar FOO", "foo"), ShouldResemble, []int{0, 4, 12})
+		So(matchPositions("Foo", ""), ShouldBeEmpty)
+		So(matchPositions("Foo", "bar"), ShouldBeEmpty)
+	})
+}
+
+func TestDrawBrowser(t *testing.T) {
+	Convey("drawBrowser", t, func() {
+		screen := tcell.NewSimulationScreen("UTF-8")
+		So(screen.Init(), ShouldBeNil)
+		defer screen.Fini()
+		screen.SetSize(60, 5)
+
+		fetch := func(q query) ([]entry, error) {
+			return []entry{
+				entry{Timestamp: 1, Service: "service", Level: "error", Text: "Foo failed"},
+				entry{Timestamp: 2, Service: "other", Level: "standard", Text: "Bar"},
+			}, nil
+		}
+		b := newBrowser(fetch, newTimeFormatter("unix", true))
+		b.reload()
+		b.resize(3)
+		b.search = "failed"
+		drawBrowser(screen, b)
+
+		line := func(y int) string {
+			cells, width, _ := screen.GetContents()
+			text := ""
+			for x := 0; x < width; x++ {
+				text += string(cells[y*width+x].Runes)
+			}
+			return strings.TrimRight(text, " ")
+		}
+		So(line(0), ShouldStartWith, " all services")
+		So(line(1), ShouldEqual, "1 | service | error    : Foo failed")
+		So(line(2), ShouldEqual, "2 | other   | standard : Bar")
+	})
+}
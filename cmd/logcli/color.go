@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+
+	"golang.org/x/term"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorDim    = "\x1b[2m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	// reverse video, so it works with any level color
+	colorHighlight = "\x1b[7m"
+)
+
+// custom levels get one of these, always the same one for the same level
+var levelPalette = []string{
+	"\x1b[32m",
+	"\x1b[34m",
+	"\x1b[35m",
+	"\x1b[36m",
+	"\x1b[92m",
+	"\x1b[94m",
+	"\x1b[95m",
+	"\x1b[96m",
+}
+
+// colorizer wraps text in ANSI color codes, or leaves it alone if colors are disabled
+type colorizer struct {
+	enabled bool
+}
+
+// newColorizer decides if colors are used, mode is one of auto, always and never.
+// auto uses colors if the output is a terminal and NO_COLOR is not set or empty.
+func newColorizer(mode string, output *os.File) (*colorizer, error) {
+	switch mode {
+	case "always":
+		return &colorizer{enabled: true}, nil
+	case "never":
+		return &colorizer{enabled: false}, nil
+	case "auto", "":
+		if os.Getenv("NO_COLOR") != "" {
+			return &colorizer{enabled: false}, nil
+		}
+		return &colorizer{enabled: isTerminal(output)}, nil
+	}
+	return nil, fmt.Errorf("unknown color mode %q, use auto, always or never", mode)
+}
+
+// isTerminal checks if the file is a terminal, character devices like /dev/null are not. Tests replace it.
+var isTerminal = func(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+func (c *colorizer) wrap(color, text string) string {
+	if !c.enabled || color == "" || text == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+func (c *colorizer) dim(text string) string {
+	return c.wrap(colorDim, text)
+}
+
+func (c *colorizer) level(level, text string) string {
+	return c.wrap(levelColor(level), text)
+}
+
+func levelColor(level string) string {
+	switch level {
+	case "error":
+		return colorRed
+	case "warning":
+		return colorYellow
+	case "standard", "":
+		return ""
+	}
+	return levelPalette[levelPaletteIndex(level, len(levelPalette))]
+}
+
+// levelPaletteIndex hashes custom levels into a palette of the given size
+func levelPaletteIndex(level string, size int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(level))
+	return int(hash.Sum32() % uint32(size))
+}
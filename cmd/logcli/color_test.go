@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestColorizer(t *testing.T) {
+	Convey("colorizer", t, func() {
+		Convey("colors levels when enabled", func() {
+			colors, err := newColorizer("always", os.Stdout)
+			So(err, ShouldBeNil)
+			So(colors.level("error", "error"), ShouldEqual, colorRed+"error"+colorReset)
+			So(colors.level("warning", "warning"), ShouldEqual, colorYellow+"warning"+colorReset)
+			So(colors.level("standard", "standard"), ShouldEqual, "standard")
+			So(levelColor("custom"), ShouldEqual, levelColor("custom"))
+			So(levelColor("custom"), ShouldNotBeEmpty)
+		})
+
+		Convey("leaves text alone when disabled", func() {
+			colors, err := newColorizer("never", os.Stdout)
+			So(err, ShouldBeNil)
+			So(colors.level("error", "error"), ShouldEqual, "error")
+		})
+
+		Convey("respects NO_COLOR in auto mode", func() {
+			os.Setenv("NO_COLOR", "1")
+			defer os.Unsetenv("NO_COLOR")
+			colors, err := newColorizer("auto", os.Stdout)
+			So(err, ShouldBeNil)
+			So(colors.enabled, ShouldBeFalse)
+		})
+
+		Convey("colors terminals in auto mode", func() {
+			defer func(original func(*os.File) bool) { isTerminal = original }(isTerminal)
+			isTerminal = func(*os.File) bool { return true }
+			colors, err := newColorizer("auto", os.Stdout)
+			So(err, ShouldBeNil)
+			So(colors.enabled, ShouldBeTrue)
+
+			Convey("unless NO_COLOR is set", func() {
+				os.Setenv("NO_COLOR", "1")
+				defer os.Unsetenv("NO_COLOR")
+				colors, err := newColorizer("auto", os.Stdout)
+				So(err, ShouldBeNil)
+				So(colors.enabled, ShouldBeFalse)
+			})
+
+			Convey("even if NO_COLOR is empty", func() {
+				os.Setenv("NO_COLOR", "")
+				defer os.Unsetenv("NO_COLOR")
+				colors, err := newColorizer("auto", os.Stdout)
+				So(err, ShouldBeNil)
+				So(colors.enabled, ShouldBeTrue)
+			})
+		})
+
+		Convey("doesn't color the null device", func() {
+			output, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+			So(err, ShouldBeNil)
+			defer output.Close()
+			colors, err := newColorizer("auto", output)
+			So(err, ShouldBeNil)
+			So(colors.enabled, ShouldBeFalse)
+		})
+
+		Convey("rejects unknown modes", func() {
+			_, err := newColorizer("sometimes", os.Stdout)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestLineFormatter(t *testing.T) {
+	Convey("lineFormatter aligns the columns", t, func() {
+		entries := []entry{
+			entry{Timestamp: 0, Service: "a", Level: "error", Text: "Foo"},
+			entry{Timestamp: 0, Service: "service", Level: "info", Text: "Bar", Fields: map[string]string{"b": "2", "a": "1"}},
+		}
+		formatter := newLineFormatter(entries, completeQuery, newTimeFormatter("unix", true), &colorizer{})
+		So(formatter.format(entries[0]), ShouldEqual, "0 | a       | error : Foo")
+		So(formatter.format(entries[1]), ShouldEqual, "0 | service | info  : Bar a=1 b=2")
+	})
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/alexmorten/log"
+	"github.com/gogo/protobuf/proto"
+)
+
+// queryShape is the kind of response the server returns, depending on the provided filters
+type queryShape int
+
+const (
+	completeQuery queryShape = iota
+	serviceQuery
+	serviceLevelQuery
+)
+
+func shapeFor(service, level, traceID string) queryShape {
+	if traceID != "" {
+		return completeQuery
+	}
+	if service != "" && level != "" {
+		return serviceLevelQuery
+	}
+	if service != "" {
+		return serviceQuery
+	}
+	return completeQuery
+}
+
+// entry is a log message out of any of the responses,
+// service and level are only set if the response contains them
+type entry struct {
+	Timestamp int64
+	Service   string
+	Level     string
+	Text      string
+	Fields    map[string]string
+	// Match and Group are only set for search results, messages of one group are next to each other in their service
+	Match bool
+	Group int
+}
+
+// readEntries decodes the response for the query shape
+func readEntries(r io.Reader, shape queryShape) (entries []entry, err error) {
+	bytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+
+	switch shape {
+	case serviceLevelQuery:
+		response := &log.GetServiceLevelResponse{}
+		if err = proto.Unmarshal(bytes, response); err != nil {
+			return
+		}
+		for _, message := range response.Messages {
+			entries = append(entries, newEntry(message.Message, "", ""))
+		}
+	case serviceQuery:
+		response := &log.GetServiceResponse{}
+		if err = proto.Unmarshal(bytes, response); err != nil {
+			return
+		}
+		for _, message := range response.Messages {
+			entries = append(entries, newEntry(message.Message, "", message.Level))
+		}
+	default:
+		response := &log.GetResponse{}
+		if err = proto.Unmarshal(bytes, response); err != nil {
+			return
+		}
+		for _, message := range response.Messages {
+			entries = append(entries, newEntry(message.Message, message.Service, message.Level))
+		}
+	}
+	return
+}
+
+func newEntry(m *log.Message, service, level string) entry {
+	return entry{
+		Timestamp: m.GetTimestamp(),
+		Service:   service,
+		Level:     level,
+		Text:      m.GetText(),
+		Fields:    m.GetFields(),
+	}
+}
+
+// lineFormatter prints entries with aligned service and level columns
+type lineFormatter struct {
+	shape        queryShape
+	times        *timeFormatter
+	colors       *colorizer
+	serviceWidth int
+	levelWidth   int
+	// search is highlighted in the text, messages that don't match it are marked as context
+	search string
+}
+
+// newLineFormatter pads the columns to the widest service and level of the entries
+func newLineFormatter(entries []entry, shape queryShape, times *timeFormatter, colors *colorizer) *lineFormatter {
+	f := &lineFormatter{
+		shape:  shape,
+		times:  times,
+		colors: colors,
+	}
+	for _, e := range entries {
+		f.fit(e)
+	}
+	return f
+}
+
+// fit widens the columns for the entry
+func (f *lineFormatter) fit(e entry) {
+	if len(e.Service) > f.serviceWidth {
+		f.serviceWidth = len(e.Service)
+	}
+	if len(e.Level) > f.levelWidth {
+		f.levelWidth = len(e.Level)
+	}
+}
+
+func (f *lineFormatter) format(e entry) string {
+	timestamp := f.colors.dim(f.times.format(e.Timestamp))
+	level := f.colors.level(e.Level, pad(e.Level, f.levelWidth))
+	text := f.text(e)
+	// like grep, context lines are separated from the text by a dash
+	separator := " : "
+	if f.search != "" && !e.Match {
+		separator = " - "
+	}
+
+	line := ""
+	switch f.shape {
+	case serviceLevelQuery:
+		line = timestamp + separator + text
+	case serviceQuery:
+		line = timestamp + " | " + level + separator + text
+	default:
+		line = timestamp + " | " + pad(e.Service, f.serviceWidth) + " | " + level + separator + text
+	}
+	if fields := formatFields(e.Fields); fields != "" {
+		line += " " + f.colors.dim(fields)
+	}
+	return line
+}
+
+// text colors errors and highlights the search
+func (f *lineFormatter) text(e entry) string {
+	color := ""
+	if e.Level == "error" {
+		color = levelColor(e.Level)
+	}
+	text := ""
+	start := 0
+	for _, position := range matchPositions(e.Text, f.search) {
+		text += f.colors.wrap(color, e.Text[start:position])
+		start = position + len(f.search)
+		text += f.colors.wrap(colorHighlight, e.Text[position:start])
+	}
+	return text + f.colors.wrap(color, e.Text[start:])
+}
+
+func pad(text string, width int) string {
+	for len(text) < width {
+		text += " "
+	}
+	return text
+}
+
+// fields are printed sorted by key, so that lines are comparable
+func formatFields(fields map[string]string) string {
+	pairs := []string{}
+	for _, key := range sortedKeys(fields) {
+		pairs = append(pairs, key+"="+fields[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+func sortedKeys(fields map[string]string) []string {
+	keys := []string{}
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexmorten/log"
+)
+
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+// histogram is the response of the server together with the query it was made for
+type histogram struct {
+	*log.GetHistogramResponse
+	query query
+}
+
+func runHistogram(args []string) error {
+	flags := flag.NewFlagSet("logcli histogram", flag.ContinueOnError)
+	serverFlags := &serverFlags{}
+	queryFlags := &queryFlags{}
+	outputFlags := &outputFlags{}
+	serverFlags.register(flags)
+	queryFlags.register(flags)
+	outputFlags.register(flags)
+	style := flags.String("style", "bars", "how the histogram is drawn: bars (one line per bucket, stacked by level) or sparkline (one line per service and level)")
+	buckets := flags.Int("buckets", 0, "number of buckets the time range is divided into (default 30 for bars, 60 for sparklines)")
+	bucket := flags.String("bucket", "", "size of the buckets, e.g. 5m or 1h (overrides buckets)")
+	width := flags.Int("width", 60, "width of the longest bar")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if queryFlags.traceID != "" {
+		return fmt.Errorf("histograms can't be restricted to a trace")
+	}
+	if *style != "bars" && *style != "sparkline" {
+		return fmt.Errorf("unknown style %q, use bars or sparkline", *style)
+	}
+	colors, err := newColorizer(outputFlags.colorMode, os.Stdout)
+	if err != nil {
+		return err
+	}
+	q, err := queryFlags.query(outputFlags.now())
+	if err != nil {
+		return err
+	}
+	params := q.params()
+	if *bucket != "" {
+		duration, err := parseDuration(*bucket)
+		if err != nil {
+			return err
+		}
+		if duration < time.Second {
+			return fmt.Errorf("buckets have to be at least a second long")
+		}
+		params.Set("bucket_seconds", strconv.FormatInt(int64(duration/time.Second), 10))
+	} else {
+		if *buckets == 0 {
+			*buckets = 30
+			if *style == "sparkline" {
+				*buckets = 60
+			}
+		}
+		params.Set("buckets", strconv.Itoa(*buckets))
+	}
+	serverURL, err := serverFlags.connect()
+	if err != nil {
+		return err
+	}
+
+	response := &log.GetHistogramResponse{}
+	if err := fetchProto(serverURL, "/histogram", params, response); err != nil {
+		return err
+	}
+	h := histogram{GetHistogramResponse: response, query: q}
+	times := outputFlags.timeFormatter()
+	if *style == "sparkline" {
+		return h.writeSparklines(os.Stdout, times, colors)
+	}
+	return h.writeBars(os.Stdout, *width, times, colors)
+}
+
+func (h histogram) bucketCount() int {
+	count := 0
+	for _, series := range h.Series {
+		if len(series.Counts) > count {
+			count = len(series.Counts)
+		}
+	}
+	return count
+}
+
+func (h histogram) bucketTime(bucket int) int64 {
+	return h.StartTime + int64(bucket)*h.BucketSeconds
+}
+
+func (h histogram) writeTitle(w io.Writer, times *timeFormatter) error {
+	if len(h.Series) == 0 {
+		_, err := fmt.Fprintln(w, "no messages")
+		return err
+	}
+	buckets := h.bucketCount()
+	_, err := fmt.Fprintf(w, "%v - %v, %v buckets of %v\n",
+		times.format(h.StartTime), times.format(h.bucketTime(buckets)-1), buckets, time.Duration(h.BucketSeconds)*time.Second)
+	return err
+}
+
+// countsPerLevel adds up the series of all services per level
+func (h histogram) countsPerLevel() (levels []string, counts map[string][]int64) {
+	counts = map[string][]int64{}
+	for _, series := range h.Series {
+		if counts[series.Level] == nil {
+			counts[series.Level] = make([]int64, h.bucketCount())
+			levels = append(levels, series.Level)
+		}
+		for i, count := range series.Counts {
+			counts[series.Level][i] += count
+		}
+	}
+	sort.Strings(levels)
+	return
+}
+
+// writeBars draws one bar per bucket, the bars are stacked by level and scaled to the fullest bucket
+func (h histogram) writeBars(w io.Writer, width int, times *timeFormatter, colors *colorizer) error {
+	if err := h.writeTitle(w, times); err != nil || len(h.Series) == 0 {
+		return err
+	}
+	levels, counts := h.countsPerLevel()
+
+	legend := []string{}
+	for _, level := range levels {
+		legend = append(legend, colors.level(level, "█ "+level)+" "+strconv.FormatInt(sum(counts[level]), 10))
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(legend, "  ")); err != nil {
+		return err
+	}
+
+	totals := make([]int64, h.bucketCount())
+	max := int64(0)
+	for _, level := range levels {
+		for i, count := range counts[level] {
+			totals[i] += count
+			if totals[i] > max {
+				max = totals[i]
+			}
+		}
+	}
+
+	for i, total := range totals {
+		bar := ""
+		stacked := int64(0)
+		drawn := 0
+		for _, level := range levels {
+			stacked += counts[level][i]
+			// rounding the stacked sums keeps the bar length independent of the number of levels
+			length := int((stacked*int64(width) + max/2) / max)
+			if length > drawn {
+				bar += colors.level(level, strings.Repeat("█", length-drawn))
+				drawn = length
+			}
+		}
+		if drawn == 0 && total > 0 {
+			bar = "▏"
+			drawn = 1
+		}
+		line := fmt.Sprintf("%v │%v%v %v", colors.dim(times.format(h.bucketTime(i))), bar, strings.Repeat(" ", width-drawn), total)
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSparklines draws one line per service and level, each scaled to its own fullest bucket
+func (h histogram) writeSparklines(w io.Writer, times *timeFormatter, colors *colorizer) error {
+	if err := h.writeTitle(w, times); err != nil || len(h.Series) == 0 {
+		return err
+	}
+	labels := []string{}
+	labelWidth := 0
+	for _, series := range h.Series {
+		label := series.Service + "/" + series.Level
+		if h.query.service != "" {
+			label = series.Level
+		}
+		labels = append(labels, label)
+		if len(label) > labelWidth {
+			labelWidth = len(label)
+		}
+	}
+
+	for i, series := range h.Series {
+		line := colors.level(series.Level, pad(labels[i], labelWidth)) + "  " +
+			colors.level(series.Level, sparkline(series.Counts)) + "  " + strconv.FormatInt(sum(series.Counts), 10)
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sparkline draws a rune per count, empty buckets are blank
+func sparkline(counts []int64) string {
+	max := int64(0)
+	for _, count := range counts {
+		if count > max {
+			max = count
+		}
+	}
+	runes := make([]rune, len(counts))
+	for i, count := range counts {
+		if count == 0 {
+			runes[i] = ' '
+			continue
+		}
+		runes[i] = sparkRunes[(count*int64(len(sparkRunes))-1)/max]
+	}
+	return string(runes)
+}
+
+func sum(counts []int64) (total int64) {
+	for _, count := range counts {
+		total += count
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alexmorten/log"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHistogram(t *testing.T) {
+	Convey("histogram", t, func() {
+		times := newTimeFormatter("15:04", true)
+		h := histogram{GetHistogramResponse: &log.GetHistogramResponse{
+			StartTime:     1526567400,
+			BucketSeconds: 60,
+			Series: []*log.HistogramSeries{
+				&log.HistogramSeries{Service: "api", Level: "error", Counts: []int64{0, 2, 8}},
+				&log.HistogramSeries{Service: "api", Level: "standard", Counts: []int64{1, 2, 0}},
+				&log.HistogramSeries{Service: "worker", Level: "standard", Counts: []int64{1, 0, 0}},
+			},
+		}}
+
+		Convey("draws stacked bars per bucket", func() {
+			buffer := &bytes.Buffer{}
+			So(h.writeBars(buffer, 8, times, &colorizer{}), ShouldBeNil)
+			So(buffer.String(), ShouldEqual,
+				"14:30 - 14:32, 3 buckets of 1m0s\n"+
+					"█ error 10  █ standard 4\n"+
+					"14:30 │██       2\n"+
+					"14:31 │████     4\n"+
+					"14:32 │████████ 8\n")
+		})
+
+		Convey("draws a sparkline per service and level", func() {
+			buffer := &bytes.Buffer{}
+			So(h.writeSparklines(buffer, times, &colorizer{}), ShouldBeNil)
+			So(buffer.String(), ShouldEqual,
+				"14:30 - 14:32, 3 buckets of 1m0s\n"+
+					"api/error         ▂█  10\n"+
+					"api/standard     ▄█   3\n"+
+					"worker/standard  █    1\n")
+		})
+
+		Convey("reports empty histograms", func() {
+			buffer := &bytes.Buffer{}
+			empty := histogram{GetHistogramResponse: &log.GetHistogramResponse{}}
+			So(empty.writeBars(buffer, 8, times, &colorizer{}), ShouldBeNil)
+			So(buffer.String(), ShouldEqual, "no messages\n")
+		})
+	})
+
+	Convey("sparkline", t, func() {
+		So(sparkline([]int64{0, 1, 4, 8}), ShouldEqual, " ▁▄█")
+	})
+}
@@ -3,115 +3,169 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"net/url"
-	"strconv"
-
-	"github.com/alexmorten/log"
-	"github.com/gogo/protobuf/proto"
+	"io"
+	"os"
+	"time"
 )
 
-var service, level, serverURL string
-var fromTime, toTime int64
+// commands are called with the arguments after their name,
+// without a known command logcli queries and prints messages
+var commands = map[string]func(args []string) error{
+	"browse":    runBrowse,
+	"services":  runServices,
+	"levels":    runLevels,
+	"send":      runSend,
+	"run":       runRun,
+	"histogram": runHistogram,
+	"usage":     runUsage,
+}
 
 func main() {
-	flag.StringVar(&service, "service", "", "restrict output to log messages from the provided service")
-	flag.StringVar(&level, "level", "", "restrict output to log messages on the provided level (can only be used together with a service)")
-	flag.StringVar(&serverURL, "url", "http://localhost:7654", "url of the log server")
-	flag.Int64Var(&fromTime, "from", 0, "look for logs after this point in time")
-	flag.Int64Var(&toTime, "to", 0, "look for logs before this point in time")
-	flag.Parse()
-	u, err := url.Parse(serverURL)
-	if err != nil {
-		fmt.Println(err)
-		return
+	run := runQuery
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if command, ok := commands[args[0]]; ok {
+			run = command
+			args = args[1:]
+		}
 	}
-	params := u.Query()
-	if service != "" {
-		params.Set("service", service)
-		if level != "" {
-			params.Set("level", level)
+	if err := run(args); err != nil {
+		if code, ok := err.(exitCode); ok {
+			os.Exit(int(code))
 		}
-	} else if level != "" {
-		fmt.Println("you can only use the level flag if you also provide a service!")
-		return
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
 	}
+}
 
-	if fromTime != 0 {
-		params.Add("from_time", strconv.FormatInt(fromTime, 10))
-	}
-	if toTime != 0 {
-		params.Add("to_time", strconv.FormatInt(toTime, 10))
-	}
-	u.RawQuery = params.Encode()
-	fmt.Println("Getting from ", u.String())
-	resp, err := http.Get(u.String())
+// queryFlags select the messages that are requested from the server
+type queryFlags struct {
+	service, level, traceID string
+	from, to, since         string
+}
 
-	if err != nil {
-		fmt.Println(err)
+func (f *queryFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.service, "service", "", "restrict output to log messages from the provided service")
+	flags.StringVar(&f.level, "level", "", "restrict output to log messages on the provided level (can only be used together with a service)")
+	flags.StringVar(&f.traceID, "trace", "", "show the log messages of all services that belong to the provided trace id")
+	flags.StringVar(&f.from, "from", "", "look for logs after this point in time ("+timeExpressionHelp+")")
+	flags.StringVar(&f.to, "to", "", "look for logs before this point in time ("+timeExpressionHelp+")")
+	flags.StringVar(&f.since, "since", "", "look for logs in the given duration up to now, e.g. 2h or 3d (can't be used together with from)")
+}
+
+func (f *queryFlags) query(now time.Time) (q query, err error) {
+	if f.level != "" && f.service == "" && f.traceID == "" {
+		err = fmt.Errorf("you can only use the level flag if you also provide a service")
 		return
 	}
-	fmt.Println(resp.Status)
-	if service != "" && level != "" {
-		handleServiceLevelResponse(resp)
-	} else if service != "" {
-		handleServiceResponse(resp)
-	} else {
-		handleCompleteResponse(resp)
+	q = query{
+		service: f.service,
+		level:   f.level,
+		traceID: f.traceID,
 	}
+	q.from, q.to, err = parseTimeRange(f.from, f.to, f.since, now)
+	return
 }
 
-func handleCompleteResponse(r *http.Response) {
-	response := &log.GetResponse{}
-	bytes, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
+// outputFlags change how messages are printed
+type outputFlags struct {
+	timeFormat, colorMode string
+	utc                   bool
+}
 
-	if err := proto.Unmarshal(bytes, response); err != nil {
-		fmt.Println(err)
-		return
-	}
+func (f *outputFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.timeFormat, "time-format", "", "format of the printed timestamps: a go time layout, rfc3339 or unix (default \""+defaultTimeLayout+"\")")
+	flags.BoolVar(&f.utc, "utc", false, "use UTC instead of local time to print timestamps and interpret times without a time zone")
+	flags.StringVar(&f.colorMode, "color", "auto", "color the output by level: auto, always or never (auto respects NO_COLOR)")
+}
 
-	for _, message := range response.Messages {
-		fmt.Printf("%v | %v | %v : %v \n", message.Message.Timestamp, message.Service, message.Level, message.Message.Text)
+func (f *outputFlags) now() time.Time {
+	if f.utc {
+		return time.Now().UTC()
 	}
+	return time.Now()
 }
 
-func handleServiceResponse(r *http.Response) {
-	response := &log.GetServiceResponse{}
-	bytes, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		fmt.Println(err)
-		return
+func (f *outputFlags) timeFormatter() *timeFormatter {
+	return newTimeFormatter(f.timeFormat, f.utc)
+}
+
+// machineTimeFormatter is used for machine readable output, it defaults to RFC3339
+func (f *outputFlags) machineTimeFormatter() *timeFormatter {
+	if f.timeFormat == "" {
+		return newTimeFormatter("rfc3339", f.utc)
 	}
+	return f.timeFormatter()
+}
 
-	if err := proto.Unmarshal(bytes, response); err != nil {
+func runQuery(args []string) error {
+	flags := flag.NewFlagSet("logcli", flag.ContinueOnError)
+	queryFlags := &queryFlags{}
+	outputFlags := &outputFlags{}
+	formatFlags := &formatFlags{}
+	serverFlags := &serverFlags{}
+	searchFlags := &searchFlags{}
+	serverFlags.register(flags)
+	queryFlags.register(flags)
+	searchFlags.register(flags)
+	outputFlags.register(flags)
+	formatFlags.register(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	err := queryMessages(queryFlags, outputFlags, formatFlags, serverFlags, searchFlags)
+	// the text output reports errors like logcli did before it had commands, on stdout and without failing
+	if err != nil && formatFlags.format == "text" {
 		fmt.Println(err)
-		return
+		return nil
 	}
+	return err
+}
 
-	for _, message := range response.Messages {
-		fmt.Printf("%v | %v : %v \n", message.Message.Timestamp, message.Level, message.Message.Text)
+// queryMessages prints the messages of the query or search, the text output starts with the url and the response status
+func queryMessages(queryFlags *queryFlags, outputFlags *outputFlags, formatFlags *formatFlags, serverFlags *serverFlags, searchFlags *searchFlags) error {
+	if searchFlags.text == "" && (searchFlags.before != 0 || searchFlags.after != 0 || searchFlags.context != 0) {
+		return fmt.Errorf("context messages can only be shown for a search")
+	}
+	if searchFlags.text != "" && queryFlags.traceID != "" {
+		return fmt.Errorf("the messages of a trace can't be searched")
 	}
-}
 
-func handleServiceLevelResponse(r *http.Response) {
-	response := &log.GetServiceLevelResponse{}
-	bytes, err := ioutil.ReadAll(r.Body)
+	colors, err := newColorizer(outputFlags.colorMode, os.Stdout)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+	q, err := queryFlags.query(outputFlags.now())
+	if err != nil {
+		return err
 	}
 
-	if err := proto.Unmarshal(bytes, response); err != nil {
-		fmt.Println(err)
-		return
+	serverURL, err := serverFlags.connect()
+	if err != nil {
+		return err
+	}
+	if searchFlags.text != "" {
+		entries, err := fetchSearch(serverURL, q, searchFlags)
+		if err != nil {
+			return err
+		}
+		text := newLineFormatter(entries, searchShape(q), outputFlags.timeFormatter(), colors)
+		text.search = searchFlags.text
+		return writeEntries(os.Stdout, entries, formatFlags, searchShape(q), text, outputFlags.machineTimeFormatter())
 	}
 
-	for _, message := range response.Messages {
-		fmt.Printf("%v : %v \n", message.Message.Timestamp, message.Message.Text)
+	var status io.Writer
+	if formatFlags.format == "text" {
+		status = os.Stdout
+	}
+	entries, err := fetchEntries(serverURL, q, status)
+	if err != nil {
+		return err
 	}
+	q.fillIn(entries)
+	return writeEntries(os.Stdout, entries, formatFlags, q.shape(),
+		newLineFormatter(entries, q.shape(), outputFlags.timeFormatter(), colors),
+		outputFlags.machineTimeFormatter())
 }
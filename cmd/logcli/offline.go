@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/alexmorten/log"
+)
+
+// serverFlags select where messages are read from, a log server or a local data directory
+type serverFlags struct {
+	url, token, dataDir string
+	tls                 tlsFlags
+}
+
+func (f *serverFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.url, "url", "http://localhost:7654", "url of the log server")
+	flags.StringVar(&f.token, "token", "", "token that is sent to the server as bearer authorization")
+	flags.StringVar(&f.dataDir, "data-dir", "", "read the block files in this data directory instead of asking a server")
+	f.tls.register(flags)
+}
+
+// connect prepares the requests to the server, with a data directory they are answered in process.
+// It returns the url that requests should be sent to.
+func (f *serverFlags) connect() (string, error) {
+	bearerToken = f.token
+	if f.dataDir == "" {
+		client, err := f.tls.client()
+		if err != nil {
+			return "", err
+		}
+		httpClient = client
+		return f.url, nil
+	}
+	info, err := os.Stat(f.dataDir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%v is not a directory", f.dataDir)
+	}
+	log.SetDataPath(f.dataDir)
+	httpClient = &http.Client{Transport: newOfflineTransport()}
+	return "http://offline", nil
+}
+
+// offlineTransport answers requests with a server that only reads from the data directory,
+// so offline queries take the same code paths as the ones to a running server
+type offlineTransport struct {
+	server *log.Server
+}
+
+func newOfflineTransport() *offlineTransport {
+	return &offlineTransport{
+		server: &log.Server{Reader: log.NewReader(&log.FileReader{})},
+	}
+}
+
+func (t *offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return nil, fmt.Errorf("a data directory can only be read")
+	}
+	recorder := httptest.NewRecorder()
+	t.server.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
+}
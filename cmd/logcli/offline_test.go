@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alexmorten/log"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOfflineQueries(t *testing.T) {
+	Convey("offline queries", t, func() {
+		dataDir, err := ioutil.TempDir("", "logcli")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dataDir)
+		defer func() { httpClient = http.DefaultClient }()
+
+		log.SetDataPath(dataDir)
+		blocks := []*log.Block{
+			&log.Block{StartTime: 5002, EndTime: 5003, Service: "api", Level: "error", Messages: []*log.Message{
+				&log.Message{Text: "Foo", Timestamp: 5002},
+				&log.Message{Text: "Bar", Timestamp: 5003, Fields: map[string]string{log.TraceIDField: "abc"}},
+			}},
+			&log.Block{StartTime: 5004, EndTime: 5004, Service: "worker", Level: "standard", Messages: []*log.Message{
+				&log.Message{Text: "Baz", Timestamp: 5004, Fields: map[string]string{log.TraceIDField: "abc"}},
+			}},
+		}
+		for _, b := range blocks {
+			So(b.WriteToFile(), ShouldBeNil)
+		}
+
+		serverURL, err := (&serverFlags{dataDir: dataDir}).connect()
+		So(err, ShouldBeNil)
+		q := query{from: time.Unix(5000, 0), to: time.Unix(6000, 0)}
+
+		Convey("read messages from the data directory", func() {
+			entries, err := fetchEntries(serverURL, q, nil)
+			So(err, ShouldBeNil)
+			So(len(entries), ShouldEqual, 3)
+			So(entries[2].Service, ShouldEqual, "worker")
+
+			q.service = "api"
+			q.level = "error"
+			entries, err = fetchEntries(serverURL, q, nil)
+			So(err, ShouldBeNil)
+			So(len(entries), ShouldEqual, 2)
+
+			entries, err = fetchEntries(serverURL, query{traceID: "abc", from: q.from, to: q.to}, nil)
+			So(err, ShouldBeNil)
+			So(len(entries), ShouldEqual, 2)
+		})
+
+		Convey("print the url and the response status", func() {
+			status := &bytes.Buffer{}
+			_, err := fetchEntries(serverURL, query{service: "worker", from: q.from, to: q.to}, status)
+			So(err, ShouldBeNil)
+			So(status.String(), ShouldStartWith, "Getting from  http://offline/api/v1/messages?")
+			So(status.String(), ShouldEndWith, "\n200 OK\n")
+		})
+
+		Convey("list services and levels", func() {
+			services, err := fetchServices(serverURL)
+			So(err, ShouldBeNil)
+			So(len(services), ShouldEqual, 2)
+			So(services[0].MessageCount, ShouldEqual, 2)
+
+			levels, err := fetchLevels(serverURL, "unknown")
+			So(err, ShouldBeNil)
+			So(levels, ShouldBeEmpty)
+		})
+
+		Convey("reject missing directories", func() {
+			_, err := (&serverFlags{dataDir: dataDir + "/missing"}).connect()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
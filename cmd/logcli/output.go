@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const outputFormatHelp = "text, json, ndjson, csv, logfmt or raw"
+
+// columns that can be selected for csv output, besides field.<name> for single message fields
+const columnHelp = "timestamp, time, service, level, text, fields, field.<name>"
+
+// formatFlags select the output format
+type formatFlags struct {
+	format  string
+	columns string
+	header  bool
+}
+
+func (f *formatFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.format, "output", "text", "output format: "+outputFormatHelp)
+	flags.StringVar(&f.columns, "columns", "", "comma separated csv columns: "+columnHelp+" (default depends on the query)")
+	flags.BoolVar(&f.header, "header", true, "print a header line with the column names in csv output")
+}
+
+// jsonEntry is how entries are serialized for json and ndjson output
+type jsonEntry struct {
+	Timestamp int64             `json:"timestamp"`
+	Time      string            `json:"time"`
+	Service   string            `json:"service,omitempty"`
+	Level     string            `json:"level,omitempty"`
+	Text      string            `json:"text"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Match     bool              `json:"match,omitempty"`
+}
+
+// writeEntries writes the entries in the output format of the flags,
+// text is printed by the lineFormatter, all other formats use machineTimes for their time values
+func writeEntries(w io.Writer, entries []entry, f *formatFlags, shape queryShape, text *lineFormatter, machineTimes *timeFormatter) error {
+	switch f.format {
+	case "text", "":
+		for i, e := range entries {
+			// search results are separated into their groups like grep does
+			if i > 0 && e.Group != entries[i-1].Group {
+				if _, err := fmt.Fprintln(w, text.colors.dim("--")); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(w, text.format(e)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "raw":
+		for _, e := range entries {
+			if _, err := fmt.Fprintln(w, e.Text); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		jsonEntries := []jsonEntry{}
+		for _, e := range entries {
+			jsonEntries = append(jsonEntries, newJSONEntry(e, machineTimes))
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(jsonEntries)
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := encoder.Encode(newJSONEntry(e, machineTimes)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "logfmt":
+		for _, e := range entries {
+			if _, err := fmt.Fprintln(w, formatLogfmt(e, machineTimes)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		return writeCSV(w, entries, f, shape, machineTimes)
+	}
+	return fmt.Errorf("unknown output format %q, use one of %v", f.format, outputFormatHelp)
+}
+
+func newJSONEntry(e entry, times *timeFormatter) jsonEntry {
+	return jsonEntry{
+		Timestamp: e.Timestamp,
+		Time:      times.format(e.Timestamp),
+		Service:   e.Service,
+		Level:     e.Level,
+		Text:      e.Text,
+		Fields:    e.Fields,
+		Match:     e.Match,
+	}
+}
+
+func defaultColumns(shape queryShape) []string {
+	switch shape {
+	case serviceLevelQuery:
+		return []string{"time", "text", "fields"}
+	case serviceQuery:
+		return []string{"time", "level", "text", "fields"}
+	}
+	return []string{"time", "service", "level", "text", "fields"}
+}
+
+func writeCSV(w io.Writer, entries []entry, f *formatFlags, shape queryShape, times *timeFormatter) error {
+	columns := defaultColumns(shape)
+	if f.columns != "" {
+		columns = strings.Split(f.columns, ",")
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+		}
+	}
+	for _, column := range columns {
+		if _, err := columnValue(entry{}, column, times); err != nil {
+			return err
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if f.header {
+		writer.Write(columns)
+	}
+	for _, e := range entries {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i], _ = columnValue(e, column, times)
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func columnValue(e entry, column string, times *timeFormatter) (string, error) {
+	switch column {
+	case "timestamp":
+		return strconv.FormatInt(e.Timestamp, 10), nil
+	case "time":
+		return times.format(e.Timestamp), nil
+	case "service":
+		return e.Service, nil
+	case "level":
+		return e.Level, nil
+	case "text":
+		return e.Text, nil
+	case "fields":
+		return formatFields(e.Fields), nil
+	}
+	if strings.HasPrefix(column, "field.") {
+		return e.Fields[strings.TrimPrefix(column, "field.")], nil
+	}
+	return "", fmt.Errorf("unknown column %q, use one of %v", column, columnHelp)
+}
+
+// formatLogfmt prints the entry as key=value pairs, fields follow the fixed keys sorted by name
+func formatLogfmt(e entry, times *timeFormatter) string {
+	pairs := []string{"time=" + logfmtValue(times.format(e.Timestamp))}
+	if e.Service != "" {
+		pairs = append(pairs, "service="+logfmtValue(e.Service))
+	}
+	if e.Level != "" {
+		pairs = append(pairs, "level="+logfmtValue(e.Level))
+	}
+	pairs = append(pairs, "msg="+logfmtValue(e.Text))
+	for _, key := range sortedKeys(e.Fields) {
+		pairs = append(pairs, logfmtKey(key)+"="+logfmtValue(e.Fields[key]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// logfmtReservedKeys are written for every entry, fields with these names get the field. prefix of the csv columns
+var logfmtReservedKeys = map[string]bool{"time": true, "service": true, "level": true, "msg": true}
+
+// keys can't be quoted, so spaces, quotes, equal signs and control characters are replaced with underscores
+func logfmtKey(key string) string {
+	key = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, key)
+	if key == "" {
+		return "_"
+	}
+	if logfmtReservedKeys[key] {
+		return "field." + key
+	}
+	return key
+}
+
+// values with spaces, quotes or equal signs are quoted
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteEntries(t *testing.T) {
+	Convey("writeEntries", t, func() {
+		entries := []entry{
+			entry{Timestamp: 1526567400, Service: "service", Level: "error", Text: "Foo failed", Fields: map[string]string{"trace_id": "abc"}},
+			entry{Timestamp: 1526567401, Service: "service", Level: "standard", Text: `Bar "quoted"`},
+		}
+		times := newTimeFormatter("rfc3339", true)
+		text := newLineFormatter(entries, completeQuery, times, &colorizer{})
+		write := func(f *formatFlags, shape queryShape) string {
+			buffer := &bytes.Buffer{}
+			So(writeEntries(buffer, entries, f, shape, text, times), ShouldBeNil)
+			return buffer.String()
+		}
+
+		Convey("as json", func() {
+			So(write(&formatFlags{format: "ndjson"}, completeQuery), ShouldEqual,
+				`{"timestamp":1526567400,"time":"2018-05-17T14:30:00Z","service":"service","level":"error","text":"Foo failed","fields":{"trace_id":"abc"}}`+"\n"+
+					`{"timestamp":1526567401,"time":"2018-05-17T14:30:01Z","service":"service","level":"standard","text":"Bar \"quoted\""}`+"\n")
+			So(write(&formatFlags{format: "json"}, completeQuery), ShouldStartWith, "[\n  {\n")
+		})
+
+		Convey("as csv", func() {
+			So(write(&formatFlags{format: "csv", header: true}, serviceQuery), ShouldEqual,
+				"time,level,text,fields\n"+
+					"2018-05-17T14:30:00Z,error,Foo failed,trace_id=abc\n"+
+					"2018-05-17T14:30:01Z,standard,\"Bar \"\"quoted\"\"\",\n")
+			So(write(&formatFlags{format: "csv", columns: "timestamp, field.trace_id"}, completeQuery), ShouldEqual,
+				"1526567400,abc\n1526567401,\n")
+
+			err := writeEntries(&bytes.Buffer{}, entries, &formatFlags{format: "csv", columns: "foo"}, completeQuery, text, times)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("as logfmt", func() {
+			So(write(&formatFlags{format: "logfmt"}, completeQuery), ShouldEqual,
+				"time=2018-05-17T14:30:00Z service=service level=error msg=\"Foo failed\" trace_id=abc\n"+
+					"time=2018-05-17T14:30:01Z service=service level=standard msg=\"Bar \\\"quoted\\\"\"\n")
+		})
+
+		Convey("as logfmt with keys that need to be changed", func() {
+			So(formatLogfmt(entry{Timestamp: 1526567400, Text: "Foo", Fields: map[string]string{
+				"level":     "debug",
+				"user name": "a",
+				"a=b":       "c",
+				`"quoted"`:  "d",
+			}}, times), ShouldEqual, `time=2018-05-17T14:30:00Z msg=Foo _quoted_=d a_b=c field.level=debug user_name=a`)
+		})
+
+		Convey("as raw text", func() {
+			So(write(&formatFlags{format: "raw"}, completeQuery), ShouldEqual, "Foo failed\nBar \"quoted\"\n")
+		})
+
+		Convey("rejects unknown formats", func() {
+			err := writeEntries(&bytes.Buffer{}, entries, &formatFlags{format: "xml"}, completeQuery, text, times)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	profileEnv = "LOGCLI_PROFILE"
+	configEnv  = "LOGCLI_CONFIG"
+)
+
+// settings that a profile may hold, they are named like the flags they set
+var profileKeys = map[string]bool{
+	"url":         true,
+	"token":       true,
+	"data-dir":    true,
+	"ca":          true,
+	"cert":        true,
+	"key":         true,
+	"service":     true,
+	"level":       true,
+	"since":       true,
+	"from":        true,
+	"to":          true,
+	"output":      true,
+	"columns":     true,
+	"header":      true,
+	"time-format": true,
+	"utc":         true,
+	"color":       true,
+}
+
+// profiles maps profile names to their settings
+type profiles map[string]map[string]string
+
+// parseFlags parses the arguments and fills in the flags that weren't set from the selected profile,
+// it is used instead of flags.Parse by every command
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	profileName := flags.String("profile", "", "use the settings of this profile from the config file (default $"+profileEnv+", then \"default\")")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	name := *profileName
+	if name == "" {
+		name = os.Getenv(profileEnv)
+	}
+	explicit := name != ""
+	if !explicit {
+		name = "default"
+	}
+
+	path := configPath()
+	all, err := loadProfiles(path)
+	if err != nil {
+		return err
+	}
+	settings, ok := all[name]
+	if !ok {
+		if explicit {
+			return fmt.Errorf("profile %q not found in %v", name, path)
+		}
+		return nil
+	}
+	return applyProfile(flags, settings)
+}
+
+// configPath is $LOGCLI_CONFIG or logcli/config in the user config directory
+func configPath() string {
+	if path := os.Getenv(configEnv); path != "" {
+		return path
+	}
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(configDir, "logcli", "config")
+}
+
+// loadProfiles reads the config file, a missing file has no profiles
+func loadProfiles(path string) (profiles, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return profiles{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	all, err := parseProfiles(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return all, nil
+}
+
+// parseProfiles reads [profile] sections with key = value lines, lines starting with # or ; are comments
+func parseProfiles(r io.Reader) (profiles, error) {
+	all := profiles{}
+	var current map[string]string
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if all[name] == nil {
+				all[name] = map[string]string{}
+			}
+			current = all[name]
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %v: expected key = value", lineNumber)
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %v: settings have to be part of a [profile]", lineNumber)
+		}
+		key := strings.TrimSpace(parts[0])
+		if !profileKeys[key] {
+			return nil, fmt.Errorf("line %v: unknown setting %q", lineNumber, key)
+		}
+		current[key] = unquote(strings.TrimSpace(parts[1]))
+	}
+	return all, scanner.Err()
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == value[len(value)-1] && (value[0] == '"' || value[0] == '\'') {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// applyProfile sets the flags of the command that weren't given on the command line,
+// settings for flags the command doesn't have are skipped
+func applyProfile(flags *flag.FlagSet, settings map[string]string) error {
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for _, key := range sortedKeys(settings) {
+		if explicit[key] || flags.Lookup(key) == nil {
+			continue
+		}
+		// a time window from the command line replaces the one of the profile
+		if (key == "since" && explicit["from"]) || (key == "from" && explicit["since"]) {
+			continue
+		}
+		if err := flags.Set(key, settings[key]); err != nil {
+			return fmt.Errorf("profile setting %v: %v", key, err)
+		}
+	}
+	return nil
+}
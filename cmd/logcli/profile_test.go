@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testConfig = `
+# servers we switch between
+[default]
+url = http://localhost:7654
+
+[production]
+url = "https://logs.example.com"
+token = secret
+service = api
+since = 2h
+output = json
+utc = true
+`
+
+func TestProfiles(t *testing.T) {
+	Convey("parseProfiles", t, func() {
+		Convey("reads profile sections", func() {
+			all, err := parseProfiles(strings.NewReader(testConfig))
+			So(err, ShouldBeNil)
+			So(all["default"], ShouldResemble, map[string]string{"url": "http://localhost:7654"})
+			So(all["production"]["url"], ShouldEqual, "https://logs.example.com")
+			So(all["production"]["since"], ShouldEqual, "2h")
+		})
+
+		Convey("rejects unknown settings and settings outside of a profile", func() {
+			_, err := parseProfiles(strings.NewReader("[default]\nurll = http://localhost"))
+			So(err, ShouldNotBeNil)
+			_, err = parseProfiles(strings.NewReader("url = http://localhost"))
+			So(err, ShouldNotBeNil)
+			_, err = parseProfiles(strings.NewReader("[default]\nurl"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("parseFlags", t, func() {
+		dir, err := ioutil.TempDir("", "logcli")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "config")
+		So(ioutil.WriteFile(path, []byte(testConfig), 0600), ShouldBeNil)
+		os.Setenv(configEnv, path)
+		defer os.Unsetenv(configEnv)
+
+		newFlags := func() (*flag.FlagSet, *serverFlags, *queryFlags, *formatFlags) {
+			flags := flag.NewFlagSet("logcli", flag.ContinueOnError)
+			flags.SetOutput(ioutil.Discard)
+			serverFlags := &serverFlags{}
+			queryFlags := &queryFlags{}
+			formatFlags := &formatFlags{}
+			serverFlags.register(flags)
+			queryFlags.register(flags)
+			formatFlags.register(flags)
+			return flags, serverFlags, queryFlags, formatFlags
+		}
+
+		Convey("uses the default profile without a selection", func() {
+			flags, serverFlags, queryFlags, _ := newFlags()
+			So(parseFlags(flags, []string{}), ShouldBeNil)
+			So(serverFlags.url, ShouldEqual, "http://localhost:7654")
+			So(queryFlags.service, ShouldEqual, "")
+		})
+
+		Convey("fills in the selected profile, flags override it", func() {
+			flags, serverFlags, queryFlags, formatFlags := newFlags()
+			So(parseFlags(flags, []string{"-profile", "production", "-service", "worker", "-from", "yesterday"}), ShouldBeNil)
+			So(serverFlags.url, ShouldEqual, "https://logs.example.com")
+			So(serverFlags.token, ShouldEqual, "secret")
+			So(formatFlags.format, ShouldEqual, "json")
+			So(queryFlags.service, ShouldEqual, "worker")
+			So(queryFlags.since, ShouldEqual, "")
+		})
+
+		Convey("selects the profile from the environment", func() {
+			os.Setenv(profileEnv, "production")
+			defer os.Unsetenv(profileEnv)
+			flags, _, queryFlags, _ := newFlags()
+			So(parseFlags(flags, []string{}), ShouldBeNil)
+			So(queryFlags.since, ShouldEqual, "2h")
+		})
+
+		Convey("fails for unknown profiles", func() {
+			flags, _, _, _ := newFlags()
+			So(parseFlags(flags, []string{"-profile", "staging"}), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexmorten/log"
+	"github.com/gogo/protobuf/proto"
+)
+
+// query describes the messages that are requested from the server,
+// zero times are left to the server defaults
+type query struct {
+	service, level, traceID string
+	from, to                time.Time
+}
+
+func (q query) shape() queryShape {
+	return shapeFor(q.service, q.level, q.traceID)
+}
+
+// fillIn sets the service and level that the response shape of the query leaves out
+func (q query) fillIn(entries []entry) {
+	for i := range entries {
+		if entries[i].Service == "" {
+			entries[i].Service = q.service
+		}
+		if entries[i].Level == "" {
+			entries[i].Level = q.level
+		}
+	}
+}
+
+func (q query) url(serverURL string) (string, error) {
+	u, err := apiURL(serverURL, "/messages")
+	if err != nil {
+		return "", err
+	}
+	params := u.Query()
+	for key, values := range q.params() {
+		params[key] = values
+	}
+	u.RawQuery = params.Encode()
+	return u.String(), nil
+}
+
+func (q query) params() url.Values {
+	params := url.Values{}
+	if q.traceID != "" {
+		params.Set("trace_id", q.traceID)
+	} else if q.service != "" {
+		params.Set("service", q.service)
+		if q.level != "" {
+			params.Set("level", q.level)
+		}
+	}
+	if !q.from.IsZero() {
+		params.Set("from_time", strconv.FormatInt(q.from.Unix(), 10))
+	}
+	if !q.to.IsZero() {
+		params.Set("to_time", strconv.FormatInt(q.to.Unix(), 10))
+	}
+	return params
+}
+
+// fetchEntries requests the messages of the query from the server,
+// the url and the response status are printed to status unless it is nil
+func fetchEntries(serverURL string, q query, status io.Writer) ([]entry, error) {
+	u, err := q.url(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	if status != nil {
+		fmt.Fprintln(status, "Getting from ", u)
+	}
+	resp, err := get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if status != nil {
+		fmt.Fprintln(status, resp.Status)
+	}
+	return readEntries(resp.Body, q.shape())
+}
+
+// fetchServices requests the known services and their levels from the server
+func fetchServices(serverURL string) ([]*log.ServiceInfo, error) {
+	response := &log.GetServicesResponse{}
+	if err := fetchProto(serverURL, "/services", url.Values{}, response); err != nil {
+		return nil, err
+	}
+	return response.Services, nil
+}
+
+// fetchLevels requests the levels of the service from the server
+func fetchLevels(serverURL, service string) ([]*log.LevelInfo, error) {
+	response := &log.GetLevelsResponse{}
+	if err := fetchProto(serverURL, "/levels", url.Values{"service": {service}}, response); err != nil {
+		return nil, err
+	}
+	return response.Levels, nil
+}
+
+// apiURL appends the api path to the path of the server url, so servers behind a path prefix can be reached
+func apiURL(serverURL, path string) (*url.URL, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + log.APIPrefix + path
+	return u, nil
+}
+
+// fetchProto requests the api path on the server and unmarshals the response into response
+func fetchProto(serverURL, path string, params url.Values, response proto.Message) error {
+	u, err := apiURL(serverURL, path)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = params.Encode()
+	resp, err := get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	bytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(bytes, response)
+}
+
+// httpClient sends the requests to the server, offline queries replace it
+var httpClient = http.DefaultClient
+
+// bearerToken authorizes the requests to the server when it is set
+var bearerToken string
+
+// get requests a gzip compressed response and transparently decompresses it,
+// responses other than 200 are returned as error
+func get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%v returned %v", url, resp.Status)
+	}
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		resp.Body = &gzipBody{Reader: gzipReader, body: resp.Body}
+	}
+	return resp, nil
+}
+
+// gzipBody decompresses the response body and closes the original body as well
+type gzipBody struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
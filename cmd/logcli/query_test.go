@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQueryURL(t *testing.T) {
+	Convey("query urls", t, func() {
+		q := query{service: "api", level: "error", from: time.Unix(5000, 0)}
+
+		Convey("point to the messages of the api", func() {
+			u, err := q.url("http://localhost:7654")
+			So(err, ShouldBeNil)
+			So(u, ShouldEqual, "http://localhost:7654/api/v1/messages?from_time=5000&level=error&service=api")
+		})
+
+		Convey("keep the path and params of the server url", func() {
+			u, err := q.url("https://example.com/logs/?tenant=a")
+			So(err, ShouldBeNil)
+			So(u, ShouldEqual, "https://example.com/logs/api/v1/messages?from_time=5000&level=error&service=api&tenant=a")
+		})
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/alexmorten/log"
+)
+
+// searchFlags select the text to search for and how many messages around each match are shown
+type searchFlags struct {
+	text                   string
+	before, after, context int
+}
+
+func (f *searchFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.text, "search", "", "only show messages that contain this text (ignoring case)")
+	flags.IntVar(&f.after, "A", 0, "show this many messages of the same service after each match")
+	flags.IntVar(&f.before, "B", 0, "show this many messages of the same service before each match")
+	flags.IntVar(&f.context, "C", 0, "show this many messages of the same service before and after each match")
+}
+
+// contextSizes returns the number of messages before and after matches, -A and -B take precedence over -C
+func (f *searchFlags) contextSizes() (before, after int) {
+	before, after = f.before, f.after
+	if before == 0 {
+		before = f.context
+	}
+	if after == 0 {
+		after = f.context
+	}
+	return
+}
+
+// searchShape is the shape search results are printed in, the context spans all levels of a service
+func searchShape(q query) queryShape {
+	return shapeFor(q.service, "", "")
+}
+
+// fetchSearch requests the matches of the search with their context,
+// the entries of each group are numbered so they can be told apart
+func fetchSearch(serverURL string, q query, f *searchFlags) (entries []entry, err error) {
+	params := q.params()
+	before, after := f.contextSizes()
+	params.Set("search", f.text)
+	params.Set("before", strconv.Itoa(before))
+	params.Set("after", strconv.Itoa(after))
+
+	response := &log.SearchResponse{}
+	if err = fetchProto(serverURL, "/search", params, response); err != nil {
+		return
+	}
+	for i, group := range response.Groups {
+		matches := map[int]bool{}
+		for _, match := range group.Matches {
+			matches[int(match)] = true
+		}
+		for j, message := range group.Messages {
+			e := newEntry(message.Message, message.Service, message.Level)
+			e.Match = matches[j]
+			e.Group = i
+			entries = append(entries, e)
+		}
+	}
+	return
+}
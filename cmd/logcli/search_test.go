@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSearchOutput(t *testing.T) {
+	Convey("search results", t, func() {
+		entries := []entry{
+			entry{Timestamp: 1526567400, Service: "api", Level: "standard", Text: "request started", Group: 0},
+			entry{Timestamp: 1526567401, Service: "api", Level: "error", Text: "request Failed", Match: true, Group: 0},
+			entry{Timestamp: 1526567500, Service: "api", Level: "error", Text: "failed again", Match: true, Group: 1},
+		}
+		times := newTimeFormatter("unix", true)
+
+		Convey("are printed in groups with marked context", func() {
+			text := newLineFormatter(entries, serviceQuery, times, &colorizer{})
+			text.search = "failed"
+			buffer := &bytes.Buffer{}
+			So(writeEntries(buffer, entries, &formatFlags{format: "text"}, serviceQuery, text, times), ShouldBeNil)
+			So(buffer.String(), ShouldEqual,
+				"1526567400 | standard - request started\n"+
+					"1526567401 | error    : request Failed\n"+
+					"--\n"+
+					"1526567500 | error    : failed again\n")
+		})
+
+		Convey("highlight the search", func() {
+			text := newLineFormatter(entries, serviceQuery, times, &colorizer{enabled: true})
+			text.search = "failed"
+			So(text.text(entries[1]), ShouldEqual, colorRed+"request "+colorReset+colorHighlight+"Failed"+colorReset)
+		})
+	})
+
+	Convey("contextSizes", t, func() {
+		before, after := (&searchFlags{context: 3, after: 1}).contextSizes()
+		So(before, ShouldEqual, 3)
+		So(after, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alexmorten/log/client"
+)
+
+// exitCode is returned by commands that want logcli to exit with the code without printing an error
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit code %v", int(c))
+}
+
+// sendFlags configure the client that ships lines to the server
+type sendFlags struct {
+	url, token, service string
+	syncTime            time.Duration
+	gzip, tee           bool
+	tls                 tlsFlags
+}
+
+func (f *sendFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.url, "url", "http://localhost:7654", "url of the log server")
+	flags.StringVar(&f.token, "token", "", "token that is sent to the server as bearer authorization")
+	flags.StringVar(&f.service, "service", "", "service that the lines are logged for")
+	flags.DurationVar(&f.syncTime, "sync-time", 5*time.Second, "how often collected lines are sent to the server")
+	flags.BoolVar(&f.gzip, "gzip", false, "compress the requests to the server")
+	flags.BoolVar(&f.tee, "tee", false, "also print the lines, so the output isn't lost for the caller")
+	f.tls.register(flags)
+}
+
+func (f *sendFlags) client() (*client.Client, error) {
+	if f.service == "" {
+		return nil, fmt.Errorf("the service flag is required")
+	}
+	config := client.NewConfig()
+	config.ServiceName = f.service
+	config.URL = f.url
+	config.Token = f.token
+	config.CAFile = f.tls.ca
+	config.CertFile = f.tls.cert
+	config.KeyFile = f.tls.key
+	config.SyncTime = f.syncTime
+	config.Gzip = f.gzip
+	return client.NewClientWithConfig(*config)
+}
+
+// teeTo returns where lines are echoed to, nil if they shouldn't be
+func (f *sendFlags) teeTo(w io.Writer) io.Writer {
+	if f.tee {
+		return w
+	}
+	return nil
+}
+
+func runSend(args []string) error {
+	flags := flag.NewFlagSet("logcli send", flag.ContinueOnError)
+	sendFlags := &sendFlags{}
+	sendFlags.register(flags)
+	level := flags.String("level", "standard", "level that the lines are logged on")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	c, err := sendFlags.client()
+	if err != nil {
+		return err
+	}
+
+	err = sendLines(c, os.Stdin, *level, sendFlags.teeTo(os.Stdout))
+	c.Shutdown()
+	return err
+}
+
+func runRun(args []string) error {
+	flags := flag.NewFlagSet("logcli run", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: logcli run -service <service name> [flags] -- <command> [arguments]")
+		flags.PrintDefaults()
+	}
+	sendFlags := &sendFlags{}
+	sendFlags.register(flags)
+	stdoutLevel := flags.String("stdout-level", "standard", "level that stdout lines of the command are logged on")
+	stderrLevel := flags.String("stderr-level", "error", "level that stderr lines of the command are logged on")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("no command given, use logcli run -service <service name> -- <command> [arguments]")
+	}
+	c, err := sendFlags.client()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(flags.Arg(0), flags.Args()[1:]...)
+	cmd.Stdin = os.Stdin
+	code, err := runCommand(c, cmd, *stdoutLevel, *stderrLevel, sendFlags.teeTo(os.Stdout), sendFlags.teeTo(os.Stderr))
+	c.Shutdown()
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return exitCode(code)
+	}
+	return nil
+}
+
+// sendLines logs every non empty line of r on the level, tee receives a copy of each line when it isn't nil
+func sendLines(logger client.Logger, r io.Reader, level string, tee io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if tee != nil && line != "" {
+			io.WriteString(tee, line)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) != "" {
+			logger.LogMessage(level, line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// runCommand runs cmd and logs its stdout and stderr lines on their levels until it exits,
+// the exit code of the command is returned, termination signals are passed on to it
+func runCommand(logger client.Logger, cmd *exec.Cmd, stdoutLevel, stderrLevel string, stdoutTee, stderrTee io.Writer) (int, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for s := range signals {
+			cmd.Process.Signal(s)
+		}
+	}()
+
+	// all output has to be read before waiting for the command, Wait closes the pipes
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sendLines(logger, stdout, stdoutLevel, stdoutTee)
+	}()
+	go func() {
+		defer wg.Done()
+		sendLines(logger, stderr, stderrLevel, stderrTee)
+	}()
+	wg.Wait()
+
+	err = cmd.Wait()
+	signal.Stop(signals)
+	close(signals)
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitStatus(exitErr), nil
+	}
+	return 0, err
+}
+
+// commands killed by a signal exit with 128 + the signal number, like in a shell
+func exitStatus(err *exec.ExitError) int {
+	status, ok := err.Sys().(syscall.WaitStatus)
+	if !ok {
+		return 1
+	}
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
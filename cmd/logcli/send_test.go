@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/alexmorten/log/client/clienttest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSendLines(t *testing.T) {
+	Convey("sendLines", t, func() {
+		recorder := clienttest.NewRecorder()
+
+		Convey("logs every non empty line", func() {
+			tee := &bytes.Buffer{}
+			input := "first\r\n\n   \nsecond\nlast without newline"
+			So(sendLines(recorder, strings.NewReader(input), "cron", tee), ShouldBeNil)
+
+			entries := recorder.Entries()
+			So(len(entries), ShouldEqual, 3)
+			So(entries[0].Level, ShouldEqual, "cron")
+			So(entries[0].Text, ShouldEqual, "first")
+			So(entries[1].Text, ShouldEqual, "second")
+			So(entries[2].Text, ShouldEqual, "last without newline")
+			So(tee.String(), ShouldEqual, input)
+		})
+
+		Convey("keeps long lines in one message", func() {
+			line := strings.Repeat("a", 100*1024)
+			So(sendLines(recorder, strings.NewReader(line+"\n"), "standard", nil), ShouldBeNil)
+			So(recorder.Entries()[0].Text, ShouldEqual, line)
+		})
+	})
+}
+
+func TestRunCommand(t *testing.T) {
+	Convey("runCommand", t, func() {
+		recorder := clienttest.NewRecorder()
+
+		Convey("logs stdout and stderr on their levels and returns the exit code", func() {
+			cmd := exec.Command("sh", "-c", "echo out; echo err >&2; exit 3")
+			code, err := runCommand(recorder, cmd, "standard", "error", nil, nil)
+			So(err, ShouldBeNil)
+			So(code, ShouldEqual, 3)
+
+			clienttest.ExpectMessage(t, recorder, "standard", "out")
+			clienttest.ExpectMessage(t, recorder, "error", "err")
+			clienttest.ExpectMessageCount(t, recorder, "standard", 1)
+			clienttest.ExpectMessageCount(t, recorder, "error", 1)
+		})
+
+		Convey("returns an error for commands that can't be started", func() {
+			_, err := runCommand(recorder, exec.Command("logcli-test-missing-command"), "standard", "error", nil, nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
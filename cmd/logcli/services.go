@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alexmorten/log"
+)
+
+// info is a service or level together with its message statistics,
+// levels are only set for services
+type info struct {
+	Name           string `json:"name"`
+	MessageCount   int64  `json:"message_count"`
+	FirstTimestamp int64  `json:"first_timestamp,omitempty"`
+	FirstTime      string `json:"first_time,omitempty"`
+	LastTimestamp  int64  `json:"last_timestamp,omitempty"`
+	LastTime       string `json:"last_time,omitempty"`
+	Levels         []info `json:"levels,omitempty"`
+}
+
+func newInfo(name string, count, first, last int64, times *timeFormatter) info {
+	i := info{Name: name, MessageCount: count}
+	if count > 0 {
+		i.FirstTimestamp = first
+		i.FirstTime = times.format(first)
+		i.LastTimestamp = last
+		i.LastTime = times.format(last)
+	}
+	return i
+}
+
+func newServiceInfos(services []*log.ServiceInfo, times *timeFormatter) (infos []info) {
+	for _, service := range services {
+		i := newInfo(service.Name, service.MessageCount, service.FirstTimestamp, service.LastTimestamp, times)
+		i.Levels = newLevelInfos(service.Levels, times)
+		infos = append(infos, i)
+	}
+	return
+}
+
+func newLevelInfos(levels []*log.LevelInfo, times *timeFormatter) (infos []info) {
+	for _, level := range levels {
+		infos = append(infos, newInfo(level.Name, level.MessageCount, level.FirstTimestamp, level.LastTimestamp, times))
+	}
+	return
+}
+
+func runServices(args []string) error {
+	flags := flag.NewFlagSet("logcli services", flag.ContinueOnError)
+	serverFlags := &serverFlags{}
+	serverFlags.register(flags)
+	outputFlags := &outputFlags{}
+	formatFlags := &formatFlags{}
+	outputFlags.register(flags)
+	formatFlags.register(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	colors, err := newColorizer(outputFlags.colorMode, os.Stdout)
+	if err != nil {
+		return err
+	}
+	serverURL, err := serverFlags.connect()
+	if err != nil {
+		return err
+	}
+
+	services, err := fetchServices(serverURL)
+	if err != nil {
+		return err
+	}
+	infos := newServiceInfos(services, infoTimeFormatter(outputFlags, formatFlags))
+	return writeInfos(os.Stdout, infos, "service", formatFlags, colors)
+}
+
+func runLevels(args []string) error {
+	flags := flag.NewFlagSet("logcli levels", flag.ContinueOnError)
+	serverFlags := &serverFlags{}
+	serverFlags.register(flags)
+	service := flags.String("service", "", "list the levels of the provided service")
+	outputFlags := &outputFlags{}
+	formatFlags := &formatFlags{}
+	outputFlags.register(flags)
+	formatFlags.register(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *service == "" {
+		return fmt.Errorf("the levels command needs a service")
+	}
+	colors, err := newColorizer(outputFlags.colorMode, os.Stdout)
+	if err != nil {
+		return err
+	}
+	serverURL, err := serverFlags.connect()
+	if err != nil {
+		return err
+	}
+
+	levels, err := fetchLevels(serverURL, *service)
+	if err != nil {
+		return err
+	}
+	infos := newLevelInfos(levels, infoTimeFormatter(outputFlags, formatFlags))
+	return writeInfos(os.Stdout, infos, "level", formatFlags, colors)
+}
+
+// text output uses the human readable time format, everything else the machine readable one
+func infoTimeFormatter(o *outputFlags, f *formatFlags) *timeFormatter {
+	if f.format == "text" || f.format == "" {
+		return o.timeFormatter()
+	}
+	return o.machineTimeFormatter()
+}
+
+// writeInfos writes services or levels in the output format of the flags,
+// kind names the first column
+func writeInfos(w io.Writer, infos []info, kind string, f *formatFlags, colors *colorizer) error {
+	if f.columns != "" {
+		return fmt.Errorf("the columns flag can only be used for messages")
+	}
+	withLevels := kind == "service"
+	switch f.format {
+	case "text", "":
+		return writeInfoTable(w, infos, kind, withLevels, colors)
+	case "raw":
+		for _, i := range infos {
+			if _, err := fmt.Fprintln(w, i.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		if infos == nil {
+			infos = []info{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		for _, i := range infos {
+			if err := encoder.Encode(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "logfmt":
+		for _, i := range infos {
+			pairs := []string{kind + "=" + logfmtValue(i.Name)}
+			if withLevels {
+				pairs = append(pairs, "levels="+logfmtValue(levelNames(i.Levels)))
+			}
+			pairs = append(pairs,
+				"message_count="+strconv.FormatInt(i.MessageCount, 10),
+				"first_time="+logfmtValue(i.FirstTime),
+				"last_time="+logfmtValue(i.LastTime),
+			)
+			if _, err := fmt.Fprintln(w, strings.Join(pairs, " ")); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		writer := csv.NewWriter(w)
+		if f.header {
+			writer.Write(infoColumns(kind, withLevels))
+		}
+		for _, i := range infos {
+			writer.Write(infoRecord(i, withLevels))
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return fmt.Errorf("unknown output format %q, use one of %v", f.format, outputFormatHelp)
+}
+
+func infoColumns(kind string, withLevels bool) []string {
+	columns := []string{kind}
+	if withLevels {
+		columns = append(columns, "levels")
+	}
+	return append(columns, "message_count", "first_time", "last_time")
+}
+
+func infoRecord(i info, withLevels bool) []string {
+	record := []string{i.Name}
+	if withLevels {
+		record = append(record, levelNames(i.Levels))
+	}
+	return append(record, strconv.FormatInt(i.MessageCount, 10), i.FirstTime, i.LastTime)
+}
+
+// writeInfoTable prints aligned columns, level names are colored like in the message output
+func writeInfoTable(w io.Writer, infos []info, kind string, withLevels bool, colors *colorizer) error {
+	header := infoColumns(kind, withLevels)
+	for i := range header {
+		header[i] = strings.ToUpper(header[i])
+	}
+	rows := [][]string{header}
+	for _, i := range infos {
+		rows = append(rows, infoRecord(i, withLevels))
+	}
+	var firstColumn func(value, cell string) string
+	if kind == "level" {
+		firstColumn = colors.level
+	}
+	return writeTable(w, rows, colors, firstColumn)
+}
+
+// writeTable prints the rows in aligned columns with the first row as dimmed header,
+// firstColumn colors the cells of the first column if it is set
+func writeTable(w io.Writer, rows [][]string, colors *colorizer, firstColumn func(value, cell string) string) error {
+	widths := []int{}
+	for _, row := range rows {
+		for column, value := range row {
+			if column >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if len(value) > widths[column] {
+				widths[column] = len(value)
+			}
+		}
+	}
+
+	for r, row := range rows {
+		cells := make([]string, len(row))
+		for column, value := range row {
+			cells[column] = pad(value, widths[column])
+			if column == len(row)-1 {
+				cells[column] = value
+			}
+			if r == 0 {
+				cells[column] = colors.dim(cells[column])
+			} else if column == 0 && firstColumn != nil {
+				cells[column] = firstColumn(value, cells[column])
+			}
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, "  "), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func levelNames(levels []info) string {
+	names := []string{}
+	for _, level := range levels {
+		names = append(names, level.Name)
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alexmorten/log"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteInfos(t *testing.T) {
+	Convey("writeInfos", t, func() {
+		times := newTimeFormatter("rfc3339", true)
+		services := newServiceInfos([]*log.ServiceInfo{
+			&log.ServiceInfo{
+				Name: "api",
+				Levels: []*log.LevelInfo{
+					&log.LevelInfo{Name: "error", MessageCount: 1, FirstTimestamp: 1526567400, LastTimestamp: 1526567400},
+					&log.LevelInfo{Name: "standard", MessageCount: 2, FirstTimestamp: 1526567401, LastTimestamp: 1526567460},
+				},
+				MessageCount:   3,
+				FirstTimestamp: 1526567400,
+				LastTimestamp:  1526567460,
+			},
+			&log.ServiceInfo{Name: "worker"},
+		}, times)
+		write := func(infos []info, kind string, f *formatFlags) string {
+			buffer := &bytes.Buffer{}
+			So(writeInfos(buffer, infos, kind, f, &colorizer{}), ShouldBeNil)
+			return buffer.String()
+		}
+
+		Convey("as aligned text", func() {
+			So(write(services, "service", &formatFlags{format: "text"}), ShouldEqual,
+				"SERVICE  LEVELS          MESSAGE_COUNT  FIRST_TIME            LAST_TIME\n"+
+					"api      error,standard  3              2018-05-17T14:30:00Z  2018-05-17T14:31:00Z\n"+
+					"worker                   0\n")
+		})
+
+		Convey("as csv", func() {
+			So(write(services[0].Levels, "level", &formatFlags{format: "csv", header: true}), ShouldEqual,
+				"level,message_count,first_time,last_time\n"+
+					"error,1,2018-05-17T14:30:00Z,2018-05-17T14:30:00Z\n"+
+					"standard,2,2018-05-17T14:30:01Z,2018-05-17T14:31:00Z\n")
+		})
+
+		Convey("as json", func() {
+			So(write(services[1:], "service", &formatFlags{format: "ndjson"}), ShouldEqual,
+				`{"name":"worker","message_count":0}`+"\n")
+			So(write(nil, "service", &formatFlags{format: "json"}), ShouldEqual, "[]\n")
+		})
+
+		Convey("as logfmt", func() {
+			So(write(services[:1], "service", &formatFlags{format: "logfmt"}), ShouldEqual,
+				"service=api levels=error,standard message_count=3 first_time=2018-05-17T14:30:00Z last_time=2018-05-17T14:31:00Z\n")
+		})
+
+		Convey("rejects csv columns", func() {
+			err := writeInfos(&bytes.Buffer{}, services, "service", &formatFlags{format: "csv", columns: "name"}, &colorizer{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
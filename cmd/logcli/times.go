@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const timeExpressionHelp = "unix seconds, RFC3339, 2006-01-02[ 15:04[:05]], 15:04, now, today, yesterday [15:04], 15m ago, -2h"
+
+const defaultTimeLayout = "2006-01-02 15:04:05"
+
+// layouts that are interpreted in the location of now
+var localLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+var clockLayouts = []string{
+	"15:04",
+	"15:04:05",
+}
+
+var dayDurationPattern = regexp.MustCompile(`^(\d+)([dw])(.*)$`)
+
+// parseTimeRange turns the -from, -to and -since flags into a time range, zero times are left to the server defaults
+func parseTimeRange(from, to, since string, now time.Time) (fromTime, toTime time.Time, err error) {
+	if from != "" && since != "" {
+		err = errors.New("from and since can't be used together")
+		return
+	}
+	if to != "" {
+		if toTime, err = parseTimeExpression(to, now); err != nil {
+			return
+		}
+	}
+	if from != "" {
+		if fromTime, err = parseTimeExpression(from, now); err != nil {
+			return
+		}
+	}
+	if since != "" {
+		duration, parseErr := parseDuration(since)
+		if parseErr != nil {
+			err = parseErr
+			return
+		}
+		end := now
+		if !toTime.IsZero() {
+			end = toTime
+		}
+		fromTime = end.Add(-duration)
+	}
+	if !fromTime.IsZero() && !toTime.IsZero() && fromTime.After(toTime) {
+		err = fmt.Errorf("%v is after %v", fromTime, toTime)
+	}
+	return
+}
+
+// parseTimeExpression parses absolute and relative (to now) points in time,
+// times without a time zone are interpreted in the location of now
+func parseTimeExpression(expression string, now time.Time) (time.Time, error) {
+	expression = strings.TrimSpace(expression)
+	lower := strings.ToLower(expression)
+
+	if unixSeconds, err := strconv.ParseInt(expression, 10, 64); err == nil {
+		return time.Unix(unixSeconds, 0), nil
+	}
+	if lower == "now" {
+		return now, nil
+	}
+	if strings.HasSuffix(lower, " ago") {
+		duration, err := parseDuration(strings.TrimSpace(strings.TrimSuffix(lower, " ago")))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-duration), nil
+	}
+	if strings.HasPrefix(lower, "-") {
+		duration, err := parseDuration(lower[1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-duration), nil
+	}
+	if strings.HasPrefix(lower, "today") {
+		return timeOnDay(now, strings.TrimPrefix(lower, "today"))
+	}
+	if strings.HasPrefix(lower, "yesterday") {
+		return timeOnDay(now.AddDate(0, 0, -1), strings.TrimPrefix(lower, "yesterday"))
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, expression); err == nil {
+		return t, nil
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, expression, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := timeOnDay(now, expression); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("can't parse %q as a time, use one of: %v", expression, timeExpressionHelp)
+}
+
+// timeOnDay returns the clock time on the day of the given time, midnight if clock is empty
+func timeOnDay(day time.Time, clock string) (time.Time, error) {
+	year, month, date := day.Date()
+	clock = strings.TrimSpace(clock)
+	if clock == "" {
+		return time.Date(year, month, date, 0, 0, 0, 0, day.Location()), nil
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, clock); err == nil {
+			return time.Date(year, month, date, t.Hour(), t.Minute(), t.Second(), 0, day.Location()), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can't parse %q as a time of day", clock)
+}
+
+// parseDuration extends time.ParseDuration with leading days (d) and weeks (w), like 1d12h or 2w
+func parseDuration(expression string) (time.Duration, error) {
+	expression = strings.TrimSpace(expression)
+	var duration time.Duration
+	if matches := dayDurationPattern.FindStringSubmatch(expression); matches != nil {
+		count, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return 0, err
+		}
+		unit := 24 * time.Hour
+		if matches[2] == "w" {
+			unit *= 7
+		}
+		duration = time.Duration(count) * unit
+		expression = matches[3]
+		if expression == "" {
+			return duration, nil
+		}
+	}
+	rest, err := time.ParseDuration(expression)
+	if err != nil {
+		return 0, err
+	}
+	if rest < 0 {
+		return 0, fmt.Errorf("duration %q should be positive", expression)
+	}
+	return duration + rest, nil
+}
+
+// timeFormatter prints the unix timestamps of messages
+type timeFormatter struct {
+	layout   string
+	location *time.Location
+}
+
+func newTimeFormatter(format string, utc bool) *timeFormatter {
+	f := &timeFormatter{
+		layout:   format,
+		location: time.Local,
+	}
+	switch strings.ToLower(format) {
+	case "":
+		f.layout = defaultTimeLayout
+	case "rfc3339":
+		f.layout = time.RFC3339
+	case "unix":
+		f.layout = "unix"
+	}
+	if utc {
+		f.location = time.UTC
+	}
+	return f
+}
+
+func (f *timeFormatter) format(timestamp int64) string {
+	if f.layout == "unix" {
+		return strconv.FormatInt(timestamp, 10)
+	}
+	return time.Unix(timestamp, 0).In(f.location).Format(f.layout)
+}
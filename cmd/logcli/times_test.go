@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseTimeExpression(t *testing.T) {
+	Convey("parseTimeExpression", t, func() {
+		now := time.Date(2018, 5, 17, 14, 30, 0, 0, time.UTC)
+
+		parse := func(expression string) time.Time {
+			parsed, err := parseTimeExpression(expression, now)
+			So(err, ShouldBeNil)
+			return parsed
+		}
+
+		So(parse("1526565600").Unix(), ShouldEqual, 1526565600)
+		So(parse("now"), ShouldEqual, now)
+		So(parse("2018-05-16T10:00:00+02:00").Unix(), ShouldEqual, time.Date(2018, 5, 16, 8, 0, 0, 0, time.UTC).Unix())
+		So(parse("2018-05-16"), ShouldEqual, time.Date(2018, 5, 16, 0, 0, 0, 0, time.UTC))
+		So(parse("2018-05-16 09:15"), ShouldEqual, time.Date(2018, 5, 16, 9, 15, 0, 0, time.UTC))
+		So(parse("09:15:30"), ShouldEqual, time.Date(2018, 5, 17, 9, 15, 30, 0, time.UTC))
+		So(parse("today"), ShouldEqual, time.Date(2018, 5, 17, 0, 0, 0, 0, time.UTC))
+		So(parse("yesterday 09:00"), ShouldEqual, time.Date(2018, 5, 16, 9, 0, 0, 0, time.UTC))
+		So(parse("15m ago"), ShouldEqual, now.Add(-15*time.Minute))
+		So(parse("1d2h ago"), ShouldEqual, now.Add(-26*time.Hour))
+		So(parse("-2h"), ShouldEqual, now.Add(-2*time.Hour))
+
+		_, err := parseTimeExpression("sometime", now)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestParseTimeRange(t *testing.T) {
+	Convey("parseTimeRange", t, func() {
+		now := time.Date(2018, 5, 17, 14, 30, 0, 0, time.UTC)
+
+		from, to, err := parseTimeRange("", "", "2h", now)
+		So(err, ShouldBeNil)
+		So(from, ShouldEqual, now.Add(-2*time.Hour))
+		So(to.IsZero(), ShouldBeTrue)
+
+		from, to, err = parseTimeRange("", "12:00", "1w", now)
+		So(err, ShouldBeNil)
+		So(to, ShouldEqual, time.Date(2018, 5, 17, 12, 0, 0, 0, time.UTC))
+		So(from, ShouldEqual, time.Date(2018, 5, 10, 12, 0, 0, 0, time.UTC))
+
+		_, _, err = parseTimeRange("1h ago", "", "2h", now)
+		So(err, ShouldNotBeNil)
+		_, _, err = parseTimeRange("now", "1h ago", "", now)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestTimeFormatter(t *testing.T) {
+	Convey("timeFormatter", t, func() {
+		timestamp := time.Date(2018, 5, 17, 14, 30, 0, 0, time.UTC).Unix()
+
+		So(newTimeFormatter("", true).format(timestamp), ShouldEqual, "2018-05-17 14:30:00")
+		So(newTimeFormatter("rfc3339", true).format(timestamp), ShouldEqual, "2018-05-17T14:30:00Z")
+		So(newTimeFormatter("unix", false).format(timestamp), ShouldEqual, "1526567400")
+		So(newTimeFormatter("15:04", true).format(timestamp), ShouldEqual, "14:30")
+	})
+}
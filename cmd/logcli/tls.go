@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/alexmorten/log"
+)
+
+// tlsFlags configure HTTPS connections to the server
+type tlsFlags struct {
+	ca, cert, key string
+}
+
+func (f *tlsFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.ca, "ca", "", "PEM encoded CAs that the certificate of the server is verified against (default the system CAs)")
+	flags.StringVar(&f.cert, "cert", "", "PEM encoded client certificate for servers that require one")
+	flags.StringVar(&f.key, "key", "", "PEM encoded key of the client certificate")
+}
+
+// client returns the http client for the settings, the default client if there are none
+func (f *tlsFlags) client() (*http.Client, error) {
+	transport, err := log.ClientTLSTransport(f.ca, f.cert, f.key)
+	if err != nil {
+		return nil, err
+	}
+	if transport == nil {
+		return http.DefaultClient, nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
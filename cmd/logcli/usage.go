@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/alexmorten/log"
+)
+
+// usage is what a service posted today together with its limits, 0 limits don't limit anything
+type usage struct {
+	Service                string `json:"service"`
+	MessageCount           int64  `json:"message_count"`
+	ByteCount              int64  `json:"byte_count"`
+	RejectedRequests       int64  `json:"rejected_requests"`
+	MessagesPerSecondLimit int64  `json:"messages_per_second_limit"`
+	BytesPerSecondLimit    int64  `json:"bytes_per_second_limit"`
+	DailyBytesLimit        int64  `json:"daily_bytes_limit"`
+}
+
+func newUsages(services []*log.ServiceUsage) []usage {
+	usages := []usage{}
+	for _, s := range services {
+		usages = append(usages, usage{
+			Service:                s.Service,
+			MessageCount:           s.MessageCount,
+			ByteCount:              s.ByteCount,
+			RejectedRequests:       s.RejectedRequests,
+			MessagesPerSecondLimit: s.MessagesPerSecondLimit,
+			BytesPerSecondLimit:    s.BytesPerSecondLimit,
+			DailyBytesLimit:        s.DailyBytesLimit,
+		})
+	}
+	return usages
+}
+
+func runUsage(args []string) error {
+	flags := flag.NewFlagSet("logcli usage", flag.ContinueOnError)
+	serverFlags := &serverFlags{}
+	serverFlags.register(flags)
+	outputFlags := &outputFlags{}
+	formatFlags := &formatFlags{}
+	outputFlags.register(flags)
+	formatFlags.register(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	colors, err := newColorizer(outputFlags.colorMode, os.Stdout)
+	if err != nil {
+		return err
+	}
+	serverURL, err := serverFlags.connect()
+	if err != nil {
+		return err
+	}
+
+	response := &log.GetUsageResponse{}
+	if err := fetchProto(serverURL, "/usage", url.Values{}, response); err != nil {
+		return err
+	}
+	return writeUsages(os.Stdout, newUsages(response.Services), formatFlags, colors)
+}
+
+// writeUsages prints the usages as table, json or ndjson
+func writeUsages(w io.Writer, usages []usage, f *formatFlags, colors *colorizer) error {
+	if f.columns != "" {
+		return fmt.Errorf("the columns flag can only be used for messages")
+	}
+	switch f.format {
+	case "text", "":
+		rows := [][]string{{"SERVICE", "MESSAGES", "BYTES", "REJECTED", "MESSAGES/S LIMIT", "BYTES/S LIMIT", "DAILY BYTES LIMIT"}}
+		for _, u := range usages {
+			rows = append(rows, []string{
+				u.Service,
+				strconv.FormatInt(u.MessageCount, 10),
+				strconv.FormatInt(u.ByteCount, 10),
+				strconv.FormatInt(u.RejectedRequests, 10),
+				formatLimit(u.MessagesPerSecondLimit),
+				formatLimit(u.BytesPerSecondLimit),
+				formatLimit(u.DailyBytesLimit),
+			})
+		}
+		return writeTable(w, rows, colors, nil)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(usages)
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		for _, u := range usages {
+			if err := encoder.Encode(u); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q for usage, use text, json or ndjson", f.format)
+}
+
+func formatLimit(limit int64) string {
+	if limit == 0 {
+		return "-"
+	}
+	return strconv.FormatInt(limit, 10)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteUsages(t *testing.T) {
+	Convey("writeUsages", t, func() {
+		usages := []usage{
+			{Service: "api", MessageCount: 1200, ByteCount: 64000, RejectedRequests: 3, MessagesPerSecondLimit: 100},
+			{Service: "db", MessageCount: 5, ByteCount: 300, DailyBytesLimit: 1000000},
+		}
+		colors := &colorizer{}
+		write := func(format string) string {
+			buffer := &bytes.Buffer{}
+			So(writeUsages(buffer, usages, &formatFlags{format: format}, colors), ShouldBeNil)
+			return buffer.String()
+		}
+
+		Convey("as table with the limits", func() {
+			So(write("text"), ShouldEqual,
+				"SERVICE  MESSAGES  BYTES  REJECTED  MESSAGES/S LIMIT  BYTES/S LIMIT  DAILY BYTES LIMIT\n"+
+					"api      1200      64000  3         100               -              -\n"+
+					"db       5         300    0         -                 -              1000000\n")
+		})
+
+		Convey("as ndjson", func() {
+			So(write("ndjson"), ShouldStartWith, `{"service":"api","message_count":1200,"byte_count":64000,"rejected_requests":3,`)
+		})
+
+		Convey("rejects other formats", func() {
+			So(writeUsages(&bytes.Buffer{}, usages, &formatFlags{format: "csv"}, colors), ShouldNotBeNil)
+		})
+	})
+}
@@ -1,9 +1,33 @@
 package main
 
 import (
+	"flag"
+	"os"
+	"strings"
+
 	"github.com/alexmorten/log"
 )
 
 func main() {
-	log.StartServer()
+	config := log.ServerConfig{}
+	flag.StringVar(&config.Addr, "addr", ":7654", "address the server listens on")
+	flag.StringVar(&config.APIKeysPath, "api-keys", os.Getenv("LOG_API_KEYS"), "JSON file with the api keys that requests are authenticated with, reloaded on SIGHUP (default $LOG_API_KEYS)")
+	flag.StringVar(&config.CertFile, "cert", "", "PEM encoded certificate that enables HTTPS, reloaded when it changes")
+	flag.StringVar(&config.KeyFile, "key", "", "PEM encoded key of the certificate")
+	flag.StringVar(&config.ClientCAFile, "client-ca", "", "PEM encoded CAs that client certificates are verified against, requests to the data routes need one and may only access the service named by its common name")
+	flag.StringVar(&config.RateLimitsPath, "rate-limits", os.Getenv("LOG_RATE_LIMITS"), "JSON file with the rate limits of the services, reloaded on SIGHUP (default $LOG_RATE_LIMITS)")
+	flag.StringVar(&config.SyslogUDPAddr, "syslog-udp", "", "address of a syslog listener for UDP, disabled if empty")
+	flag.StringVar(&config.SyslogTCPAddr, "syslog-tcp", "", "address of a syslog listener for TCP with octet-counted or newline-framed messages, disabled if empty")
+	flag.StringVar(&config.LineTCPAddr, "lines-tcp", "", "address of a TCP listener for newline-delimited \"service level text\" or logfmt lines, disabled if empty")
+	flag.StringVar(&config.GRPCAddr, "grpc", "", "address of the gRPC service, it uses the certificates and api keys of the http server, disabled if empty")
+	lokiServiceLabels := flag.String("loki-service-labels", "", "comma separated stream labels that name the service of Loki pushes, the first one a stream has is used (default service_name,service,app,job,container_name)")
+	lokiLevelLabels := flag.String("loki-level-labels", "", "comma separated stream labels that name the level of Loki pushes (default level,detected_level,severity)")
+	flag.Parse()
+	if *lokiServiceLabels != "" {
+		config.LokiServiceLabels = strings.Split(*lokiServiceLabels, ",")
+	}
+	if *lokiLevelLabels != "" {
+		config.LokiLevelLabels = strings.Split(*lokiLevelLabels, ",")
+	}
+	log.StartServerWithConfig(config)
 }
@@ -0,0 +1,116 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var defaultMaxDecompressedBodySize int64 = 64 * 1024 * 1024
+
+var errBodyTooLarge = errors.New("decompressed body is too large")
+
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// readRequestBody reads the whole body and decompresses it if it is gzip encoded, other encodings aren't supported.
+// The body may not be larger than maxSize after decompression to protect against gzip bombs
+func readRequestBody(r *http.Request, maxSize int64) ([]byte, error) {
+	body := r.Body
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip":
+		gzipReader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		body = gzipReader
+	default:
+		return nil, errUnsupportedEncoding
+	}
+
+	// read one more byte than allowed to notice bodies that are too large
+	bytes, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(bytes)) > maxSize {
+		return nil, errBodyTooLarge
+	}
+	return bytes, nil
+}
+
+// bodyErrorStatus is the status of requests whose body couldn't be read
+func bodyErrorStatus(err error) int {
+	switch err {
+	case errBodyTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case errUnsupportedEncoding:
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
+
+// acceptsGzip checks the Accept-Encoding header for gzip or *, an encoding with a quality of 0 is refused.
+// gzip takes precedence over *, so "gzip;q=0, *" doesn't accept gzip.
+func acceptsGzip(r *http.Request) bool {
+	gzipQuality, wildcardQuality := -1.0, -1.0
+	for _, header := range r.Header["Accept-Encoding"] {
+		for _, encoding := range strings.Split(header, ",") {
+			parts := strings.Split(encoding, ";")
+			switch strings.ToLower(strings.TrimSpace(parts[0])) {
+			case "gzip":
+				gzipQuality = encodingQuality(parts[1:])
+			case "*":
+				wildcardQuality = encodingQuality(parts[1:])
+			}
+		}
+	}
+	if gzipQuality >= 0 {
+		return gzipQuality > 0
+	}
+	return wildcardQuality > 0
+}
+
+// encodingQuality parses the q parameter of an encoding, it is 1 without one and 0 if it is invalid
+func encodingQuality(params []string) float64 {
+	for _, param := range params {
+		pair := strings.SplitN(param, "=", 2)
+		if len(pair) != 2 || !strings.EqualFold(strings.TrimSpace(pair[0]), "q") {
+			continue
+		}
+		quality, err := strconv.ParseFloat(strings.TrimSpace(pair[1]), 64)
+		if err != nil || quality < 0 || quality > 1 {
+			return 0
+		}
+		return quality
+	}
+	return 1
+}
+
+//gzipResponseWriter compresses everything that is written to it, Close has to be called to flush it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gzipWriter *gzip.Writer
+}
+
+func newGzipResponseWriter(w http.ResponseWriter) *gzipResponseWriter {
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &gzipResponseWriter{
+		ResponseWriter: w,
+		gzipWriter:     gzip.NewWriter(w),
+	}
+}
+
+func (w *gzipResponseWriter) Write(bytes []byte) (int, error) {
+	return w.gzipWriter.Write(bytes)
+}
+
+func (w *gzipResponseWriter) Close() error {
+	return w.gzipWriter.Close()
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	Convey("acceptsGzip", t, func() {
+		accepts := func(headers ...string) bool {
+			req := httptest.NewRequest("GET", "/", nil)
+			for _, header := range headers {
+				req.Header.Add("Accept-Encoding", header)
+			}
+			return acceptsGzip(req)
+		}
+
+		Convey("accepts gzip with a positive quality", func() {
+			So(accepts("gzip"), ShouldBeTrue)
+			So(accepts("deflate, gzip"), ShouldBeTrue)
+			So(accepts("GZIP;q=0.5"), ShouldBeTrue)
+			So(accepts("deflate", "gzip; q=1.0"), ShouldBeTrue)
+		})
+
+		Convey("treats a quality of 0 as refusal", func() {
+			So(accepts("gzip;q=0"), ShouldBeFalse)
+			So(accepts("gzip;q=0.0"), ShouldBeFalse)
+			So(accepts("gzip; q=0.000"), ShouldBeFalse)
+			So(accepts("gzip;q=invalid"), ShouldBeFalse)
+		})
+
+		Convey("falls back to the wildcard", func() {
+			So(accepts("*"), ShouldBeTrue)
+			So(accepts("*;q=0"), ShouldBeFalse)
+			So(accepts("gzip;q=0, *"), ShouldBeFalse)
+			So(accepts("gzip, *;q=0"), ShouldBeTrue)
+		})
+
+		Convey("doesn't accept gzip without a header", func() {
+			So(accepts(), ShouldBeFalse)
+			So(accepts("deflate, br"), ShouldBeFalse)
+		})
+	})
+}
@@ -3,12 +3,31 @@ package log
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
+	"sync"
+	"time"
 )
 
 var pathPrefix = "data"
 
+//SetDataPath changes the directory that blocks are written to and read from, it defaults to "data"
+func SetDataPath(path string) {
+	pathPrefix = path
+}
+
 //FileReader handles reading messages from the filesystem
-type FileReader struct{}
+type FileReader struct {
+	countsMutex sync.Mutex
+	// message counts of the block files by path, so every file is only read once for the statistics
+	counts map[string]fileCount
+}
+
+//fileCount is the number of messages in a block file, as long as the file is unchanged
+type fileCount struct {
+	modTime  time.Time
+	size     int64
+	messages int
+}
 
 //GetBlock for given service ,level and timerange
 func (f *FileReader) GetBlock(startTime, endTime int64, service, level string) *Block {
@@ -19,6 +38,7 @@ func (f *FileReader) GetBlock(startTime, endTime int64, service, level string) *
 			if b.IsInTimeRange(startTime, endTime) {
 				b.Service = service
 				b.Level = level
+				metrics.filesScanned.inc()
 				if e := b.ReadFromFile(); e == nil {
 					blocks = append(blocks, b)
 				}
@@ -49,7 +69,7 @@ func (f *FileReader) GetBlock(startTime, endTime int64, service, level string) *
 func (f *FileReader) GetLevels(service string) (levels []string) {
 	dirInfos, err := ioutil.ReadDir(levelPath(service))
 	if err != nil {
-		fmt.Println(err)
+		printUnlessNotExist(err)
 		return
 	}
 	for _, info := range dirInfos {
@@ -62,7 +82,7 @@ func (f *FileReader) GetLevels(service string) (levels []string) {
 func (f *FileReader) GetServices() (services []string) {
 	dirInfos, err := ioutil.ReadDir(servicePath())
 	if err != nil {
-		fmt.Println(err)
+		printUnlessNotExist(err)
 		return
 	}
 	for _, info := range dirInfos {
@@ -71,13 +91,67 @@ func (f *FileReader) GetServices() (services []string) {
 	return
 }
 
+//levelInfo takes the time range of the level from the names of its block files,
+//the message count of a file is only read the first time it is seen
+func (f *FileReader) levelInfo(service, level string) *LevelInfo {
+	info := &LevelInfo{Name: level}
+	fileInfos, err := ioutil.ReadDir(BlockPath(service, level))
+	if err != nil {
+		printUnlessNotExist(err)
+		return info
+	}
+	for _, fileInfo := range fileInfos {
+		b, err := ParseFileNameIntoBlock(fileInfo.Name())
+		if err != nil {
+			continue
+		}
+		b.Service = service
+		b.Level = level
+		count := f.messageCount(b, fileInfo)
+		if count == 0 {
+			continue
+		}
+		if info.MessageCount == 0 || b.StartTime < info.FirstTimestamp {
+			info.FirstTimestamp = b.StartTime
+		}
+		if info.MessageCount == 0 || b.EndTime > info.LastTimestamp {
+			info.LastTimestamp = b.EndTime
+		}
+		info.MessageCount += int64(count)
+	}
+	return info
+}
+
+func (f *FileReader) messageCount(b *Block, fileInfo os.FileInfo) int {
+	path := b.path() + "/" + b.fileName()
+	f.countsMutex.Lock()
+	count, ok := f.counts[path]
+	f.countsMutex.Unlock()
+	if ok && count.modTime.Equal(fileInfo.ModTime()) && count.size == fileInfo.Size() {
+		return count.messages
+	}
+
+	metrics.filesScanned.inc()
+	if err := b.ReadFromFile(); err != nil {
+		return 0
+	}
+	count = fileCount{modTime: fileInfo.ModTime(), size: fileInfo.Size(), messages: len(b.Messages)}
+	f.countsMutex.Lock()
+	if f.counts == nil {
+		f.counts = map[string]fileCount{}
+	}
+	f.counts[path] = count
+	f.countsMutex.Unlock()
+	return count.messages
+}
+
 //Shutdown for the Store interface
 func (f *FileReader) Shutdown() {}
 
 func getFileNames(service, level string) (files []string) {
 	fileInfos, err := ioutil.ReadDir(BlockPath(service, level))
 	if err != nil {
-		fmt.Println(err)
+		printUnlessNotExist(err)
 		return
 	}
 	for _, info := range fileInfos {
@@ -93,3 +167,10 @@ func servicePath() string {
 func levelPath(service string) string {
 	return fmt.Sprintf("%v/%v", servicePath(), service)
 }
+
+// services and levels that were never written are expected, they are simply empty
+func printUnlessNotExist(err error) {
+	if !os.IsNotExist(err) {
+		fmt.Println(err)
+	}
+}
@@ -60,6 +60,16 @@ func TestFileReader(t *testing.T) {
 			So(levels[0], ShouldEqual, "file_reader")
 			So(levels[1], ShouldEqual, "file_reader2")
 		})
+		Convey("get level statistics without reading the files again", func() {
+			info := r.levelInfo("test", "file_reader")
+			So(info, ShouldResemble, &LevelInfo{Name: "file_reader", MessageCount: 3, FirstTimestamp: 5002, LastTimestamp: 10001})
+
+			scanned := metrics.filesScanned.value()
+			So(r.levelInfo("test", "file_reader"), ShouldResemble, info)
+			So(metrics.filesScanned.value(), ShouldEqual, scanned)
+
+			So(r.levelInfo("test", "missing"), ShouldResemble, &LevelInfo{Name: "missing"})
+		})
 	})
 	os.RemoveAll(pathPrefix)
 }
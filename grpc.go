@@ -0,0 +1,174 @@
+package log
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+//logService implements the Log gRPC service on top of the server
+type logService struct {
+	server *Server
+}
+
+//NewGRPCServer serves the Log service of the server, tlsConfig enables TLS if it isn't nil.
+//Requests are authenticated like the http data routes, with the api key as bearer token in the authorization metadata.
+func (s *Server) NewGRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	options := []grpc.ServerOption{}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(withHTTP2(tlsConfig))))
+	}
+	grpcServer := grpc.NewServer(options...)
+	RegisterLogServer(grpcServer, &logService{server: s})
+	return grpcServer
+}
+
+//withHTTP2 makes the configs that the server hands out per client offer h2, gRPC doesn't work without it
+func withHTTP2(config *tls.Config) *tls.Config {
+	config = config.Clone()
+	getConfigForClient := config.GetConfigForClient
+	if getConfigForClient != nil {
+		config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			clientConfig, err := getConfigForClient(hello)
+			if err != nil || clientConfig == nil {
+				return clientConfig, err
+			}
+			clientConfig.NextProtos = []string{"h2"}
+			return clientConfig, nil
+		}
+	}
+	return config
+}
+
+//Push acks every request of the stream, rejected requests don't end the stream
+func (l *logService) Push(stream Log_PushServer) error {
+	key, err := l.authenticate(stream.Context(), WriteScope)
+	if err != nil {
+		return err
+	}
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ack := &PushAck{Sequence: request.Sequence}
+		httpStatus, retryAfter := l.server.admitBlocks(key, request.Blocks)
+		if httpStatus == http.StatusOK {
+			l.server.ingest(request.Blocks)
+		} else {
+			ack.Code = int32(grpcCode(httpStatus))
+			ack.Error = http.StatusText(httpStatus)
+			ack.RetryAfterSeconds = retryAfterSeconds(retryAfter)
+		}
+		if err := stream.Send(ack); err != nil {
+			return err
+		}
+	}
+}
+
+//Query streams the messages that GET /messages returns for the same parameters
+func (l *logService) Query(request *QueryRequest, stream Log_QueryServer) error {
+	defer metrics.queryDuration.observeSince(time.Now(), "grpc_query")
+	key, err := l.authenticate(stream.Context(), ReadScope)
+	if err != nil {
+		return err
+	}
+	if !key.allowsQuery(request.Service, request.Level) {
+		return status.Error(codes.PermissionDenied, http.StatusText(http.StatusForbidden))
+	}
+
+	// unlike the query parameters of the http api, 0 can't be told apart from a missing time,
+	// so the start time 0 reads from the beginning like from_time=0 and the end time 0 up to now
+	endTime := request.EndTime
+	if endTime == 0 {
+		endTime = time.Now().Unix()
+	}
+	return l.server.Reader.eachCompleteMessageInTimeRange(request.StartTime, endTime, request.Service, request.Level, func(message *CompleteMessage) error {
+		if request.TraceId != "" && !message.Message.HasField(TraceIDField, request.TraceId) {
+			return nil
+		}
+		if !key.allows(message.Service, message.Level) {
+			return nil
+		}
+		return stream.Send(message)
+	})
+}
+
+//Tail streams the messages of the service and level as the server accepts them, until the client cancels it.
+//A tail that can't keep up ends with ResourceExhausted.
+func (l *logService) Tail(request *TailRequest, stream Log_TailServer) error {
+	key, err := l.authenticate(stream.Context(), ReadScope)
+	if err != nil {
+		return err
+	}
+	if !key.allowsQuery(request.Service, request.Level) {
+		return status.Error(codes.PermissionDenied, http.StatusText(http.StatusForbidden))
+	}
+
+	t := l.server.tails.subscribe(request.Service, request.Level, key)
+	defer l.server.tails.unsubscribe(t)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-t.dropped:
+			return status.Error(codes.ResourceExhausted, "the tail fell behind the incoming messages")
+		case block := <-t.blocks:
+			for _, message := range block.Messages {
+				err := stream.Send(&CompleteMessage{Message: message, Service: block.Service, Level: block.Level})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+//authenticate checks the bearer token of the metadata and the client certificate of the peer like authorize does
+func (l *logService) authenticate(ctx context.Context, scope string) (*APIKey, error) {
+	token := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md["authorization"]) > 0 {
+		token = bearerToken(md["authorization"][0])
+	}
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+	}
+	key, err := l.server.authenticate(token, state, scope)
+	if err != nil {
+		return nil, status.Error(grpcCode(err.status), err.message)
+	}
+	return key, nil
+}
+
+//grpcCode is the gRPC status code that corresponds to the http status
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
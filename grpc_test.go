@@ -0,0 +1,173 @@
+package log
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGRPCService(t *testing.T) {
+	Convey("gRPC service", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		grpcServer := s.NewGRPCServer(nil)
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+
+		conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		client := NewLogClient(conn)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		query := func(ctx context.Context, request *QueryRequest) ([]*CompleteMessage, error) {
+			stream, err := client.Query(ctx, request)
+			if err != nil {
+				return nil, err
+			}
+			messages := []*CompleteMessage{}
+			for {
+				message, err := stream.Recv()
+				if err == io.EOF {
+					return messages, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				messages = append(messages, message)
+			}
+		}
+
+		Convey("pushes, queries and tails messages", func() {
+			tail, err := client.Tail(ctx, &TailRequest{Service: "api"})
+			So(err, ShouldBeNil)
+			waitForTails(s, 1)
+
+			push, err := client.Push(ctx)
+			So(err, ShouldBeNil)
+			So(push.Send(&PushRequest{Sequence: 1, Blocks: []*Block{
+				&Block{StartTime: 5001, EndTime: 5001, Service: "api", Level: "error", Messages: []*Message{&Message{Text: "Foo", Timestamp: 5001}}},
+				&Block{StartTime: 5002, EndTime: 5002, Service: "db", Level: "error", Messages: []*Message{&Message{Text: "Bar", Timestamp: 5002}}},
+			}}), ShouldBeNil)
+			ack, err := push.Recv()
+			So(err, ShouldBeNil)
+			So(ack, ShouldResemble, &PushAck{Sequence: 1})
+
+			So(push.Send(&PushRequest{Sequence: 2, Blocks: []*Block{&Block{Service: "api", Level: "error"}}}), ShouldBeNil)
+			ack, err = push.Recv()
+			So(err, ShouldBeNil)
+			So(ack.Sequence, ShouldEqual, 2)
+			So(codes.Code(ack.Code), ShouldEqual, codes.InvalidArgument)
+			So(push.CloseSend(), ShouldBeNil)
+
+			tailed, err := tail.Recv()
+			So(err, ShouldBeNil)
+			So(tailed.Service, ShouldEqual, "api")
+			So(tailed.Message.Text, ShouldEqual, "Foo")
+
+			time.Sleep(10 * time.Millisecond)
+			messages, err := query(ctx, &QueryRequest{StartTime: 5000, EndTime: 5010, Service: "api"})
+			So(err, ShouldBeNil)
+			So(len(messages), ShouldEqual, 1)
+			So(messages[0].Level, ShouldEqual, "error")
+			So(messages[0].Message.Text, ShouldEqual, "Foo")
+
+			messages, err = query(ctx, &QueryRequest{StartTime: 5000, EndTime: 5010})
+			So(err, ShouldBeNil)
+			So(len(messages), ShouldEqual, 2)
+			So(messages[0].Message.Text, ShouldEqual, "Foo")
+			So(messages[1].Message.Text, ShouldEqual, "Bar")
+
+			messages, err = query(ctx, &QueryRequest{})
+			So(err, ShouldBeNil)
+			So(len(messages), ShouldEqual, 2)
+		})
+
+		Convey("leaves out the messages of other traces and of levels the key can't read", func() {
+			s.ingest([]*Block{
+				&Block{StartTime: 6001, EndTime: 6002, Service: "api", Level: "error", Messages: []*Message{
+					&Message{Text: "Foo", Timestamp: 6001, Fields: map[string]string{TraceIDField: "abc"}},
+					&Message{Text: "Bar", Timestamp: 6002},
+				}},
+				&Block{StartTime: 6003, EndTime: 6003, Service: "api", Level: "debug", Messages: []*Message{
+					&Message{Text: "Baz", Timestamp: 6003, Fields: map[string]string{TraceIDField: "abc"}},
+				}},
+			})
+			time.Sleep(10 * time.Millisecond)
+
+			messages, err := query(ctx, &QueryRequest{StartTime: 6000, EndTime: 6010, TraceId: "abc"})
+			So(err, ShouldBeNil)
+			So(len(messages), ShouldEqual, 2)
+
+			file, _ := ioutil.TempFile("", "api-keys")
+			defer os.Remove(file.Name())
+			ioutil.WriteFile(file.Name(), []byte(`{"keys": [{"token": "reader", "scopes": ["read"], "levels": ["error"]}]}`), 0600)
+			s.APIKeys, err = LoadAPIKeys(file.Name())
+			So(err, ShouldBeNil)
+			messages, err = query(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer reader"), &QueryRequest{StartTime: 6000, EndTime: 6010, Service: "api"})
+			So(err, ShouldBeNil)
+			So(len(messages), ShouldEqual, 2)
+			So(messages[1].Message.Text, ShouldEqual, "Bar")
+		})
+
+		Convey("authenticates with the api keys", func() {
+			file, _ := ioutil.TempFile("", "api-keys")
+			defer os.Remove(file.Name())
+			ioutil.WriteFile(file.Name(), []byte(`{"keys": [{"token": "writer", "scopes": ["write"]}]}`), 0600)
+			s.APIKeys, err = LoadAPIKeys(file.Name())
+			So(err, ShouldBeNil)
+
+			_, err := query(ctx, &QueryRequest{})
+			So(status.Code(err), ShouldEqual, codes.Unauthenticated)
+			_, err = query(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer writer"), &QueryRequest{})
+			So(status.Code(err), ShouldEqual, codes.PermissionDenied)
+		})
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	os.RemoveAll(pathPrefix)
+}
+
+func TestTailHub(t *testing.T) {
+	Convey("tailHub drops tails that fall behind", t, func() {
+		hub := &tailHub{}
+		slow := hub.subscribe("", "", nil)
+		other := hub.subscribe("other", "", nil)
+		block := &Block{Service: "api", Level: "standard"}
+		for i := 0; i <= tailBufferSize; i++ {
+			hub.publish([]*Block{block})
+		}
+
+		_, open := <-slow.dropped
+		So(open, ShouldBeFalse)
+		So(len(other.blocks), ShouldEqual, 0)
+		So(len(hub.tails), ShouldEqual, 1)
+	})
+}
+
+func waitForTails(s *Server, count int) {
+	for i := 0; i < 100; i++ {
+		s.tails.mutex.Lock()
+		subscribed := len(s.tails.tails)
+		s.tails.mutex.Unlock()
+		if subscribed >= count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
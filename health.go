@@ -0,0 +1,78 @@
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+var defaultMaxQueuedBlocks = 100
+
+//readinessCheck is the result of one of the checks behind /readyz, a nil err means it passed
+type readinessCheck struct {
+	name string
+	err  error
+}
+
+//handleHealthGet answers as long as the server is able to handle requests at all
+func (s *Server) handleHealthGet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "ok")
+}
+
+//handleReadyGet lists the readiness checks, the status is 503 if any of them failed
+func (s *Server) handleReadyGet(w http.ResponseWriter, r *http.Request) {
+	checks := s.readinessChecks()
+	status := http.StatusOK
+	for _, check := range checks {
+		if check.err != nil {
+			status = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	for _, check := range checks {
+		if check.err != nil {
+			fmt.Fprintf(w, "%v: %v\n", check.name, check.err)
+		} else {
+			fmt.Fprintf(w, "%v: ok\n", check.name)
+		}
+	}
+}
+
+//readinessChecks checks that blocks can be written and the writers keep up with the incoming blocks.
+//There is no write ahead log to replay on startup, so the server doesn't have to wait for anything before it is ready.
+func (s *Server) readinessChecks() []readinessCheck {
+	checks := []readinessCheck{
+		{name: "data directory", err: checkWritable(servicePath())},
+	}
+	if s.WriterCollection != nil {
+		checks = append(checks, readinessCheck{name: "writer queues", err: s.checkQueuedBlocks()})
+	}
+	return checks
+}
+
+//checkWritable creates and removes a file in the directory
+func checkWritable(dir string) error {
+	file, err := ioutil.TempFile(dir, ".readyz")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func (s *Server) checkQueuedBlocks() error {
+	if s.MaxQueuedBlocks <= 0 {
+		return nil
+	}
+	queued := 0
+	for _, sample := range s.WriterCollection.queueDepths() {
+		queued += int(sample.value)
+	}
+	if queued > s.MaxQueuedBlocks {
+		return fmt.Errorf("%v blocks are waiting to be written, more than %v", queued, s.MaxQueuedBlocks)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	Convey("Health Endpoints", t, func() {
+		pathPrefix = "test"
+		os.MkdirAll(pathPrefix, os.ModePerm)
+		collection := NewWriterCollection(NewCache())
+		s := &Server{WriterCollection: collection, MaxQueuedBlocks: 2}
+		get := func(path string) (int, string) {
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, httptest.NewRequest("GET", path, nil))
+			return resp.Code, resp.Body.String()
+		}
+
+		Convey("healthz answers while the server runs", func() {
+			code, body := get("/healthz")
+			So(code, ShouldEqual, 200)
+			So(body, ShouldEqual, "ok\n")
+		})
+
+		Convey("readyz passes when blocks can be written", func() {
+			code, body := get("/readyz")
+			So(code, ShouldEqual, 200)
+			So(body, ShouldEqual, "data directory: ok\nwriter queues: ok\n")
+		})
+
+		Convey("readyz fails when the data directory is missing", func() {
+			pathPrefix = "test/missing"
+			code, body := get("/readyz")
+			So(code, ShouldEqual, 503)
+			So(body, ShouldStartWith, "data directory: ")
+			So(body, ShouldNotStartWith, "data directory: ok")
+		})
+
+		Convey("readyz fails when too many blocks wait for the writers", func() {
+			// a writer that isn't running keeps its blocks queued
+			writer := &Writer{Service: "test", Level: "queued", InChannel: make(chan *Block, 3)}
+			collection.writers[writer.HashKey()] = writer
+			for i := 0; i < 3; i++ {
+				writer.InChannel <- &Block{}
+			}
+			code, body := get("/readyz")
+			So(code, ShouldEqual, 503)
+			So(body, ShouldContainSubstring, "writer queues: 3 blocks are waiting to be written, more than 2\n")
+
+			s.MaxQueuedBlocks = 0
+			code, _ = get("/readyz")
+			So(code, ShouldEqual, 200)
+		})
+	})
+
+	os.RemoveAll("test")
+}
@@ -0,0 +1,63 @@
+package log
+
+import (
+	"sort"
+)
+
+//maxHistogramBuckets limits the size of a histogram, so a tiny bucket size can't exhaust the memory
+const maxHistogramBuckets = 10000
+
+//GetHistogram counts the messages in the timerange per bucket of bucketSeconds, grouped by service and level.
+//The first bucket starts at startTime rounded down to a multiple of bucketSeconds.
+//Service and level restrict the counted messages the same way they restrict the message queries.
+func (r *Reader) GetHistogram(startTime, endTime, bucketSeconds int64, service, level string) *GetHistogramResponse {
+	histogram := &GetHistogramResponse{
+		StartTime:     histogramStart(startTime, bucketSeconds),
+		BucketSeconds: bucketSeconds,
+	}
+	bucketCount := histogramBucketCount(startTime, endTime, bucketSeconds)
+
+	for _, block := range r.blocksInTimeRange(startTime, endTime, service, level) {
+		series := &HistogramSeries{
+			Service: block.Service,
+			Level:   block.Level,
+			Counts:  make([]int64, bucketCount),
+		}
+		counted := false
+		for _, message := range block.Messages {
+			if !message.IsInTimeRange(startTime, endTime) {
+				continue
+			}
+			series.Counts[(message.Timestamp-histogram.StartTime)/bucketSeconds]++
+			counted = true
+		}
+		if counted {
+			histogram.Series = append(histogram.Series, series)
+		}
+	}
+
+	sort.Slice(histogram.Series, func(i, j int) bool {
+		a, b := histogram.Series[i], histogram.Series[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Level < b.Level
+	})
+	return histogram
+}
+
+func histogramStart(startTime, bucketSeconds int64) int64 {
+	start := startTime - startTime%bucketSeconds
+	if start > startTime {
+		// the remainder of negative times is negative
+		start -= bucketSeconds
+	}
+	return start
+}
+
+func histogramBucketCount(startTime, endTime, bucketSeconds int64) int64 {
+	if endTime < startTime {
+		return 0
+	}
+	return (endTime-histogramStart(startTime, bucketSeconds))/bucketSeconds + 1
+}
@@ -0,0 +1,192 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultLineLevel = "standard"
+
+//ServeLineTCP reads newline-delimited messages from the connections of listener until accepting fails,
+//every line is either "service level text" or logfmt
+func (s *Server) ServeLineTCP(listener net.Listener) error {
+	return s.acceptConnections(listener, s.serveLineConn)
+}
+
+func (s *Server) serveLineConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReaderSize(conn, maxListenerMessageSize)
+	for {
+		conn.SetReadDeadline(time.Now().Add(listenerReadTimeout))
+		line, err := readLine(reader)
+		if len(strings.TrimSpace(string(line))) > 0 {
+			s.receiveLine(string(line))
+		}
+		if err != nil {
+			if err != io.EOF && !isTimeout(err) && !s.listeners.isClosed() {
+				metrics.listenerInvalidMessages.inc("lines_tcp")
+			}
+			return
+		}
+	}
+}
+
+//receiveLine parses the line and adds it to the block of its service and level
+func (s *Server) receiveLine(line string) {
+	metrics.listenerMessages.inc("lines_tcp")
+	service, level, message, err := parseLine(line, time.Now())
+	if err != nil {
+		metrics.listenerInvalidMessages.inc("lines_tcp")
+		return
+	}
+	s.listenerBatcher().add(service, level, message)
+}
+
+//parseLine parses lines whose first word contains a "=" as logfmt and all others as "service level text",
+//lines whose service or level isn't a valid name are rejected
+func parseLine(line string, received time.Time) (service, level string, message *Message, err error) {
+	service, level, message, err = parseLineFormat(line, received)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if !ValidName(service) || !ValidName(level) {
+		return "", "", nil, fmt.Errorf("invalid service or level in %q", line)
+	}
+	return service, level, message, nil
+}
+
+func parseLineFormat(line string, received time.Time) (service, level string, message *Message, err error) {
+	line = strings.TrimSpace(line)
+	first := line
+	if space := strings.IndexByte(line, ' '); space >= 0 {
+		first = line[:space]
+	}
+	if strings.Contains(first, "=") {
+		return parseLogfmtLine(line, received)
+	}
+
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return "", "", nil, fmt.Errorf("line without level: %q", line)
+	}
+	message = &Message{Timestamp: received.Unix()}
+	if len(parts) == 3 {
+		message.Text = parts[2]
+	}
+	return parts[0], parts[1], message, nil
+}
+
+//parseLogfmtLine takes the service, level, text and timestamp out of the keys service, level, msg (or message) and time (or ts),
+//the other keys become fields
+func parseLogfmtLine(line string, received time.Time) (service, level string, message *Message, err error) {
+	pairs, err := parseLogfmt(line)
+	if err != nil {
+		return "", "", nil, err
+	}
+	service = takeValue(pairs, "service")
+	if service == "" {
+		return "", "", nil, fmt.Errorf("line without service: %q", line)
+	}
+	level = takeValue(pairs, "level")
+	if level == "" {
+		level = defaultLineLevel
+	}
+	message = &Message{Text: takeValue(pairs, "msg", "message"), Timestamp: received.Unix()}
+	if timestamp := takeValue(pairs, "time", "ts"); timestamp != "" {
+		if message.Timestamp, err = parseLineTimestamp(timestamp); err != nil {
+			return "", "", nil, err
+		}
+	}
+	if len(pairs) > 0 {
+		message.Fields = pairs
+	}
+	return service, level, message, nil
+}
+
+//takeValue removes the first of the keys that is set from pairs and returns its value
+func takeValue(pairs map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := pairs[key]; ok {
+			delete(pairs, key)
+			return value
+		}
+	}
+	return ""
+}
+
+//parseLineTimestamp accepts RFC 3339 timestamps and unix seconds
+func parseLineTimestamp(value string) (int64, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+	return timestamp.Unix(), nil
+}
+
+//parseLogfmt parses key=value pairs separated by spaces, values with spaces are quoted like Go strings.
+//Keys without "=" get empty values.
+func parseLogfmt(line string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for line != "" {
+		if line[0] == ' ' {
+			line = line[1:]
+			continue
+		}
+		end := strings.IndexAny(line, "= ")
+		if end < 0 {
+			end = len(line)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("logfmt pair without key")
+		}
+		key := line[:end]
+		line = line[end:]
+		if !strings.HasPrefix(line, "=") {
+			pairs[key] = ""
+			continue
+		}
+		line = line[1:]
+
+		if !strings.HasPrefix(line, `"`) {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			pairs[key] = line[:end]
+			line = line[end:]
+			continue
+		}
+		end = closingQuote(line)
+		if end < 0 {
+			return nil, fmt.Errorf("value of %v is not closed", key)
+		}
+		value, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("value of %v: %v", key, err)
+		}
+		pairs[key] = value
+		line = line[end+1:]
+	}
+	return pairs, nil
+}
+
+//closingQuote is the index of the quote that closes the one at the start of s, -1 if there is none
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
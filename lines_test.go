@@ -0,0 +1,164 @@
+package log
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseLine(t *testing.T) {
+	Convey("parseLine", t, func() {
+		received := time.Unix(1000, 0)
+
+		Convey("parses service, level and text", func() {
+			service, level, message, err := parseLine("api error  Connection refused: db:5432\r", received)
+			So(err, ShouldBeNil)
+			So(service, ShouldEqual, "api")
+			So(level, ShouldEqual, "error")
+			So(message, ShouldResemble, &Message{Text: " Connection refused: db:5432", Timestamp: 1000})
+
+			_, _, message, err = parseLine("api standard", received)
+			So(err, ShouldBeNil)
+			So(message.Text, ShouldEqual, "")
+
+			_, _, _, err = parseLine("api", received)
+			So(err, ShouldNotBeNil)
+
+			_, _, _, err = parseLine("../../tmp/x error Foo", received)
+			So(err, ShouldNotBeNil)
+			_, _, _, err = parseLine("api a/b Foo", received)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("parses logfmt", func() {
+			service, level, message, err := parseLine(`service=api level=warning msg="slow \"query\"" ts=2018-01-01T00:00:00Z duration=2.5s cached`, received)
+			So(err, ShouldBeNil)
+			So(service, ShouldEqual, "api")
+			So(level, ShouldEqual, "warning")
+			So(message, ShouldResemble, &Message{
+				Text:      `slow "query"`,
+				Timestamp: 1514764800,
+				Fields:    map[string]string{"duration": "2.5s", "cached": ""},
+			})
+
+			service, level, message, err = parseLine(`message=Started service=worker time=1234`, received)
+			So(err, ShouldBeNil)
+			So(service, ShouldEqual, "worker")
+			So(level, ShouldEqual, defaultLineLevel)
+			So(message, ShouldResemble, &Message{Text: "Started", Timestamp: 1234})
+		})
+
+		Convey("rejects invalid logfmt", func() {
+			for _, line := range []string{
+				`level=error msg=Foo`,
+				`service=api msg="Foo`,
+				`service=api =Foo`,
+				`service=api time=yesterday`,
+				`service=../../tmp level=error msg=Foo`,
+				`service=api level=a/b msg=Foo`,
+				`service=.. msg=Foo`,
+			} {
+				_, _, _, err := parseLine(line, received)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestLineListener(t *testing.T) {
+	Convey("Line listener", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+		s.IngestWindow = 10 * time.Millisecond
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer listener.Close()
+		go s.ServeLineTCP(listener)
+
+		invalid := metrics.listenerInvalidMessages.value("lines_tcp")
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		So(err, ShouldBeNil)
+		fmt.Fprint(conn, "service=app msg=First time=1000\n\n")
+		fmt.Fprint(conn, "app standard Second\n")
+		fmt.Fprint(conn, "invalid\n")
+		fmt.Fprint(conn, "../../tmp/x error Escaped\n")
+		fmt.Fprint(conn, "service=app level=error msg=Third time=1001")
+		conn.Close()
+
+		time.Sleep(100 * time.Millisecond)
+		s.Shutdown()
+
+		messages := s.Reader.GetServiceLevelMessagesInTimeRange(0, time.Now().Unix()+1, "app", "standard")
+		texts := []string{}
+		for _, message := range messages {
+			texts = append(texts, message.Message.Text)
+		}
+		So(texts, ShouldContain, "First")
+		So(texts, ShouldContain, "Second")
+		errors := s.Reader.GetServiceLevelMessagesInTimeRange(0, 2000, "app", "error")
+		So(len(errors), ShouldEqual, 1)
+		So(errors[0].Message.Text, ShouldEqual, "Third")
+		So(metrics.listenerInvalidMessages.value("lines_tcp"), ShouldEqual, invalid+2)
+	})
+	os.RemoveAll(pathPrefix)
+}
+
+func TestLineListenerShutdown(t *testing.T) {
+	Convey("Line listener shutdown closes the open connections before the batcher stops", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		served := make(chan error, 1)
+		go func() { served <- s.ServeLineTCP(listener) }()
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		fmt.Fprint(conn, "app standard Open\n")
+		time.Sleep(50 * time.Millisecond)
+		s.Shutdown()
+
+		So(<-served, ShouldBeNil)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = conn.Read(make([]byte, 1))
+		So(err, ShouldNotBeNil)
+		messages := s.Reader.GetServiceLevelMessagesInTimeRange(0, time.Now().Unix()+1, "app", "standard")
+		So(len(messages), ShouldEqual, 1)
+
+		So(func() { s.receiveLine("app standard Late") }, ShouldNotPanic)
+	})
+	os.RemoveAll(pathPrefix)
+}
+
+func TestLineListenerReadTimeout(t *testing.T) {
+	Convey("Line listener closes connections that stay idle", t, func() {
+		defer func(timeout time.Duration) { listenerReadTimeout = timeout }(listenerReadTimeout)
+		listenerReadTimeout = 20 * time.Millisecond
+		pathPrefix = "test"
+		s := NewDefaultServer()
+		defer s.Shutdown()
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		go s.ServeLineTCP(listener)
+		invalid := metrics.listenerInvalidMessages.value("lines_tcp")
+
+		idle, err := net.Dial("tcp", listener.Addr().String())
+		So(err, ShouldBeNil)
+		defer idle.Close()
+		idle.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = idle.Read(make([]byte, 1))
+		So(err, ShouldNotBeNil)
+		So(isTimeout(err), ShouldBeFalse)
+		So(metrics.listenerInvalidMessages.value("lines_tcp"), ShouldEqual, invalid)
+	})
+	os.RemoveAll(pathPrefix)
+}
@@ -0,0 +1,114 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+//maxListenerMessageSize limits a single message or line that the listeners read
+const maxListenerMessageSize = 64 * 1024
+
+var errMessageTooLarge = errors.New("message is too large")
+
+//listenerReadTimeout closes connections that don't send anything for this long
+var listenerReadTimeout = 5 * time.Minute
+
+//listenerSet tracks the listeners and connections that hand messages to the batcher,
+//so Shutdown can close them before it stops the batcher
+type listenerSet struct {
+	mutex   sync.Mutex
+	closed  bool
+	closers map[io.Closer]bool
+	serving sync.WaitGroup
+}
+
+//open tracks c until done is called, once the set is closed it closes c right away and returns false
+func (l *listenerSet) open(c io.Closer) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		c.Close()
+		return false
+	}
+	if l.closers == nil {
+		l.closers = map[io.Closer]bool{}
+	}
+	l.closers[c] = true
+	l.serving.Add(1)
+	return true
+}
+
+//done stops tracking c
+func (l *listenerSet) done(c io.Closer) {
+	l.mutex.Lock()
+	delete(l.closers, c)
+	l.mutex.Unlock()
+	l.serving.Done()
+}
+
+func (l *listenerSet) isClosed() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.closed
+}
+
+//close closes every listener and connection and waits until they handed their last message to the batcher
+func (l *listenerSet) close() {
+	l.mutex.Lock()
+	l.closed = true
+	for c := range l.closers {
+		c.Close()
+	}
+	l.mutex.Unlock()
+	l.serving.Wait()
+}
+
+//acceptConnections hands every connection of listener to serve in its own goroutine until accepting fails
+//or the server shuts down
+func (s *Server) acceptConnections(listener net.Listener, serve func(conn net.Conn)) error {
+	if !s.listeners.open(listener) {
+		return nil
+	}
+	defer s.listeners.done(listener)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.listeners.isClosed() {
+				return nil
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		if !s.listeners.open(conn) {
+			continue
+		}
+		go func() {
+			defer s.listeners.done(conn)
+			serve(conn)
+		}()
+	}
+}
+
+//isTimeout reports whether err is a read deadline that expired
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+//readLine reads up to the next newline and returns the line without it,
+//the line is only valid until the next read from reader
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, errMessageTooLarge
+	}
+	return bytes.TrimRight(line, "\r\n"), err
+}
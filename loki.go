@@ -0,0 +1,296 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+//defaultLokiService is the service of streams without any of the service labels
+const defaultLokiService = "unknown_service"
+
+//default stream labels that name the service and the level of Loki pushes, in the order they are looked up
+var (
+	defaultLokiServiceLabels = []string{"service_name", "service", "app", "job", "container_name"}
+	defaultLokiLevelLabels   = []string{"level", "detected_level", "severity"}
+)
+
+//lokiPushRequest is a Loki push, the JSON tags follow the JSON encoding of the push API
+type lokiPushRequest struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Labels  map[string]string `json:"stream"`
+	Entries []*lokiEntry      `json:"values"`
+}
+
+//lokiEntry is a log line, its JSON encoding is [timestamp in nanoseconds, line, structured metadata]
+type lokiEntry struct {
+	Timestamp time.Time
+	Line      string
+	Metadata  map[string]string
+}
+
+func (e *lokiEntry) UnmarshalJSON(data []byte) error {
+	values := []json.RawMessage{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	if len(values) < 2 || len(values) > 3 {
+		return fmt.Errorf("entry with %v values", len(values))
+	}
+	var timestamp string
+	if err := json.Unmarshal(values[0], &timestamp); err != nil {
+		return err
+	}
+	nanoseconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	e.Timestamp = time.Unix(0, nanoseconds)
+	if err := json.Unmarshal(values[1], &e.Line); err != nil {
+		return err
+	}
+	if len(values) == 3 {
+		return json.Unmarshal(values[2], &e.Metadata)
+	}
+	return nil
+}
+
+//handleLokiPush accepts pushes of the Loki push API, as JSON or as snappy compressed protobuf like promtail sends them
+func (s *Server) handleLokiPush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != "application/x-protobuf" && contentType != "application/json" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	bytes, err := readRequestBody(r, s.MaxDecompressedBodySize)
+	if err != nil {
+		w.WriteHeader(bodyErrorStatus(err))
+		return
+	}
+
+	request := &lokiPushRequest{}
+	if contentType == "application/json" {
+		err = json.Unmarshal(bytes, request)
+	} else {
+		request, err = decodeLokiPushRequest(bytes, s.MaxDecompressedBodySize)
+	}
+	if err == errBodyTooLarge {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	blocks := request.blocks(s.lokiServiceLabels(), s.lokiLevelLabels())
+	status, retryAfter := s.admitBlocks(requestKey(r), blocks)
+	if status != http.StatusOK {
+		rejectBlocks(w, status, retryAfter)
+		return
+	}
+	s.ingest(blocks)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) lokiServiceLabels() []string {
+	if len(s.LokiServiceLabels) == 0 {
+		return defaultLokiServiceLabels
+	}
+	return s.LokiServiceLabels
+}
+
+func (s *Server) lokiLevelLabels() []string {
+	if len(s.LokiLevelLabels) == 0 {
+		return defaultLokiLevelLabels
+	}
+	return s.LokiLevelLabels
+}
+
+//blocks groups the entries by service and level, the first of the service and level labels that a stream has set
+//name its service and level and all other labels are stored as fields, along with the structured metadata of the entries.
+//Characters that aren't allowed in service names are replaced with underscores.
+func (request *lokiPushRequest) blocks(serviceLabels, levelLabels []string) []*Block {
+	blocksByKey := map[string]*Block{}
+	for _, stream := range request.Streams {
+		labels := map[string]string{}
+		for name, value := range stream.Labels {
+			labels[name] = value
+		}
+		// label values like the namespace/app of kubernetes jobs aren't valid service names,
+		// rejecting them would stop promtail, which retries the same push
+		service := sanitizeName(takeValue(labels, serviceLabels...))
+		if service == "" {
+			service = defaultLokiService
+		}
+		level := levelFromName(takeValue(labels, levelLabels...))
+
+		key := WriterKeyFor(service, level)
+		for _, entry := range stream.Entries {
+			block, ok := blocksByKey[key]
+			if !ok {
+				block = &Block{Service: service, Level: level}
+				blocksByKey[key] = block
+			}
+			block.Messages = append(block.Messages, entry.message(labels))
+		}
+	}
+	return sealBlocks(blocksByKey)
+}
+
+func (e *lokiEntry) message(labels map[string]string) *Message {
+	var fields map[string]string
+	if len(labels) > 0 || len(e.Metadata) > 0 {
+		fields = map[string]string{}
+	}
+	for name, value := range labels {
+		fields[name] = value
+	}
+	for name, value := range e.Metadata {
+		fields[name] = value
+	}
+	return &Message{Text: e.Line, Timestamp: e.Timestamp.Unix(), Fields: fields}
+}
+
+//decodeLokiPushRequest decompresses and reads the protobuf encoding of a PushRequest,
+//the decompressed push may not be larger than maxSize
+func decodeLokiPushRequest(compressed []byte, maxSize int64) (*lokiPushRequest, error) {
+	size, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, err
+	}
+	if int64(size) > maxSize {
+		return nil, errBodyTooLarge
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, err
+	}
+	request := &lokiPushRequest{}
+	return request, decodeFields(&wireReader{data: data}, request.decode)
+}
+
+func (request *lokiPushRequest) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if field != 1 || wireType != wireBytes {
+		return false, nil
+	}
+	stream := &lokiStream{}
+	request.Streams = append(request.Streams, stream)
+	return true, decodeEmbeddedMessage(r, stream.decode)
+}
+
+func (stream *lokiStream) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if wireType != wireBytes {
+		return false, nil
+	}
+	switch field {
+	case 1:
+		labels, err := r.string()
+		if err != nil {
+			return true, err
+		}
+		stream.Labels, err = parseLokiLabels(labels)
+		return true, err
+	case 2:
+		entry := &lokiEntry{}
+		stream.Entries = append(stream.Entries, entry)
+		return true, decodeEmbeddedMessage(r, entry.decode)
+	}
+	return false, nil
+}
+
+func (e *lokiEntry) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if wireType != wireBytes {
+		return false, nil
+	}
+	var err error
+	switch field {
+	case 1:
+		var seconds, nanos uint64
+		err = decodeEmbeddedMessage(r, func(r *wireReader, field uint64, wireType int) (bool, error) {
+			var err error
+			switch {
+			case field == 1 && wireType == wireVarint:
+				seconds, err = r.varint()
+			case field == 2 && wireType == wireVarint:
+				nanos, err = r.varint()
+			default:
+				return false, nil
+			}
+			return true, err
+		})
+		e.Timestamp = time.Unix(int64(seconds), int64(nanos))
+	case 2:
+		e.Line, err = r.string()
+	case 3:
+		var name, value string
+		err = decodeEmbeddedMessage(r, func(r *wireReader, field uint64, wireType int) (bool, error) {
+			var err error
+			switch {
+			case field == 1 && wireType == wireBytes:
+				name, err = r.string()
+			case field == 2 && wireType == wireBytes:
+				value, err = r.string()
+			default:
+				return false, nil
+			}
+			return true, err
+		})
+		if e.Metadata == nil {
+			e.Metadata = map[string]string{}
+		}
+		e.Metadata[name] = value
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+//parseLokiLabels parses the labels of a stream in the Prometheus format, {name="value", other="value"}
+func parseLokiLabels(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("labels %q are not enclosed in braces", s)
+	}
+	labels := map[string]string{}
+	rest := s[1 : len(s)-1]
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		if rest == "" {
+			return labels, nil
+		}
+		equals := strings.IndexByte(rest, '=')
+		if equals <= 0 {
+			return nil, fmt.Errorf("label without value in %q", s)
+		}
+		name := strings.TrimSpace(rest[:equals])
+		rest = strings.TrimSpace(rest[equals+1:])
+		end := -1
+		if strings.HasPrefix(rest, `"`) {
+			end = closingQuote(rest)
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("value of label %v is not quoted", name)
+		}
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("value of label %v: %v", name, err)
+		}
+		labels[name] = value
+		rest = rest[end+1:]
+	}
+}
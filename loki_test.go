@@ -0,0 +1,174 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/golang/snappy"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const lokiJSONRequest = `{
+	"streams": [{
+		"stream": {"job": "checkout", "level": "warn", "host": "web-1"},
+		"values": [
+			["1544712661000000000", "slow payment"],
+			["1544712660300000000", "retrying payment", {"trace_id": "5b8efff798038103d269b633813fc60c"}]
+		]
+	}, {
+		"stream": {"filename": "/var/log/syslog"},
+		"values": [["1000000000000", "no service"]]
+	}]
+}`
+
+//lokiProtobufRequest has the first stream of lokiJSONRequest and an unknown field in every message
+func lokiProtobufRequest() []byte {
+	entry := func(seconds, nanos uint64, line string, metadata ...[]byte) []byte {
+		return protobufBytes(2,
+			protobufBytes(1, protobufVarint(1, seconds), protobufVarint(2, nanos), protobufVarint(3, 0)),
+			protobufString(2, line),
+			bytes.Join(metadata, nil),
+			protobufVarint(4, 1),
+		)
+	}
+	stream := bytes.Join([][]byte{
+		protobufString(1, `{job="checkout", level="warn",host="web-1"}`),
+		entry(1544712661, 0, "slow payment"),
+		entry(1544712660, 300000000, "retrying payment",
+			protobufBytes(3, protobufString(1, TraceIDField), protobufString(2, "5b8efff798038103d269b633813fc60c"))),
+		protobufVarint(3, 12345),
+	}, nil)
+	return snappy.Encode(nil, protobufBytes(1, stream))
+}
+
+var lokiCheckoutMessages = []*Message{
+	{
+		Text:      "retrying payment",
+		Timestamp: 1544712660,
+		Fields:    map[string]string{"host": "web-1", TraceIDField: "5b8efff798038103d269b633813fc60c"},
+	},
+	{Text: "slow payment", Timestamp: 1544712661, Fields: map[string]string{"host": "web-1"}},
+}
+
+func TestLokiPushRequest(t *testing.T) {
+	Convey("Loki push requests", t, func() {
+		Convey("map the JSON encoding to blocks", func() {
+			request := &lokiPushRequest{}
+			So(json.Unmarshal([]byte(lokiJSONRequest), request), ShouldBeNil)
+			blocks := request.blocks(defaultLokiServiceLabels, defaultLokiLevelLabels)
+			So(len(blocks), ShouldEqual, 2)
+
+			So(blocks[0].Service, ShouldEqual, "checkout")
+			So(blocks[0].Level, ShouldEqual, "warning")
+			So(blocks[0].StartTime, ShouldEqual, 1544712660)
+			So(blocks[0].EndTime, ShouldEqual, 1544712661)
+			So(blocks[0].Messages, ShouldResemble, lokiCheckoutMessages)
+
+			So(blocks[1].Service, ShouldEqual, defaultLokiService)
+			So(blocks[1].Level, ShouldEqual, "standard")
+			So(blocks[1].Messages, ShouldResemble, []*Message{{
+				Text:      "no service",
+				Timestamp: 1000,
+				Fields:    map[string]string{"filename": "/var/log/syslog"},
+			}})
+		})
+
+		Convey("map the configured labels", func() {
+			request := &lokiPushRequest{}
+			So(json.Unmarshal([]byte(lokiJSONRequest), request), ShouldBeNil)
+			blocks := request.blocks([]string{"host"}, []string{"severity"})
+			So(len(blocks), ShouldEqual, 2)
+			So(blocks[1].Service, ShouldEqual, "web-1")
+			So(blocks[1].Level, ShouldEqual, "standard")
+			So(blocks[1].Messages[1].Fields, ShouldResemble, map[string]string{"job": "checkout", "level": "warn"})
+		})
+
+		Convey("replace the characters that service names don't allow", func() {
+			request := &lokiPushRequest{}
+			So(json.Unmarshal([]byte(`{"streams": [
+				{"stream": {"job": "payments/checkout"}, "values": [["1000000000000", "slow payment"]]},
+				{"stream": {"job": ".."}, "values": [["1000000000000", "no service"]]}
+			]}`), request), ShouldBeNil)
+			blocks := request.blocks(defaultLokiServiceLabels, defaultLokiLevelLabels)
+			So(len(blocks), ShouldEqual, 2)
+			So(blocks[0].Service, ShouldEqual, "payments_checkout")
+			So(blocks[1].Service, ShouldEqual, defaultLokiService)
+			for _, block := range blocks {
+				So(block.Valid(), ShouldBeTrue)
+			}
+		})
+
+		Convey("map the snappy compressed protobuf encoding to blocks", func() {
+			request, err := decodeLokiPushRequest(lokiProtobufRequest(), defaultMaxDecompressedBodySize)
+			So(err, ShouldBeNil)
+			blocks := request.blocks(defaultLokiServiceLabels, defaultLokiLevelLabels)
+			So(len(blocks), ShouldEqual, 1)
+			So(blocks[0].Service, ShouldEqual, "checkout")
+			So(blocks[0].Level, ShouldEqual, "warning")
+			So(blocks[0].Messages, ShouldResemble, lokiCheckoutMessages)
+
+			_, err = decodeLokiPushRequest(lokiProtobufRequest(), 10)
+			So(err, ShouldEqual, errBodyTooLarge)
+			_, err = decodeLokiPushRequest([]byte("not snappy"), defaultMaxDecompressedBodySize)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("reject invalid JSON entries", func() {
+			for _, body := range []string{
+				`{"streams": [{"values": [["1000"]]}]}`,
+				`{"streams": [{"values": [["yesterday", "line"]]}]}`,
+				`{"streams": [{"values": [[1000, "line"]]}]}`,
+			} {
+				So(json.Unmarshal([]byte(body), &lokiPushRequest{}), ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestParseLokiLabels(t *testing.T) {
+	Convey("parseLokiLabels", t, func() {
+		labels, err := parseLokiLabels(` {job="varlogs", path="C:\\logs", msg="say \"hi\", bye",} `)
+		So(err, ShouldBeNil)
+		So(labels, ShouldResemble, map[string]string{"job": "varlogs", "path": `C:\logs`, "msg": `say "hi", bye`})
+
+		labels, err = parseLokiLabels("{}")
+		So(err, ShouldBeNil)
+		So(labels, ShouldBeEmpty)
+
+		for _, invalid := range []string{`job="varlogs"`, `{job}`, `{job=varlogs}`, `{job="varlogs}`} {
+			_, err = parseLokiLabels(invalid)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestLokiPushEndpoint(t *testing.T) {
+	Convey("Loki push endpoint", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+
+		post := func(contentType string, body []byte) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", "/loki/api/v1/push", bytes.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			return resp
+		}
+
+		So(post("application/json", []byte(lokiJSONRequest)).Code, ShouldEqual, http.StatusNoContent)
+		So(post("application/x-protobuf", lokiProtobufRequest()).Code, ShouldEqual, http.StatusNoContent)
+		So(post("application/json", []byte(`{"streams": [{"stream": {"job": "payments/checkout"}, "values": [["1000000000000", "slow payment"]]}]}`)).Code, ShouldEqual, http.StatusNoContent)
+		So(post("application/json", []byte(`{"streams": {}}`)).Code, ShouldEqual, http.StatusBadRequest)
+		So(post("text/plain", []byte("slow payment")).Code, ShouldEqual, http.StatusUnsupportedMediaType)
+
+		s.MaxDecompressedBodySize = 10
+		So(post("application/x-protobuf", lokiProtobufRequest()).Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+
+		s.Shutdown()
+	})
+	os.RemoveAll(pathPrefix)
+}
@@ -1,6 +1,19 @@
 package log
 
+//Field names under which the client stores request correlation info in Message.Fields
+const (
+	TraceIDField   = "trace_id"
+	SpanIDField    = "span_id"
+	RequestIDField = "request_id"
+)
+
 //IsInTimeRange checks if the message is in the timerange
 func (m *Message) IsInTimeRange(startTime, endTime int64) bool {
 	return m.Timestamp >= startTime && m.Timestamp <= endTime
 }
+
+//HasField checks if the message has the field set to the given value
+func (m *Message) HasField(key, value string) bool {
+	fieldValue, ok := m.GetFields()[key]
+	return ok && fieldValue == value
+}
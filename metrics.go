@@ -0,0 +1,298 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//serverMetrics of the server, they are served in the Prometheus text format at /metrics
+type serverMetrics struct {
+	messagesAccepted     *counterVec
+	blocksAccepted       *counterVec
+	writeErrors          *counterVec
+	blockWriteDuration   *histogramVec
+	cacheEvictedMessages *counterVec
+	filesScanned         *counterVec
+	queryDuration        *histogramVec
+	rateLimitedRequests  *counterVec
+	//listenerMessages and listenerInvalidMessages count what the syslog and line listeners receive
+	listenerMessages        *counterVec
+	listenerInvalidMessages *counterVec
+	droppedMessages         *counterVec
+}
+
+var defaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var metrics = newMetrics()
+
+func newMetrics() *serverMetrics {
+	return &serverMetrics{
+		messagesAccepted:        newCounterVec("log_messages_accepted_total", "Messages accepted by the post endpoint.", "service", "level"),
+		blocksAccepted:          newCounterVec("log_blocks_accepted_total", "Blocks accepted by the post endpoint.", "service", "level"),
+		writeErrors:             newCounterVec("log_block_write_errors_total", "Blocks that could not be written to disk.", "service", "level"),
+		blockWriteDuration:      newHistogramVec("log_block_write_duration_seconds", "Time it takes to write a block to disk.", defaultDurationBuckets),
+		cacheEvictedMessages:    newCounterVec("log_cache_evicted_messages_total", "Messages removed from the cache to stay below its limit."),
+		filesScanned:            newCounterVec("log_file_reader_files_scanned_total", "Block files read from disk to answer queries."),
+		queryDuration:           newHistogramVec("log_query_duration_seconds", "Time it takes to answer a query.", defaultDurationBuckets, "endpoint"),
+		rateLimitedRequests:     newCounterVec("log_rate_limited_requests_total", "Posts rejected because the service was over one of its limits.", "service"),
+		listenerMessages:        newCounterVec("log_listener_messages_total", "Messages received by the listeners.", "listener"),
+		listenerInvalidMessages: newCounterVec("log_listener_invalid_messages_total", "Messages of the listeners that could not be parsed.", "listener"),
+		droppedMessages:         newCounterVec("log_listener_dropped_messages_total", "Messages of the listeners dropped because the service was over one of its limits.", "service"),
+	}
+}
+
+//counterVec is a counter per combination of label values
+type counterVec struct {
+	name, help string
+	labelNames []string
+	mutex      sync.Mutex
+	values     map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+//newCounterVec creates a counter with the label names
+func newCounterVec(name, help string, labelNames ...string) *counterVec {
+	return &counterVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		values:     map[string]*counterValue{},
+	}
+}
+
+//add delta to the counter of the label values
+func (c *counterVec) add(delta float64, labels ...string) {
+	key := strings.Join(labels, "\xff")
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	value, ok := c.values[key]
+	if !ok {
+		value = &counterValue{labels: labels}
+		c.values[key] = value
+	}
+	value.value += delta
+}
+
+//inc adds one to the counter of the label values
+func (c *counterVec) inc(labels ...string) {
+	c.add(1, labels...)
+}
+
+//value returns the counter of the label values
+func (c *counterVec) value(labels ...string) float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if value, ok := c.values[strings.Join(labels, "\xff")]; ok {
+		return value.value
+	}
+	return 0
+}
+
+//write writes the counters in the Prometheus text format
+func (c *counterVec) write(w io.Writer) error {
+	return c.writeFor(w, nil)
+}
+
+//writeFor writes the counters whose service and level the key allows
+func (c *counterVec) writeFor(w io.Writer, key *APIKey) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := writeMetricHeader(w, c.name, c.help, "counter"); err != nil {
+		return err
+	}
+	if len(c.labelNames) == 0 && len(c.values) == 0 {
+		_, err := fmt.Fprintf(w, "%v 0\n", c.name)
+		return err
+	}
+	for _, valueKey := range sortedValueKeys(c.values) {
+		value := c.values[valueKey]
+		if !key.allowsSample(c.labelNames, value.labels) {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%v%v %v\n", c.name, formatLabels(c.labelNames, value.labels), formatFloat(value.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//histogramVec counts observations into buckets per combination of label values
+type histogramVec struct {
+	name, help string
+	labelNames []string
+	buckets    []float64
+	mutex      sync.Mutex
+	values     map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	// counts per bucket, not cumulative
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+//newHistogramVec creates a histogram with the upper bounds of its buckets and label names
+func newHistogramVec(name, help string, buckets []float64, labelNames ...string) *histogramVec {
+	return &histogramVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		values:     map[string]*histogramValue{},
+	}
+}
+
+//observe adds a value to the histogram of the label values
+func (h *histogramVec) observe(observed float64, labels ...string) {
+	key := strings.Join(labels, "\xff")
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	value, ok := h.values[key]
+	if !ok {
+		value = &histogramValue{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = value
+	}
+	for i, bound := range h.buckets {
+		if observed <= bound {
+			value.counts[i]++
+			break
+		}
+	}
+	value.sum += observed
+	value.count++
+}
+
+//observeSince adds the seconds since start to the histogram of the label values
+func (h *histogramVec) observeSince(start time.Time, labels ...string) {
+	h.observe(time.Since(start).Seconds(), labels...)
+}
+
+//write writes the histograms in the Prometheus text format
+func (h *histogramVec) write(w io.Writer) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err := writeMetricHeader(w, h.name, h.help, "histogram"); err != nil {
+		return err
+	}
+	for _, key := range sortedHistogramKeys(h.values) {
+		value := h.values[key]
+		bucketLabels := append(append([]string{}, h.labelNames...), "le")
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += value.counts[i]
+			labels := formatLabels(bucketLabels, append(append([]string{}, value.labels...), formatFloat(bound)))
+			if _, err := fmt.Fprintf(w, "%v_bucket%v %v\n", h.name, labels, cumulative); err != nil {
+				return err
+			}
+		}
+		labels := formatLabels(bucketLabels, append(append([]string{}, value.labels...), "+Inf"))
+		if _, err := fmt.Fprintf(w, "%v_bucket%v %v\n", h.name, labels, value.count); err != nil {
+			return err
+		}
+		labels = formatLabels(h.labelNames, value.labels)
+		if _, err := fmt.Fprintf(w, "%v_sum%v %v\n%v_count%v %v\n", h.name, labels, formatFloat(value.sum), h.name, labels, value.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//gaugeSample is a gauge value that is read when the metrics are written
+type gaugeSample struct {
+	labels []string
+	value  float64
+}
+
+func writeGauge(w io.Writer, name, help string, labelNames []string, samples []gaugeSample) error {
+	if err := writeMetricHeader(w, name, help, "gauge"); err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		if _, err := fmt.Fprintf(w, "%v%v %v\n", name, formatLabels(labelNames, sample.labels), formatFloat(sample.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//allowsSample reports if the key may see a sample, samples with service and level labels are filtered like messages
+func (k *APIKey) allowsSample(names, values []string) bool {
+	service, level := "", ""
+	for i, name := range names {
+		if i >= len(values) {
+			break
+		}
+		switch name {
+		case "service":
+			service = values[i]
+		case "level":
+			level = values[i]
+		}
+	}
+	if service == "" {
+		return true
+	}
+	if level == "" {
+		return k.allowsService(service)
+	}
+	return k.allows(service, level)
+}
+
+func writeMetricHeader(w io.Writer, name, help, metricType string) error {
+	_, err := fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, metricType)
+	return err
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := []string{}
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, name+"=\""+escapeLabelValue(value)+"\"")
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedValueKeys(values map[string]*counterValue) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedHistogramKeys(values map[string]*histogramValue) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
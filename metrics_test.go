@@ -0,0 +1,50 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetrics(t *testing.T) {
+	Convey("counterVec", t, func() {
+		counter := newCounterVec("test_total", "Things that happened.", "service", "level")
+		counter.add(2, "b", "x")
+		counter.inc("a", "quote\"d")
+		counter.inc("b", "x")
+
+		buffer := &bytes.Buffer{}
+		So(counter.write(buffer), ShouldBeNil)
+		So(buffer.String(), ShouldEqual,
+			"# HELP test_total Things that happened.\n"+
+				"# TYPE test_total counter\n"+
+				"test_total{service=\"a\",level=\"quote\\\"d\"} 1\n"+
+				"test_total{service=\"b\",level=\"x\"} 3\n")
+		So(counter.value("b", "x"), ShouldEqual, 3)
+
+		Convey("without labels it starts at zero", func() {
+			buffer := &bytes.Buffer{}
+			So(newCounterVec("empty_total", "Nothing.").write(buffer), ShouldBeNil)
+			So(buffer.String(), ShouldEndWith, "empty_total 0\n")
+		})
+	})
+
+	Convey("histogramVec", t, func() {
+		histogram := newHistogramVec("test_seconds", "Durations.", []float64{0.1, 1}, "endpoint")
+		histogram.observe(0.05, "search")
+		histogram.observe(0.5, "search")
+		histogram.observe(3, "search")
+
+		buffer := &bytes.Buffer{}
+		So(histogram.write(buffer), ShouldBeNil)
+		So(buffer.String(), ShouldEqual,
+			"# HELP test_seconds Durations.\n"+
+				"# TYPE test_seconds histogram\n"+
+				"test_seconds_bucket{endpoint=\"search\",le=\"0.1\"} 1\n"+
+				"test_seconds_bucket{endpoint=\"search\",le=\"1\"} 2\n"+
+				"test_seconds_bucket{endpoint=\"search\",le=\"+Inf\"} 3\n"+
+				"test_seconds_sum{endpoint=\"search\"} 3.55\n"+
+				"test_seconds_count{endpoint=\"search\"} 3\n")
+	})
+}
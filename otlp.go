@@ -0,0 +1,455 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//otlpServiceAttribute is the resource attribute that names the service
+	otlpServiceAttribute = "service.name"
+	//defaultOTLPService is what the OpenTelemetry SDKs call services without a name
+	defaultOTLPService = "unknown_service"
+	otlpSeverityField  = "severity"
+)
+
+//otlpLogsRequest is the part of an OTLP ExportLogsServiceRequest that the server stores,
+//the JSON tags follow the OTLP JSON encoding
+type otlpLogsRequest struct {
+	ResourceLogs []*otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource     `json:"resource"`
+	ScopeLogs []*otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	LogRecords []*otlpLogRecord `json:"logRecords"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         otlpUint64      `json:"timeUnixNano"`
+	ObservedTimeUnixNano otlpUint64      `json:"observedTimeUnixNano"`
+	SeverityNumber       int32           `json:"severityNumber"`
+	SeverityText         string          `json:"severityText"`
+	Body                 *otlpAnyValue   `json:"body"`
+	Attributes           []*otlpKeyValue `json:"attributes"`
+	TraceID              otlpID          `json:"traceId"`
+	SpanID               otlpID          `json:"spanId"`
+}
+
+type otlpKeyValue struct {
+	Key   string        `json:"key"`
+	Value *otlpAnyValue `json:"value"`
+}
+
+//otlpAnyValue has one of its values set
+type otlpAnyValue struct {
+	StringValue *string          `json:"stringValue"`
+	BoolValue   *bool            `json:"boolValue"`
+	IntValue    *otlpInt64       `json:"intValue"`
+	DoubleValue *float64         `json:"doubleValue"`
+	ArrayValue  *otlpArrayValue  `json:"arrayValue"`
+	KvlistValue *otlpKvlistValue `json:"kvlistValue"`
+	BytesValue  []byte           `json:"bytesValue"`
+}
+
+type otlpArrayValue struct {
+	Values []*otlpAnyValue `json:"values"`
+}
+
+type otlpKvlistValue struct {
+	Values []*otlpKeyValue `json:"values"`
+}
+
+//otlpUint64 and otlpInt64 are encoded as strings in OTLP JSON, some exporters send numbers instead
+type otlpUint64 uint64
+
+type otlpInt64 int64
+
+//otlpID is a trace or span id, hex encoded in OTLP JSON
+type otlpID []byte
+
+func (u *otlpUint64) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseUint(strings.Trim(string(data), `"`), 10, 64)
+	*u = otlpUint64(value)
+	return err
+}
+
+func (i *otlpInt64) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	*i = otlpInt64(value)
+	return err
+}
+
+//UnmarshalJSON accepts base64 as well, which is what generic protobuf JSON encoders produce
+func (id *otlpID) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		if decoded, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return fmt.Errorf("invalid id %q", encoded)
+		}
+	}
+	*id = decoded
+	return nil
+}
+
+//handleOTLPLogsPost accepts OTLP/HTTP log exports in protobuf and JSON, they are stored like posted blocks
+func (s *Server) handleOTLPLogsPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != "application/x-protobuf" && contentType != "application/json" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	bytes, err := readRequestBody(r, s.MaxDecompressedBodySize)
+	if err != nil {
+		w.WriteHeader(bodyErrorStatus(err))
+		return
+	}
+
+	request, err := decodeOTLPLogsRequestFor(contentType, bytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	blocks := request.blocks(time.Now())
+	status, retryAfter := s.admitBlocks(requestKey(r), blocks)
+	if status != http.StatusOK {
+		rejectBlocks(w, status, retryAfter)
+		return
+	}
+	s.ingest(blocks)
+
+	// an empty ExportLogsServiceResponse
+	w.Header().Set("Content-Type", contentType)
+	if contentType == "application/json" {
+		w.Write([]byte("{}"))
+	}
+}
+
+//blocks groups the log records by service and level, sorted by time.
+//Resources whose service.name isn't a valid service name get the default service and keep it as a field, like syslog messages.
+func (request *otlpLogsRequest) blocks(received time.Time) []*Block {
+	blocksByKey := map[string]*Block{}
+	for _, resourceLogs := range request.ResourceLogs {
+		service := defaultOTLPService
+		resourceFields := map[string]string{}
+		for _, attribute := range resourceLogs.Resource.Attributes {
+			if attribute.Key == otlpServiceAttribute && ValidName(attribute.Value.text()) {
+				service = attribute.Value.text()
+			} else {
+				resourceFields[attribute.Key] = attribute.Value.text()
+			}
+		}
+
+		for _, scopeLogs := range resourceLogs.ScopeLogs {
+			for _, record := range scopeLogs.LogRecords {
+				level := otlpLevel(record.SeverityNumber, record.SeverityText)
+				key := WriterKeyFor(service, level)
+				block, ok := blocksByKey[key]
+				if !ok {
+					block = &Block{Service: service, Level: level}
+					blocksByKey[key] = block
+				}
+				block.Messages = append(block.Messages, record.message(resourceFields, received))
+			}
+		}
+	}
+	return sealBlocks(blocksByKey)
+}
+
+//message stores the attributes of the resource and the record as fields, the ones of the record take precedence
+func (record *otlpLogRecord) message(resourceFields map[string]string, received time.Time) *Message {
+	fields := map[string]string{}
+	for key, value := range resourceFields {
+		fields[key] = value
+	}
+	for _, attribute := range record.Attributes {
+		fields[attribute.Key] = attribute.Value.text()
+	}
+	if record.SeverityText != "" {
+		fields[otlpSeverityField] = record.SeverityText
+	}
+	if isSetID(record.TraceID) {
+		fields[TraceIDField] = hex.EncodeToString(record.TraceID)
+	}
+	if isSetID(record.SpanID) {
+		fields[SpanIDField] = hex.EncodeToString(record.SpanID)
+	}
+	if len(fields) == 0 {
+		fields = nil
+	}
+
+	timestamp := received.Unix()
+	if record.TimeUnixNano != 0 {
+		timestamp = int64(record.TimeUnixNano / otlpUint64(time.Second))
+	} else if record.ObservedTimeUnixNano != 0 {
+		timestamp = int64(record.ObservedTimeUnixNano / otlpUint64(time.Second))
+	}
+	return &Message{Text: record.Body.text(), Timestamp: timestamp, Fields: fields}
+}
+
+func isSetID(id []byte) bool {
+	for _, b := range id {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//otlpLevel maps ERROR and FATAL to error, WARN to warning and everything else to standard,
+//records without a severity number are mapped by their severity text
+func otlpLevel(number int32, text string) string {
+	switch {
+	case number >= 17:
+		return "error"
+	case number >= 13:
+		return "warning"
+	case number > 0:
+		return "standard"
+	}
+	return levelFromName(text)
+}
+
+//levelFromName maps the level names of other logging libraries to the levels of the client
+func levelFromName(name string) string {
+	switch strings.ToLower(name) {
+	case "error", "err", "fatal", "critical", "crit", "alert", "emerg", "panic":
+		return "error"
+	case "warn", "warning":
+		return "warning"
+	default:
+		return "standard"
+	}
+}
+
+//text is a string value as it is and every other value JSON encoded
+func (v *otlpAnyValue) text() string {
+	if v != nil && v.StringValue != nil {
+		return *v.StringValue
+	}
+	value := v.value()
+	if value == nil {
+		return ""
+	}
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(bytes)
+}
+
+func (v *otlpAnyValue) value() interface{} {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.ArrayValue != nil:
+		values := []interface{}{}
+		for _, value := range v.ArrayValue.Values {
+			values = append(values, value.value())
+		}
+		return values
+	case v.KvlistValue != nil:
+		values := map[string]interface{}{}
+		for _, keyValue := range v.KvlistValue.Values {
+			values[keyValue.Key] = keyValue.Value.value()
+		}
+		return values
+	case v.BytesValue != nil:
+		return v.BytesValue
+	}
+	return nil
+}
+
+//decodeOTLPLogsRequestFor decodes the body of a request with the content type
+func decodeOTLPLogsRequestFor(contentType string, data []byte) (*otlpLogsRequest, error) {
+	if contentType == "application/json" {
+		request := &otlpLogsRequest{}
+		return request, json.Unmarshal(data, request)
+	}
+	return decodeOTLPLogsRequest(data)
+}
+
+//decodeOTLPLogsRequest reads the protobuf encoding of an ExportLogsServiceRequest
+func decodeOTLPLogsRequest(data []byte) (*otlpLogsRequest, error) {
+	request := &otlpLogsRequest{}
+	return request, decodeFields(&wireReader{data: data}, request.decode)
+}
+
+func (request *otlpLogsRequest) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if field != 1 || wireType != wireBytes {
+		return false, nil
+	}
+	resourceLogs := &otlpResourceLogs{}
+	request.ResourceLogs = append(request.ResourceLogs, resourceLogs)
+	return true, decodeEmbeddedMessage(r, resourceLogs.decode)
+}
+
+func (l *otlpResourceLogs) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if wireType != wireBytes {
+		return false, nil
+	}
+	switch field {
+	case 1:
+		return true, decodeEmbeddedMessage(r, l.Resource.decode)
+	case 2:
+		scopeLogs := &otlpScopeLogs{}
+		l.ScopeLogs = append(l.ScopeLogs, scopeLogs)
+		return true, decodeEmbeddedMessage(r, scopeLogs.decode)
+	}
+	return false, nil
+}
+
+func (resource *otlpResource) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if field != 1 || wireType != wireBytes {
+		return false, nil
+	}
+	attribute := &otlpKeyValue{}
+	resource.Attributes = append(resource.Attributes, attribute)
+	return true, decodeEmbeddedMessage(r, attribute.decode)
+}
+
+func (l *otlpScopeLogs) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if field != 2 || wireType != wireBytes {
+		return false, nil
+	}
+	record := &otlpLogRecord{}
+	l.LogRecords = append(l.LogRecords, record)
+	return true, decodeEmbeddedMessage(r, record.decode)
+}
+
+func (record *otlpLogRecord) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	var err error
+	switch {
+	case field == 1 && wireType == wireFixed64:
+		var value uint64
+		value, err = r.fixed64()
+		record.TimeUnixNano = otlpUint64(value)
+	case field == 11 && wireType == wireFixed64:
+		var value uint64
+		value, err = r.fixed64()
+		record.ObservedTimeUnixNano = otlpUint64(value)
+	case field == 2 && wireType == wireVarint:
+		var value uint64
+		value, err = r.varint()
+		record.SeverityNumber = int32(value)
+	case field == 3 && wireType == wireBytes:
+		record.SeverityText, err = r.string()
+	case field == 5 && wireType == wireBytes:
+		record.Body = &otlpAnyValue{}
+		err = decodeEmbeddedMessage(r, record.Body.decode)
+	case field == 6 && wireType == wireBytes:
+		attribute := &otlpKeyValue{}
+		record.Attributes = append(record.Attributes, attribute)
+		err = decodeEmbeddedMessage(r, attribute.decode)
+	case field == 9 && wireType == wireBytes:
+		record.TraceID, err = r.bytes()
+	case field == 10 && wireType == wireBytes:
+		record.SpanID, err = r.bytes()
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+func (kv *otlpKeyValue) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if wireType != wireBytes {
+		return false, nil
+	}
+	var err error
+	switch field {
+	case 1:
+		kv.Key, err = r.string()
+	case 2:
+		kv.Value = &otlpAnyValue{}
+		err = decodeEmbeddedMessage(r, kv.Value.decode)
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+func (v *otlpAnyValue) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	var err error
+	switch {
+	case field == 1 && wireType == wireBytes:
+		var value string
+		value, err = r.string()
+		v.StringValue = &value
+	case field == 2 && wireType == wireVarint:
+		var value uint64
+		value, err = r.varint()
+		boolValue := value != 0
+		v.BoolValue = &boolValue
+	case field == 3 && wireType == wireVarint:
+		var value uint64
+		value, err = r.varint()
+		intValue := otlpInt64(value)
+		v.IntValue = &intValue
+	case field == 4 && wireType == wireFixed64:
+		var value float64
+		value, err = r.double()
+		v.DoubleValue = &value
+	case field == 5 && wireType == wireBytes:
+		v.ArrayValue = &otlpArrayValue{}
+		err = decodeEmbeddedMessage(r, v.ArrayValue.decode)
+	case field == 6 && wireType == wireBytes:
+		v.KvlistValue = &otlpKvlistValue{}
+		err = decodeEmbeddedMessage(r, v.KvlistValue.decode)
+	case field == 7 && wireType == wireBytes:
+		var value []byte
+		value, err = r.bytes()
+		v.BytesValue = append([]byte{}, value...)
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+func (a *otlpArrayValue) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if field != 1 || wireType != wireBytes {
+		return false, nil
+	}
+	value := &otlpAnyValue{}
+	a.Values = append(a.Values, value)
+	return true, decodeEmbeddedMessage(r, value.decode)
+}
+
+func (l *otlpKvlistValue) decode(r *wireReader, field uint64, wireType int) (bool, error) {
+	if field != 1 || wireType != wireBytes {
+		return false, nil
+	}
+	keyValue := &otlpKeyValue{}
+	l.Values = append(l.Values, keyValue)
+	return true, decodeEmbeddedMessage(r, keyValue.decode)
+}
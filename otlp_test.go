@@ -0,0 +1,240 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const otlpJSONRequest = `{
+	"resourceLogs": [{
+		"resource": {"attributes": [
+			{"key": "service.name", "value": {"stringValue": "checkout"}},
+			{"key": "host.name", "value": {"stringValue": "web-1"}}
+		]},
+		"scopeLogs": [{"logRecords": [
+			{
+				"timeUnixNano": "1544712660300000000",
+				"severityNumber": 17,
+				"severityText": "ERROR",
+				"body": {"stringValue": "payment failed"},
+				"attributes": [
+					{"key": "host.name", "value": {"stringValue": "web-2"}},
+					{"key": "attempt", "value": {"intValue": "3"}},
+					{"key": "retry", "value": {"boolValue": true}}
+				],
+				"traceId": "5b8efff798038103d269b633813fc60c",
+				"spanId": "eee19b7ec3c1b174"
+			},
+			{
+				"observedTimeUnixNano": 1544712661000000000,
+				"severityText": "warn",
+				"body": {"kvlistValue": {"values": [{"key": "cart", "value": {"arrayValue": {"values": [{"intValue": 1}, {"doubleValue": 2.5}]}}}]}}
+			}
+		]}]
+	}, {
+		"scopeLogs": [{"logRecords": [{"body": {"stringValue": "no resource"}, "spanId": "0000000000000000"}]}]
+	}]
+}`
+
+func protobufField(field, wireType int, value []byte) []byte {
+	key := make([]byte, binary.MaxVarintLen64)
+	return append(key[:binary.PutUvarint(key, uint64(field<<3|wireType))], value...)
+}
+
+func protobufVarint(field int, value uint64) []byte {
+	bytes := make([]byte, binary.MaxVarintLen64)
+	return protobufField(field, wireVarint, bytes[:binary.PutUvarint(bytes, value)])
+}
+
+func protobufFixed64(field int, value uint64) []byte {
+	bytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bytes, value)
+	return protobufField(field, wireFixed64, bytes)
+}
+
+func protobufBytes(field int, parts ...[]byte) []byte {
+	value := bytes.Join(parts, nil)
+	length := make([]byte, binary.MaxVarintLen64)
+	return protobufField(field, wireBytes, append(length[:binary.PutUvarint(length, uint64(len(value)))], value...))
+}
+
+func protobufString(field int, value string) []byte {
+	return protobufBytes(field, []byte(value))
+}
+
+//otlpProtobufRequest has the first record of otlpJSONRequest and an unknown field in every message
+func otlpProtobufRequest() []byte {
+	stringValue := func(value string) []byte { return protobufString(1, value) }
+	keyValue := func(field int, key string, value []byte) []byte {
+		return protobufBytes(field, protobufString(1, key), protobufBytes(2, value))
+	}
+	traceID := []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}
+	spanID := []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74}
+
+	record := bytes.Join([][]byte{
+		protobufFixed64(1, 1544712660300000000),
+		protobufVarint(2, 17),
+		protobufString(3, "ERROR"),
+		protobufBytes(5, stringValue("payment failed")),
+		keyValue(6, "host.name", stringValue("web-2")),
+		keyValue(6, "attempt", protobufVarint(3, 3)),
+		keyValue(6, "retry", protobufVarint(2, 1)),
+		protobufVarint(7, 0),
+		protobufVarint(8, 1),
+		protobufBytes(9, traceID),
+		protobufBytes(10, spanID),
+	}, nil)
+	resource := bytes.Join([][]byte{
+		keyValue(1, "service.name", stringValue("checkout")),
+		keyValue(1, "host.name", stringValue("web-1")),
+		protobufVarint(2, 0),
+	}, nil)
+	scopeLogs := bytes.Join([][]byte{
+		protobufBytes(1, protobufString(1, "scope")),
+		protobufBytes(2, record),
+		protobufString(3, "https://opentelemetry.io/schemas/1.21.0"),
+	}, nil)
+	return protobufBytes(1, protobufBytes(1, resource), protobufBytes(2, scopeLogs))
+}
+
+var otlpErrorMessage = &Message{
+	Text:      "payment failed",
+	Timestamp: 1544712660,
+	Fields: map[string]string{
+		"host.name":       "web-2",
+		"attempt":         "3",
+		"retry":           "true",
+		otlpSeverityField: "ERROR",
+		TraceIDField:      "5b8efff798038103d269b633813fc60c",
+		SpanIDField:       "eee19b7ec3c1b174",
+	},
+}
+
+func TestOTLPLogsRequest(t *testing.T) {
+	Convey("OTLP logs requests", t, func() {
+		received := time.Unix(1000, 0)
+
+		Convey("map the JSON encoding to blocks", func() {
+			request, err := decodeOTLPLogsRequestFor("application/json", []byte(otlpJSONRequest))
+			So(err, ShouldBeNil)
+			blocks := request.blocks(received)
+			So(len(blocks), ShouldEqual, 3)
+
+			So(blocks[0].Service, ShouldEqual, "checkout")
+			So(blocks[0].Level, ShouldEqual, "error")
+			So(blocks[0].Messages, ShouldResemble, []*Message{otlpErrorMessage})
+
+			So(blocks[1].Service, ShouldEqual, "checkout")
+			So(blocks[1].Level, ShouldEqual, "warning")
+			So(blocks[1].Messages, ShouldResemble, []*Message{{
+				Text:      `{"cart":[1,2.5]}`,
+				Timestamp: 1544712661,
+				Fields:    map[string]string{"host.name": "web-1", otlpSeverityField: "warn"},
+			}})
+
+			So(blocks[2].Service, ShouldEqual, defaultOTLPService)
+			So(blocks[2].Level, ShouldEqual, "standard")
+			So(blocks[2].Messages, ShouldResemble, []*Message{{Text: "no resource", Timestamp: 1000}})
+		})
+
+		Convey("keep service names that aren't valid as a field of the default service", func() {
+			request, err := decodeOTLPLogsRequestFor("application/json", []byte(`{"resourceLogs": [{
+				"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "payments/checkout"}}]},
+				"scopeLogs": [{"logRecords": [{"body": {"stringValue": "payment failed"}}]}]
+			}, {
+				"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "checkout"}}]},
+				"scopeLogs": [{"logRecords": [{"body": {"stringValue": "payment accepted"}}]}]
+			}]}`))
+			So(err, ShouldBeNil)
+			blocks := request.blocks(received)
+			So(len(blocks), ShouldEqual, 2)
+			So(blocks[0].Service, ShouldEqual, "checkout")
+			So(blocks[1].Service, ShouldEqual, defaultOTLPService)
+			So(blocks[1].Messages[0].Fields, ShouldResemble, map[string]string{otlpServiceAttribute: "payments/checkout"})
+			for _, block := range blocks {
+				So(block.Valid(), ShouldBeTrue)
+			}
+		})
+
+		Convey("map the protobuf encoding to blocks", func() {
+			request, err := decodeOTLPLogsRequestFor("application/x-protobuf", otlpProtobufRequest())
+			So(err, ShouldBeNil)
+			blocks := request.blocks(received)
+			So(len(blocks), ShouldEqual, 1)
+			So(blocks[0].Service, ShouldEqual, "checkout")
+			So(blocks[0].Level, ShouldEqual, "error")
+			So(blocks[0].StartTime, ShouldEqual, 1544712660)
+			So(blocks[0].Messages, ShouldResemble, []*Message{otlpErrorMessage})
+		})
+
+		Convey("reject truncated protobuf", func() {
+			data := otlpProtobufRequest()
+			_, err := decodeOTLPLogsRequest(data[:len(data)-3])
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("reject protobuf that is nested too deep", func() {
+			request := func(levels int) []byte {
+				value := protobufString(1, "payment failed")
+				for i := 0; i < levels; i++ {
+					value = protobufBytes(5, protobufBytes(1, value))
+				}
+				return protobufBytes(1, protobufBytes(2, protobufBytes(2, protobufBytes(5, value))))
+			}
+			_, err := decodeOTLPLogsRequest(request(20))
+			So(err, ShouldBeNil)
+			_, err = decodeOTLPLogsRequest(request(200))
+			So(err, ShouldEqual, errTooDeep)
+		})
+
+		Convey("map severities to levels", func() {
+			So(otlpLevel(21, ""), ShouldEqual, "error")
+			So(otlpLevel(13, "error"), ShouldEqual, "warning")
+			So(otlpLevel(9, "error"), ShouldEqual, "standard")
+			So(otlpLevel(0, "Fatal"), ShouldEqual, "error")
+			So(otlpLevel(0, "debug"), ShouldEqual, "standard")
+		})
+	})
+}
+
+func TestOTLPLogsEndpoint(t *testing.T) {
+	Convey("OTLP logs endpoint", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+
+		post := func(contentType string, body []byte) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", "/v1/logs", bytes.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			return resp
+		}
+
+		resp := post("application/json", []byte(otlpJSONRequest))
+		So(resp.Code, ShouldEqual, http.StatusOK)
+		So(resp.Body.String(), ShouldEqual, "{}")
+		So(resp.Header().Get("Content-Type"), ShouldEqual, "application/json")
+
+		resp = post("application/x-protobuf", otlpProtobufRequest())
+		So(resp.Code, ShouldEqual, http.StatusOK)
+		So(resp.Body.Len(), ShouldEqual, 0)
+
+		So(post("application/json", []byte(`{"resourceLogs": 1}`)).Code, ShouldEqual, http.StatusBadRequest)
+		So(post("text/plain", []byte("payment failed")).Code, ShouldEqual, http.StatusUnsupportedMediaType)
+
+		req := httptest.NewRequest("GET", "/v1/logs", nil)
+		resp = httptest.NewRecorder()
+		s.ServeHTTP(resp, req)
+		So(resp.Code, ShouldEqual, http.StatusMethodNotAllowed)
+
+		s.Shutdown()
+	})
+	os.RemoveAll(pathPrefix)
+}
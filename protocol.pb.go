@@ -17,6 +17,20 @@ It has these top-level messages:
 	GetServiceResponse
 	GetResponse
 	PostRequest
+	LevelInfo
+	ServiceInfo
+	GetServicesResponse
+	GetLevelsResponse
+	HistogramSeries
+	GetHistogramResponse
+	SearchGroup
+	SearchResponse
+	ServiceUsage
+	GetUsageResponse
+	PushRequest
+	PushAck
+	QueryRequest
+	TailRequest
 */
 package log
 
@@ -24,6 +38,11 @@ import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+)
+
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
 var _ = fmt.Errorf
@@ -36,8 +55,9 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Message struct {
-	Text      string `protobuf:"bytes,1,opt,name=text" json:"text,omitempty"`
-	Timestamp int64  `protobuf:"varint,2,opt,name=timestamp" json:"timestamp,omitempty"`
+	Text      string            `protobuf:"bytes,1,opt,name=text" json:"text,omitempty"`
+	Timestamp int64             `protobuf:"varint,2,opt,name=timestamp" json:"timestamp,omitempty"`
+	Fields    map[string]string `protobuf:"bytes,3,rep,name=fields" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *Message) Reset()                    { *m = Message{} }
@@ -59,6 +79,13 @@ func (m *Message) GetTimestamp() int64 {
 	return 0
 }
 
+func (m *Message) GetFields() map[string]string {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
 type PlainMessage struct {
 	Message *Message `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
 }
@@ -243,6 +270,454 @@ func (m *PostRequest) GetBlocks() []*Block {
 	return nil
 }
 
+type LevelInfo struct {
+	Name           string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	MessageCount   int64  `protobuf:"varint,2,opt,name=message_count,json=messageCount" json:"message_count,omitempty"`
+	FirstTimestamp int64  `protobuf:"varint,3,opt,name=first_timestamp,json=firstTimestamp" json:"first_timestamp,omitempty"`
+	LastTimestamp  int64  `protobuf:"varint,4,opt,name=last_timestamp,json=lastTimestamp" json:"last_timestamp,omitempty"`
+}
+
+func (m *LevelInfo) Reset()                    { *m = LevelInfo{} }
+func (m *LevelInfo) String() string            { return proto.CompactTextString(m) }
+func (*LevelInfo) ProtoMessage()               {}
+func (*LevelInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *LevelInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *LevelInfo) GetMessageCount() int64 {
+	if m != nil {
+		return m.MessageCount
+	}
+	return 0
+}
+
+func (m *LevelInfo) GetFirstTimestamp() int64 {
+	if m != nil {
+		return m.FirstTimestamp
+	}
+	return 0
+}
+
+func (m *LevelInfo) GetLastTimestamp() int64 {
+	if m != nil {
+		return m.LastTimestamp
+	}
+	return 0
+}
+
+type ServiceInfo struct {
+	Name           string       `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Levels         []*LevelInfo `protobuf:"bytes,2,rep,name=levels" json:"levels,omitempty"`
+	MessageCount   int64        `protobuf:"varint,3,opt,name=message_count,json=messageCount" json:"message_count,omitempty"`
+	FirstTimestamp int64        `protobuf:"varint,4,opt,name=first_timestamp,json=firstTimestamp" json:"first_timestamp,omitempty"`
+	LastTimestamp  int64        `protobuf:"varint,5,opt,name=last_timestamp,json=lastTimestamp" json:"last_timestamp,omitempty"`
+}
+
+func (m *ServiceInfo) Reset()                    { *m = ServiceInfo{} }
+func (m *ServiceInfo) String() string            { return proto.CompactTextString(m) }
+func (*ServiceInfo) ProtoMessage()               {}
+func (*ServiceInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *ServiceInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ServiceInfo) GetLevels() []*LevelInfo {
+	if m != nil {
+		return m.Levels
+	}
+	return nil
+}
+
+func (m *ServiceInfo) GetMessageCount() int64 {
+	if m != nil {
+		return m.MessageCount
+	}
+	return 0
+}
+
+func (m *ServiceInfo) GetFirstTimestamp() int64 {
+	if m != nil {
+		return m.FirstTimestamp
+	}
+	return 0
+}
+
+func (m *ServiceInfo) GetLastTimestamp() int64 {
+	if m != nil {
+		return m.LastTimestamp
+	}
+	return 0
+}
+
+type GetServicesResponse struct {
+	Services []*ServiceInfo `protobuf:"bytes,1,rep,name=services" json:"services,omitempty"`
+}
+
+func (m *GetServicesResponse) Reset()                    { *m = GetServicesResponse{} }
+func (m *GetServicesResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetServicesResponse) ProtoMessage()               {}
+func (*GetServicesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *GetServicesResponse) GetServices() []*ServiceInfo {
+	if m != nil {
+		return m.Services
+	}
+	return nil
+}
+
+type GetLevelsResponse struct {
+	Levels []*LevelInfo `protobuf:"bytes,1,rep,name=levels" json:"levels,omitempty"`
+}
+
+func (m *GetLevelsResponse) Reset()                    { *m = GetLevelsResponse{} }
+func (m *GetLevelsResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetLevelsResponse) ProtoMessage()               {}
+func (*GetLevelsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *GetLevelsResponse) GetLevels() []*LevelInfo {
+	if m != nil {
+		return m.Levels
+	}
+	return nil
+}
+
+type HistogramSeries struct {
+	Service string  `protobuf:"bytes,1,opt,name=service" json:"service,omitempty"`
+	Level   string  `protobuf:"bytes,2,opt,name=level" json:"level,omitempty"`
+	Counts  []int64 `protobuf:"varint,3,rep,packed,name=counts" json:"counts,omitempty"`
+}
+
+func (m *HistogramSeries) Reset()                    { *m = HistogramSeries{} }
+func (m *HistogramSeries) String() string            { return proto.CompactTextString(m) }
+func (*HistogramSeries) ProtoMessage()               {}
+func (*HistogramSeries) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *HistogramSeries) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *HistogramSeries) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+func (m *HistogramSeries) GetCounts() []int64 {
+	if m != nil {
+		return m.Counts
+	}
+	return nil
+}
+
+type GetHistogramResponse struct {
+	StartTime     int64              `protobuf:"varint,1,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	BucketSeconds int64              `protobuf:"varint,2,opt,name=bucket_seconds,json=bucketSeconds" json:"bucket_seconds,omitempty"`
+	Series        []*HistogramSeries `protobuf:"bytes,3,rep,name=series" json:"series,omitempty"`
+}
+
+func (m *GetHistogramResponse) Reset()                    { *m = GetHistogramResponse{} }
+func (m *GetHistogramResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetHistogramResponse) ProtoMessage()               {}
+func (*GetHistogramResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *GetHistogramResponse) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *GetHistogramResponse) GetBucketSeconds() int64 {
+	if m != nil {
+		return m.BucketSeconds
+	}
+	return 0
+}
+
+func (m *GetHistogramResponse) GetSeries() []*HistogramSeries {
+	if m != nil {
+		return m.Series
+	}
+	return nil
+}
+
+type SearchGroup struct {
+	Messages []*CompleteMessage `protobuf:"bytes,1,rep,name=messages" json:"messages,omitempty"`
+	Matches  []int32            `protobuf:"varint,2,rep,packed,name=matches" json:"matches,omitempty"`
+}
+
+func (m *SearchGroup) Reset()                    { *m = SearchGroup{} }
+func (m *SearchGroup) String() string            { return proto.CompactTextString(m) }
+func (*SearchGroup) ProtoMessage()               {}
+func (*SearchGroup) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *SearchGroup) GetMessages() []*CompleteMessage {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
+func (m *SearchGroup) GetMatches() []int32 {
+	if m != nil {
+		return m.Matches
+	}
+	return nil
+}
+
+type SearchResponse struct {
+	Groups []*SearchGroup `protobuf:"bytes,1,rep,name=groups" json:"groups,omitempty"`
+}
+
+func (m *SearchResponse) Reset()                    { *m = SearchResponse{} }
+func (m *SearchResponse) String() string            { return proto.CompactTextString(m) }
+func (*SearchResponse) ProtoMessage()               {}
+func (*SearchResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *SearchResponse) GetGroups() []*SearchGroup {
+	if m != nil {
+		return m.Groups
+	}
+	return nil
+}
+
+type ServiceUsage struct {
+	Service                string `protobuf:"bytes,1,opt,name=service" json:"service,omitempty"`
+	DayStart               int64  `protobuf:"varint,2,opt,name=day_start,json=dayStart" json:"day_start,omitempty"`
+	MessageCount           int64  `protobuf:"varint,3,opt,name=message_count,json=messageCount" json:"message_count,omitempty"`
+	ByteCount              int64  `protobuf:"varint,4,opt,name=byte_count,json=byteCount" json:"byte_count,omitempty"`
+	RejectedRequests       int64  `protobuf:"varint,5,opt,name=rejected_requests,json=rejectedRequests" json:"rejected_requests,omitempty"`
+	MessagesPerSecondLimit int64  `protobuf:"varint,6,opt,name=messages_per_second_limit,json=messagesPerSecondLimit" json:"messages_per_second_limit,omitempty"`
+	BytesPerSecondLimit    int64  `protobuf:"varint,7,opt,name=bytes_per_second_limit,json=bytesPerSecondLimit" json:"bytes_per_second_limit,omitempty"`
+	DailyBytesLimit        int64  `protobuf:"varint,8,opt,name=daily_bytes_limit,json=dailyBytesLimit" json:"daily_bytes_limit,omitempty"`
+}
+
+func (m *ServiceUsage) Reset()                    { *m = ServiceUsage{} }
+func (m *ServiceUsage) String() string            { return proto.CompactTextString(m) }
+func (*ServiceUsage) ProtoMessage()               {}
+func (*ServiceUsage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *ServiceUsage) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *ServiceUsage) GetDayStart() int64 {
+	if m != nil {
+		return m.DayStart
+	}
+	return 0
+}
+
+func (m *ServiceUsage) GetMessageCount() int64 {
+	if m != nil {
+		return m.MessageCount
+	}
+	return 0
+}
+
+func (m *ServiceUsage) GetByteCount() int64 {
+	if m != nil {
+		return m.ByteCount
+	}
+	return 0
+}
+
+func (m *ServiceUsage) GetRejectedRequests() int64 {
+	if m != nil {
+		return m.RejectedRequests
+	}
+	return 0
+}
+
+func (m *ServiceUsage) GetMessagesPerSecondLimit() int64 {
+	if m != nil {
+		return m.MessagesPerSecondLimit
+	}
+	return 0
+}
+
+func (m *ServiceUsage) GetBytesPerSecondLimit() int64 {
+	if m != nil {
+		return m.BytesPerSecondLimit
+	}
+	return 0
+}
+
+func (m *ServiceUsage) GetDailyBytesLimit() int64 {
+	if m != nil {
+		return m.DailyBytesLimit
+	}
+	return 0
+}
+
+type GetUsageResponse struct {
+	Services []*ServiceUsage `protobuf:"bytes,1,rep,name=services" json:"services,omitempty"`
+}
+
+func (m *GetUsageResponse) Reset()                    { *m = GetUsageResponse{} }
+func (m *GetUsageResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetUsageResponse) ProtoMessage()               {}
+func (*GetUsageResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *GetUsageResponse) GetServices() []*ServiceUsage {
+	if m != nil {
+		return m.Services
+	}
+	return nil
+}
+
+type PushRequest struct {
+	Sequence uint64   `protobuf:"varint,1,opt,name=sequence" json:"sequence,omitempty"`
+	Blocks   []*Block `protobuf:"bytes,2,rep,name=blocks" json:"blocks,omitempty"`
+}
+
+func (m *PushRequest) Reset()                    { *m = PushRequest{} }
+func (m *PushRequest) String() string            { return proto.CompactTextString(m) }
+func (*PushRequest) ProtoMessage()               {}
+func (*PushRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *PushRequest) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *PushRequest) GetBlocks() []*Block {
+	if m != nil {
+		return m.Blocks
+	}
+	return nil
+}
+
+type PushAck struct {
+	Sequence          uint64 `protobuf:"varint,1,opt,name=sequence" json:"sequence,omitempty"`
+	Code              int32  `protobuf:"varint,2,opt,name=code" json:"code,omitempty"`
+	Error             string `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	RetryAfterSeconds int64  `protobuf:"varint,4,opt,name=retry_after_seconds,json=retryAfterSeconds" json:"retry_after_seconds,omitempty"`
+}
+
+func (m *PushAck) Reset()                    { *m = PushAck{} }
+func (m *PushAck) String() string            { return proto.CompactTextString(m) }
+func (*PushAck) ProtoMessage()               {}
+func (*PushAck) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *PushAck) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *PushAck) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *PushAck) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *PushAck) GetRetryAfterSeconds() int64 {
+	if m != nil {
+		return m.RetryAfterSeconds
+	}
+	return 0
+}
+
+type QueryRequest struct {
+	StartTime int64  `protobuf:"varint,1,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	EndTime   int64  `protobuf:"varint,2,opt,name=end_time,json=endTime" json:"end_time,omitempty"`
+	Service   string `protobuf:"bytes,3,opt,name=service" json:"service,omitempty"`
+	Level     string `protobuf:"bytes,4,opt,name=level" json:"level,omitempty"`
+	TraceId   string `protobuf:"bytes,5,opt,name=trace_id,json=traceId" json:"trace_id,omitempty"`
+}
+
+func (m *QueryRequest) Reset()                    { *m = QueryRequest{} }
+func (m *QueryRequest) String() string            { return proto.CompactTextString(m) }
+func (*QueryRequest) ProtoMessage()               {}
+func (*QueryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *QueryRequest) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *QueryRequest) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+func (m *QueryRequest) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *QueryRequest) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+func (m *QueryRequest) GetTraceId() string {
+	if m != nil {
+		return m.TraceId
+	}
+	return ""
+}
+
+type TailRequest struct {
+	Service string `protobuf:"bytes,1,opt,name=service" json:"service,omitempty"`
+	Level   string `protobuf:"bytes,2,opt,name=level" json:"level,omitempty"`
+}
+
+func (m *TailRequest) Reset()                    { *m = TailRequest{} }
+func (m *TailRequest) String() string            { return proto.CompactTextString(m) }
+func (*TailRequest) ProtoMessage()               {}
+func (*TailRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *TailRequest) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *TailRequest) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Message)(nil), "log.Message")
 	proto.RegisterType((*PlainMessage)(nil), "log.PlainMessage")
@@ -253,32 +728,311 @@ func init() {
 	proto.RegisterType((*GetServiceResponse)(nil), "log.GetServiceResponse")
 	proto.RegisterType((*GetResponse)(nil), "log.GetResponse")
 	proto.RegisterType((*PostRequest)(nil), "log.PostRequest")
+	proto.RegisterType((*LevelInfo)(nil), "log.LevelInfo")
+	proto.RegisterType((*ServiceInfo)(nil), "log.ServiceInfo")
+	proto.RegisterType((*GetServicesResponse)(nil), "log.GetServicesResponse")
+	proto.RegisterType((*GetLevelsResponse)(nil), "log.GetLevelsResponse")
+	proto.RegisterType((*HistogramSeries)(nil), "log.HistogramSeries")
+	proto.RegisterType((*GetHistogramResponse)(nil), "log.GetHistogramResponse")
+	proto.RegisterType((*SearchGroup)(nil), "log.SearchGroup")
+	proto.RegisterType((*SearchResponse)(nil), "log.SearchResponse")
+	proto.RegisterType((*ServiceUsage)(nil), "log.ServiceUsage")
+	proto.RegisterType((*GetUsageResponse)(nil), "log.GetUsageResponse")
+	proto.RegisterType((*PushRequest)(nil), "log.PushRequest")
+	proto.RegisterType((*PushAck)(nil), "log.PushAck")
+	proto.RegisterType((*QueryRequest)(nil), "log.QueryRequest")
+	proto.RegisterType((*TailRequest)(nil), "log.TailRequest")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Log service
+
+type LogClient interface {
+	Push(ctx context.Context, opts ...grpc.CallOption) (Log_PushClient, error)
+	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Log_QueryClient, error)
+	Tail(ctx context.Context, in *TailRequest, opts ...grpc.CallOption) (Log_TailClient, error)
+}
+
+type logClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewLogClient(cc *grpc.ClientConn) LogClient {
+	return &logClient{cc}
+}
+
+func (c *logClient) Push(ctx context.Context, opts ...grpc.CallOption) (Log_PushClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Log_serviceDesc.Streams[0], c.cc, "/log.Log/Push", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &logPushClient{stream}
+	return x, nil
+}
+
+type Log_PushClient interface {
+	Send(*PushRequest) error
+	Recv() (*PushAck, error)
+	grpc.ClientStream
+}
+
+type logPushClient struct {
+	grpc.ClientStream
+}
+
+func (x *logPushClient) Send(m *PushRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *logPushClient) Recv() (*PushAck, error) {
+	m := new(PushAck)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *logClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (Log_QueryClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Log_serviceDesc.Streams[1], c.cc, "/log.Log/Query", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &logQueryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Log_QueryClient interface {
+	Recv() (*CompleteMessage, error)
+	grpc.ClientStream
+}
+
+type logQueryClient struct {
+	grpc.ClientStream
+}
+
+func (x *logQueryClient) Recv() (*CompleteMessage, error) {
+	m := new(CompleteMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *logClient) Tail(ctx context.Context, in *TailRequest, opts ...grpc.CallOption) (Log_TailClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Log_serviceDesc.Streams[2], c.cc, "/log.Log/Tail", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &logTailClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Log_TailClient interface {
+	Recv() (*CompleteMessage, error)
+	grpc.ClientStream
+}
+
+type logTailClient struct {
+	grpc.ClientStream
+}
+
+func (x *logTailClient) Recv() (*CompleteMessage, error) {
+	m := new(CompleteMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Log service
+
+type LogServer interface {
+	Push(Log_PushServer) error
+	Query(*QueryRequest, Log_QueryServer) error
+	Tail(*TailRequest, Log_TailServer) error
+}
+
+func RegisterLogServer(s *grpc.Server, srv LogServer) {
+	s.RegisterService(&_Log_serviceDesc, srv)
+}
+
+func _Log_Push_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(LogServer).Push(&logPushServer{stream})
+}
+
+type Log_PushServer interface {
+	Send(*PushAck) error
+	Recv() (*PushRequest, error)
+	grpc.ServerStream
+}
+
+type logPushServer struct {
+	grpc.ServerStream
+}
+
+func (x *logPushServer) Send(m *PushAck) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *logPushServer) Recv() (*PushRequest, error) {
+	m := new(PushRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Log_Query_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogServer).Query(m, &logQueryServer{stream})
+}
+
+type Log_QueryServer interface {
+	Send(*CompleteMessage) error
+	grpc.ServerStream
+}
+
+type logQueryServer struct {
+	grpc.ServerStream
+}
+
+func (x *logQueryServer) Send(m *CompleteMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Log_Tail_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogServer).Tail(m, &logTailServer{stream})
+}
+
+type Log_TailServer interface {
+	Send(*CompleteMessage) error
+	grpc.ServerStream
+}
+
+type logTailServer struct {
+	grpc.ServerStream
+}
+
+func (x *logTailServer) Send(m *CompleteMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Log_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "log.Log",
+	HandlerType: (*LogServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Push",
+			Handler:       _Log_Push_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Query",
+			Handler:       _Log_Query_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Tail",
+			Handler:       _Log_Tail_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "protocol.proto",
 }
 
 func init() { proto.RegisterFile("protocol.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 342 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x92, 0x4f, 0x4b, 0xc3, 0x40,
-	0x10, 0xc5, 0x89, 0x69, 0x9b, 0x76, 0x5a, 0x2a, 0xae, 0x05, 0x23, 0x28, 0x94, 0x3d, 0x48, 0x2f,
-	0xd6, 0x7f, 0xe0, 0xc5, 0x83, 0xa0, 0x48, 0x3d, 0xa8, 0x94, 0xd5, 0xbb, 0xa4, 0xed, 0x50, 0x82,
-	0x9b, 0x6c, 0xec, 0x8e, 0xc5, 0xef, 0xe2, 0x97, 0x95, 0x4c, 0x92, 0x36, 0x35, 0x78, 0x10, 0xbc,
-	0x4d, 0xe6, 0xed, 0xfb, 0xbd, 0x61, 0x26, 0xd0, 0x4d, 0x16, 0x86, 0xcc, 0xd4, 0xe8, 0x21, 0x17,
-	0xc2, 0xd5, 0x66, 0x2e, 0xaf, 0xc0, 0x7b, 0x44, 0x6b, 0x83, 0x39, 0x0a, 0x01, 0x35, 0xc2, 0x4f,
-	0xf2, 0x9d, 0xbe, 0x33, 0x68, 0x29, 0xae, 0xc5, 0x01, 0xb4, 0x28, 0x8c, 0xd0, 0x52, 0x10, 0x25,
-	0xfe, 0x56, 0xdf, 0x19, 0xb8, 0x6a, 0xdd, 0x90, 0x97, 0xd0, 0x19, 0xeb, 0x20, 0x8c, 0x0b, 0xc2,
-	0x11, 0x78, 0x51, 0x56, 0x32, 0xa4, 0x7d, 0xde, 0x19, 0x6a, 0x33, 0x1f, 0xe6, 0xb2, 0x2a, 0x44,
-	0xf9, 0x04, 0xdd, 0x67, 0x5c, 0x2c, 0xc3, 0x29, 0xfe, 0xd1, 0x29, 0x7a, 0x50, 0xd7, 0xb8, 0x44,
-	0xcd, 0xb3, 0xb4, 0x54, 0xf6, 0x21, 0x43, 0xd8, 0xbe, 0x35, 0x51, 0xa2, 0x91, 0xfe, 0x07, 0x28,
-	0x7c, 0xf0, 0x6c, 0x36, 0xa0, 0xef, 0x72, 0xbf, 0xf8, 0x94, 0x5f, 0x0e, 0xd4, 0x6f, 0xb4, 0x99,
-	0xbe, 0x95, 0xdf, 0x38, 0x1b, 0x6f, 0x7e, 0x61, 0x0e, 0xa0, 0x99, 0x87, 0x5a, 0xdf, 0xed, 0xbb,
-	0x95, 0x91, 0x56, 0xaa, 0x38, 0x04, 0xb0, 0x14, 0x2c, 0xe8, 0x35, 0xdd, 0xb4, 0x5f, 0xcb, 0xb6,
-	0xce, 0x9d, 0x97, 0x30, 0x42, 0xb1, 0x0f, 0x4d, 0x8c, 0x67, 0x99, 0x58, 0x67, 0xd1, 0xc3, 0x78,
-	0x96, 0x4a, 0xf2, 0x1e, 0xf6, 0x46, 0x48, 0xf9, 0x6e, 0x1f, 0xd2, 0x58, 0x85, 0x36, 0x31, 0xb1,
-	0x45, 0x71, 0x5c, 0x8a, 0x77, 0x38, 0x7e, 0x87, 0xe3, 0xcb, 0x07, 0x5c, 0xcf, 0x20, 0xef, 0x40,
-	0xac, 0x49, 0x2b, 0xc8, 0x49, 0x05, 0xb2, 0xcb, 0x90, 0xcd, 0x6b, 0x96, 0x30, 0xd7, 0xd0, 0x1e,
-	0x21, 0xad, 0xfc, 0xa7, 0x15, 0x7f, 0x8f, 0xfd, 0x3f, 0xae, 0x57, 0x02, 0x9c, 0x41, 0x7b, 0x6c,
-	0x2c, 0x29, 0x7c, 0xff, 0x40, 0x4b, 0x42, 0x42, 0x63, 0x92, 0x6e, 0xbf, 0xb0, 0x03, 0xdb, 0xf9,
-	0x20, 0x2a, 0x57, 0x26, 0x0d, 0xfe, 0xbd, 0x2f, 0xbe, 0x03, 0x00, 0x00, 0xff, 0xff, 0x07, 0x7c,
-	0x7b, 0x5a, 0xf0, 0x02, 0x00, 0x00,
+	// 1006 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x5f, 0x6f, 0xdb, 0x54,
+	0x14, 0x97, 0xe3, 0xfc, 0x3d, 0x49, 0xd3, 0xe6, 0xb6, 0x2a, 0x6e, 0x61, 0x52, 0x65, 0xb4, 0x11,
+	0x8d, 0x51, 0x4a, 0x27, 0x21, 0x36, 0x84, 0x50, 0x57, 0x8d, 0x6c, 0x52, 0x87, 0x8a, 0x5b, 0x1e,
+	0xf6, 0x64, 0xb9, 0xf6, 0x69, 0x6a, 0xe2, 0xd8, 0xd9, 0xbd, 0x37, 0x15, 0x79, 0xe2, 0x9d, 0x57,
+	0x90, 0x78, 0xe0, 0x0b, 0xf0, 0x29, 0xf8, 0x10, 0x7c, 0x22, 0x74, 0xcf, 0xbd, 0x76, 0x9c, 0xb4,
+	0x19, 0xad, 0xc4, 0xdb, 0x3d, 0xff, 0xff, 0xfc, 0xce, 0x39, 0x36, 0x74, 0x27, 0x3c, 0x93, 0x59,
+	0x98, 0x25, 0xfb, 0xf4, 0x60, 0x76, 0x92, 0x0d, 0xdd, 0xbf, 0x2c, 0x68, 0xbc, 0x41, 0x21, 0x82,
+	0x21, 0x32, 0x06, 0x55, 0x89, 0x3f, 0x4b, 0xc7, 0xda, 0xb3, 0xfa, 0x2d, 0x8f, 0xde, 0xec, 0x23,
+	0x68, 0xc9, 0x78, 0x8c, 0x42, 0x06, 0xe3, 0x89, 0x53, 0xd9, 0xb3, 0xfa, 0xb6, 0x37, 0x67, 0xb0,
+	0x03, 0xa8, 0x5f, 0xc6, 0x98, 0x44, 0xc2, 0xb1, 0xf7, 0xec, 0x7e, 0xfb, 0xd0, 0xd9, 0x4f, 0xb2,
+	0xe1, 0xbe, 0xf1, 0xb7, 0xff, 0x1d, 0x89, 0x5e, 0xa6, 0x92, 0xcf, 0x3c, 0xa3, 0xb7, 0xfb, 0x0c,
+	0xda, 0x25, 0x36, 0xdb, 0x00, 0x7b, 0x84, 0x33, 0x13, 0x51, 0x3d, 0xd9, 0x16, 0xd4, 0xae, 0x83,
+	0x64, 0x8a, 0x14, 0xac, 0xe5, 0x69, 0xe2, 0x79, 0xe5, 0x2b, 0xcb, 0xfd, 0x12, 0x3a, 0xa7, 0x49,
+	0x10, 0xa7, 0x79, 0xba, 0x8f, 0xa0, 0x31, 0xd6, 0x4f, 0xb2, 0x6f, 0x1f, 0x76, 0xca, 0xd1, 0xbd,
+	0x5c, 0xe8, 0x7e, 0x0f, 0xdd, 0x33, 0xe4, 0xd7, 0x71, 0x88, 0xf7, 0xb4, 0x54, 0xb9, 0x24, 0x78,
+	0x8d, 0x49, 0x9e, 0x0b, 0x11, 0x6e, 0x0c, 0xeb, 0xc7, 0xd9, 0x78, 0x92, 0xa0, 0xfc, 0x7f, 0x1c,
+	0x32, 0x07, 0x1a, 0x42, 0x27, 0xe8, 0xd8, 0xc4, 0xcf, 0x49, 0xf7, 0x4f, 0x0b, 0x6a, 0x2f, 0x92,
+	0x2c, 0x1c, 0x95, 0x75, 0xac, 0x05, 0x9d, 0x15, 0x3e, 0xfb, 0xd0, 0x34, 0x41, 0x73, 0x6c, 0x16,
+	0x53, 0x2a, 0xa4, 0xec, 0x01, 0x80, 0x90, 0x01, 0x97, 0xbe, 0x82, 0xd5, 0xa9, 0x6a, 0x88, 0x89,
+	0x73, 0x1e, 0x8f, 0x91, 0xed, 0x40, 0x13, 0xd3, 0x48, 0x0b, 0x6b, 0x24, 0x6c, 0x60, 0x1a, 0x29,
+	0x91, 0xfb, 0x0a, 0x3e, 0x18, 0xa0, 0x34, 0xbd, 0x3d, 0x51, 0x61, 0x3d, 0x14, 0x93, 0x2c, 0x15,
+	0xc8, 0x3e, 0x2b, 0x85, 0xb7, 0x28, 0x7c, 0x8f, 0xc2, 0x97, 0x01, 0x9c, 0xe7, 0xe0, 0xbe, 0x04,
+	0x36, 0xf7, 0x54, 0x38, 0xf9, 0xfc, 0x86, 0x93, 0x4d, 0x72, 0xb2, 0x88, 0x66, 0xc9, 0xcd, 0xb7,
+	0xd0, 0x1e, 0xa0, 0x2c, 0xec, 0x0f, 0x6e, 0xd8, 0x6f, 0x91, 0xfd, 0x12, 0x7a, 0x25, 0x07, 0x5f,
+	0x40, 0xfb, 0x34, 0x13, 0xd2, 0xc3, 0x77, 0x53, 0x14, 0x92, 0xb9, 0x50, 0xbf, 0x50, 0xdd, 0xcf,
+	0xcd, 0x81, 0xcc, 0x09, 0x10, 0xcf, 0x48, 0xdc, 0xdf, 0x2d, 0x68, 0x51, 0xed, 0xaf, 0xd3, 0xcb,
+	0x4c, 0xad, 0x50, 0x1a, 0x8c, 0x73, 0x8c, 0xe8, 0xcd, 0x3e, 0x86, 0x35, 0x13, 0xc0, 0x0f, 0xb3,
+	0x69, 0x2a, 0xcd, 0x1a, 0x75, 0x0c, 0xf3, 0x58, 0xf1, 0xd8, 0x27, 0xb0, 0x7e, 0x19, 0x73, 0x21,
+	0xfd, 0x62, 0xb9, 0x68, 0x16, 0x6c, 0xaf, 0x4b, 0xec, 0xf3, 0x9c, 0xcb, 0x1e, 0x42, 0x37, 0x09,
+	0x16, 0xf4, 0x34, 0x64, 0x6b, 0x49, 0x50, 0x52, 0x73, 0xff, 0xb6, 0xa0, 0x6d, 0xfa, 0xb4, 0x32,
+	0xb1, 0x47, 0x50, 0xa7, 0x61, 0x11, 0x4e, 0x85, 0xca, 0xeb, 0x52, 0x79, 0x45, 0x31, 0x9e, 0x91,
+	0xde, 0x2c, 0xc0, 0xbe, 0x5b, 0x01, 0xd5, 0x3b, 0x16, 0x50, 0xbb, 0xad, 0x80, 0x63, 0xd8, 0x9c,
+	0x8f, 0x84, 0x28, 0x30, 0x7d, 0x02, 0x4d, 0x33, 0xf8, 0x39, 0x28, 0x1b, 0xe5, 0x99, 0xa0, 0xbc,
+	0x0b, 0x0d, 0xf7, 0x6b, 0xe8, 0x0d, 0x50, 0x52, 0x45, 0x73, 0x17, 0xf3, 0xb2, 0xad, 0xf7, 0x95,
+	0xed, 0xbe, 0x85, 0xf5, 0x57, 0xb1, 0x90, 0xd9, 0x90, 0x07, 0xe3, 0x33, 0xe4, 0x31, 0x8a, 0x7b,
+	0x6f, 0xe1, 0x36, 0xd4, 0xa9, 0x63, 0x7a, 0x07, 0x6d, 0xcf, 0x50, 0xee, 0xaf, 0x16, 0x6c, 0x0d,
+	0x50, 0x16, 0xee, 0x8b, 0xdc, 0x16, 0x97, 0xd1, 0x5a, 0x5e, 0xc6, 0x87, 0xd0, 0xbd, 0x98, 0x86,
+	0x23, 0x94, 0xbe, 0xc0, 0x30, 0x4b, 0x23, 0x61, 0x66, 0x69, 0x4d, 0x73, 0xcf, 0x34, 0x93, 0x3d,
+	0x81, 0xba, 0xa0, 0x84, 0x1d, 0xbb, 0x34, 0xf6, 0x4b, 0xc5, 0x78, 0x46, 0xc7, 0x7d, 0xab, 0x26,
+	0x25, 0xe0, 0xe1, 0xd5, 0x80, 0x67, 0xd3, 0xc9, 0xfd, 0xb7, 0x46, 0x75, 0x65, 0x1c, 0xc8, 0xf0,
+	0x0a, 0xf5, 0x20, 0xd5, 0xbc, 0x9c, 0x74, 0x9f, 0x43, 0x57, 0xbb, 0x2e, 0x0a, 0xec, 0x43, 0x7d,
+	0xa8, 0xc2, 0x2c, 0xa3, 0x57, 0xc4, 0xf7, 0x8c, 0xdc, 0xfd, 0xa7, 0x02, 0x1d, 0x83, 0xea, 0x8f,
+	0x74, 0x3c, 0x57, 0x37, 0xff, 0x43, 0x68, 0x45, 0xc1, 0xcc, 0xa7, 0x3e, 0x99, 0x8e, 0x34, 0xa3,
+	0x60, 0x76, 0xa6, 0xe8, 0xbb, 0x4d, 0xef, 0x03, 0x80, 0x8b, 0x99, 0xcc, 0x35, 0xcc, 0x11, 0x54,
+	0x1c, 0x2d, 0xfe, 0x14, 0x7a, 0x1c, 0x7f, 0xc2, 0x50, 0x62, 0xe4, 0x73, 0x7d, 0x1c, 0x84, 0x19,
+	0xdb, 0x8d, 0x5c, 0x60, 0x8e, 0x86, 0x60, 0xcf, 0x60, 0x27, 0x6f, 0x8d, 0x3f, 0x41, 0x6e, 0xa0,
+	0xf2, 0x93, 0x78, 0x1c, 0x4b, 0xa7, 0x4e, 0x46, 0xdb, 0xb9, 0xc2, 0x29, 0x72, 0x0d, 0xda, 0x89,
+	0x92, 0xb2, 0xa7, 0xb0, 0xad, 0x82, 0xde, 0x62, 0xd7, 0x20, 0xbb, 0x4d, 0x92, 0x2e, 0x19, 0x3d,
+	0x86, 0x5e, 0x14, 0xc4, 0xc9, 0xcc, 0xd7, 0xa6, 0x5a, 0xbf, 0x49, 0xfa, 0xeb, 0x24, 0x78, 0xa1,
+	0xf8, 0xa4, 0xeb, 0x1e, 0xc1, 0xc6, 0x00, 0x25, 0xf5, 0xb3, 0x7c, 0xab, 0x97, 0x56, 0xaa, 0x57,
+	0x5e, 0x29, 0xad, 0x3c, 0xdf, 0xa9, 0x37, 0xd0, 0x3e, 0x9d, 0x8a, 0xab, 0xfc, 0x46, 0xee, 0x2a,
+	0xeb, 0x77, 0x53, 0x4c, 0x0d, 0x2c, 0x55, 0xaf, 0xa0, 0x4b, 0xf7, 0xb3, 0xb2, 0xf2, 0x7e, 0xfe,
+	0x02, 0x0d, 0xe5, 0xee, 0x28, 0x1c, 0xbd, 0xd7, 0x15, 0x83, 0x6a, 0x98, 0x45, 0xfa, 0xaf, 0xa0,
+	0xe6, 0xd1, 0x5b, 0xed, 0x1c, 0x72, 0x9e, 0x71, 0xf3, 0xd5, 0xd4, 0x04, 0xdb, 0x87, 0x4d, 0x8e,
+	0x92, 0xcf, 0xfc, 0xe0, 0x52, 0x16, 0x5d, 0x14, 0x06, 0xd3, 0x1e, 0x89, 0x8e, 0x2e, 0x65, 0xde,
+	0x42, 0xe1, 0xfe, 0x66, 0x41, 0xe7, 0x87, 0x29, 0xf2, 0x59, 0x5e, 0xd1, 0x7f, 0xec, 0x60, 0xf9,
+	0x83, 0x58, 0x59, 0xf8, 0x20, 0xae, 0xfe, 0x90, 0xcf, 0xcf, 0x43, 0xb5, 0x7c, 0x1e, 0x76, 0xa0,
+	0x29, 0x79, 0x10, 0xa2, 0x1f, 0x47, 0x34, 0x4d, 0x2d, 0xaf, 0x41, 0xf4, 0xeb, 0xc8, 0xfd, 0x06,
+	0xda, 0xe7, 0x41, 0x9c, 0xe4, 0x39, 0xdd, 0xf3, 0xf0, 0x1c, 0xfe, 0x61, 0x81, 0x7d, 0x92, 0x0d,
+	0xd9, 0x63, 0xa8, 0xaa, 0xee, 0x32, 0xbd, 0x66, 0x25, 0xdc, 0x76, 0x3b, 0x05, 0xe7, 0x28, 0x1c,
+	0xf5, 0xad, 0x03, 0x8b, 0x1d, 0x42, 0x8d, 0xfa, 0xc0, 0x34, 0xfc, 0xe5, 0x9e, 0xec, 0xde, 0x7a,
+	0x02, 0x0e, 0x2c, 0x76, 0x00, 0x55, 0x95, 0xa6, 0xf1, 0x5f, 0xca, 0x78, 0x95, 0xc5, 0x45, 0x9d,
+	0x7e, 0x3e, 0x9f, 0xfe, 0x3b, 0x00, 0x19, 0x18, 0x83, 0x64, 0x8e, 0x0a, 0x00, 0x00,
 }
@@ -0,0 +1,302 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+//RateLimit for the posts of a service, zero values don't limit anything
+type RateLimit struct {
+	MessagesPerSecond int64 `json:"messages_per_second"`
+	BytesPerSecond    int64 `json:"bytes_per_second"`
+	//DailyBytes is reset at midnight UTC
+	DailyBytes int64 `json:"daily_bytes"`
+}
+
+type rateLimitsFile struct {
+	Default  RateLimit            `json:"default"`
+	Services map[string]RateLimit `json:"services"`
+}
+
+//RateLimits track how much every service posts and reject the posts of services that are over their limits
+type RateLimits struct {
+	path          string
+	mutex         sync.Mutex
+	defaultLimit  RateLimit
+	serviceLimits map[string]RateLimit
+	usages        map[string]*serviceUsage
+	//day that the usages were last swept for services of earlier days
+	day time.Time
+	now func() time.Time
+}
+
+//serviceUsage holds a token bucket per rate, the buckets hold a second of their rate
+//and may go into debt, so a request that is bigger than a second of the rate is accepted once the bucket is full
+type serviceUsage struct {
+	messageTokens, byteTokens float64
+	updated                   time.Time
+	day                       time.Time
+	messages, bytes, rejected int64
+}
+
+//requestSize is what a request posts for a service
+type requestSize struct {
+	messages, bytes int64
+}
+
+//NewRateLimits without any limits, they only track the usage of the services
+func NewRateLimits() *RateLimits {
+	return &RateLimits{
+		serviceLimits: map[string]RateLimit{},
+		usages:        map[string]*serviceUsage{},
+		now:           time.Now,
+	}
+}
+
+//LoadRateLimits reads the limits from the JSON file at path
+func LoadRateLimits(path string) (*RateLimits, error) {
+	limits := NewRateLimits()
+	limits.path = path
+	return limits, limits.Reload()
+}
+
+//Reload reads the file again, the previous limits stay in use if it is invalid
+func (l *RateLimits) Reload() error {
+	bytes, err := ioutil.ReadFile(l.path)
+	if err != nil {
+		return err
+	}
+	file := &rateLimitsFile{}
+	if err := json.Unmarshal(bytes, file); err != nil {
+		return fmt.Errorf("%v: %v", l.path, err)
+	}
+	if err := file.Default.validate(); err != nil {
+		return fmt.Errorf("%v: default: %v", l.path, err)
+	}
+	for service, limit := range file.Services {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("%v: service %v: %v", l.path, service, err)
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.defaultLimit = file.Default
+	l.serviceLimits = file.Services
+	if l.serviceLimits == nil {
+		l.serviceLimits = map[string]RateLimit{}
+	}
+	return nil
+}
+
+func (r RateLimit) validate() error {
+	if r.MessagesPerSecond < 0 || r.BytesPerSecond < 0 || r.DailyBytes < 0 {
+		return fmt.Errorf("limits can't be negative")
+	}
+	return nil
+}
+
+//limit of the service, services without their own limit have the default one
+func (l *RateLimits) limit(service string) RateLimit {
+	if limit, ok := l.serviceLimits[service]; ok {
+		return limit
+	}
+	return l.defaultLimit
+}
+
+//usage of the service with its buckets refilled and its counters reset on a new day
+func (l *RateLimits) usage(service string, now time.Time) *serviceUsage {
+	limit := l.limit(service)
+	u, ok := l.usages[service]
+	if !ok {
+		u = &serviceUsage{
+			messageTokens: float64(limit.MessagesPerSecond),
+			byteTokens:    float64(limit.BytesPerSecond),
+			updated:       now,
+		}
+		l.usages[service] = u
+	}
+	if day := startOfDay(now); !u.day.Equal(day) {
+		u.day = day
+		u.messages, u.bytes, u.rejected = 0, 0, 0
+	}
+	elapsed := now.Sub(u.updated).Seconds()
+	u.updated = now
+	u.messageTokens = refill(u.messageTokens, elapsed, limit.MessagesPerSecond)
+	u.byteTokens = refill(u.byteTokens, elapsed, limit.BytesPerSecond)
+	return u
+}
+
+//startDay drops the usages of services that didn't post since the previous day started,
+//so services that post only once don't pile up
+func (l *RateLimits) startDay(now time.Time) {
+	day := startOfDay(now)
+	if l.day.Equal(day) {
+		return
+	}
+	l.day = day
+	for service, u := range l.usages {
+		if !u.day.Equal(day) {
+			delete(l.usages, service)
+		}
+	}
+}
+
+func refill(tokens, elapsed float64, rate int64) float64 {
+	if rate <= 0 {
+		return 0
+	}
+	return math.Min(tokens+elapsed*float64(rate), float64(rate))
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+//admit counts the request if all of its services are within their limits,
+//otherwise nothing is counted and it returns how long to wait before retrying.
+//Requests that exceed a daily limit on their own are never admitted and return no wait.
+func (l *RateLimits) admit(sizes map[string]requestSize) (time.Duration, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	l.startDay(now)
+
+	if service, exceeds := l.oversizedService(sizes); exceeds {
+		l.usage(service, now).rejected++
+		metrics.rateLimitedRequests.inc(service)
+		return 0, false
+	}
+
+	retryAfter := time.Duration(0)
+	for _, service := range sortedServices(sizes) {
+		u := l.usage(service, now)
+		wait := u.wait(sizes[service], l.limit(service), now)
+		if wait > 0 {
+			u.rejected++
+			metrics.rateLimitedRequests.inc(service)
+		}
+		if wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	if retryAfter > 0 {
+		return retryAfter, false
+	}
+
+	for service, size := range sizes {
+		u := l.usages[service]
+		u.messageTokens -= float64(size.messages)
+		u.byteTokens -= float64(size.bytes)
+		u.messages += size.messages
+		u.bytes += size.bytes
+	}
+	return 0, true
+}
+
+//oversizedService returns a service that the request posts more bytes for than its daily limit allows,
+//such a request can't be admitted on any day
+func (l *RateLimits) oversizedService(sizes map[string]requestSize) (string, bool) {
+	for _, service := range sortedServices(sizes) {
+		limit := l.limit(service)
+		if limit.DailyBytes > 0 && sizes[service].bytes > limit.DailyBytes {
+			return service, true
+		}
+	}
+	return "", false
+}
+
+//wait returns how long it takes until the request fits into the limit, 0 if it does already
+func (u *serviceUsage) wait(size requestSize, limit RateLimit, now time.Time) time.Duration {
+	wait := time.Duration(0)
+	// requests that are larger than the daily limit never fit, admit rejects them before
+	if limit.DailyBytes > 0 && size.bytes <= limit.DailyBytes && u.bytes+size.bytes > limit.DailyBytes {
+		wait = u.day.Add(24 * time.Hour).Sub(now)
+	}
+	if w := bucketWait(u.messageTokens, size.messages, limit.MessagesPerSecond); w > wait {
+		wait = w
+	}
+	if w := bucketWait(u.byteTokens, size.bytes, limit.BytesPerSecond); w > wait {
+		wait = w
+	}
+	return wait
+}
+
+//bucketWait is the time until the bucket holds the cost, or is full for costs bigger than the bucket
+func bucketWait(tokens float64, cost, rate int64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	needed := math.Min(float64(cost), float64(rate))
+	if tokens >= needed {
+		return 0
+	}
+	return time.Duration((needed - tokens) / float64(rate) * float64(time.Second))
+}
+
+//Usage returns what every service posted today together with its limits, sorted by service
+func (l *RateLimits) Usage() []*ServiceUsage {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	l.startDay(now)
+
+	services := map[string]bool{}
+	for service := range l.usages {
+		services[service] = true
+	}
+	for service := range l.serviceLimits {
+		services[service] = true
+	}
+
+	usages := []*ServiceUsage{}
+	for service := range services {
+		u := l.usage(service, now)
+		limit := l.limit(service)
+		usages = append(usages, &ServiceUsage{
+			Service:                service,
+			DayStart:               u.day.Unix(),
+			MessageCount:           u.messages,
+			ByteCount:              u.bytes,
+			RejectedRequests:       u.rejected,
+			MessagesPerSecondLimit: limit.MessagesPerSecond,
+			BytesPerSecondLimit:    limit.BytesPerSecond,
+			DailyBytesLimit:        limit.DailyBytes,
+		})
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Service < usages[j].Service })
+	return usages
+}
+
+//requestSizes adds up the messages and encoded bytes of the blocks per service
+func requestSizes(blocks []*Block) map[string]requestSize {
+	sizes := map[string]requestSize{}
+	for _, block := range blocks {
+		size := sizes[block.Service]
+		size.messages += int64(len(block.Messages))
+		size.bytes += int64(proto.Size(block))
+		sizes[block.Service] = size
+	}
+	return sizes
+}
+
+func sortedServices(sizes map[string]requestSize) []string {
+	services := []string{}
+	for service := range sizes {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+//retryAfterSeconds rounds the wait up to whole seconds for the Retry-After header
+func retryAfterSeconds(wait time.Duration) int64 {
+	return int64((wait + time.Second - 1) / time.Second)
+}
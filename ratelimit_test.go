@@ -0,0 +1,117 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimits(t *testing.T) {
+	Convey("RateLimits", t, func() {
+		now := time.Date(2018, 5, 17, 23, 59, 0, 0, time.UTC)
+		limits := NewRateLimits()
+		limits.now = func() time.Time { return now }
+		limits.defaultLimit = RateLimit{MessagesPerSecond: 10}
+		limits.serviceLimits = map[string]RateLimit{"big": RateLimit{BytesPerSecond: 100, DailyBytes: 250}}
+		admit := func(service string, messages, bytes int64) (time.Duration, bool) {
+			return limits.admit(map[string]requestSize{service: requestSize{messages: messages, bytes: bytes}})
+		}
+
+		Convey("accepts messages up to the rate", func() {
+			_, ok := admit("api", 10, 0)
+			So(ok, ShouldBeTrue)
+			wait, ok := admit("api", 1, 0)
+			So(ok, ShouldBeFalse)
+			So(wait, ShouldEqual, 100*time.Millisecond)
+
+			now = now.Add(500 * time.Millisecond)
+			_, ok = admit("api", 5, 0)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("accepts requests bigger than a second of the rate once the bucket is full", func() {
+			_, ok := admit("api", 30, 0)
+			So(ok, ShouldBeTrue)
+			wait, ok := admit("api", 1, 0)
+			So(ok, ShouldBeFalse)
+			So(wait, ShouldEqual, 2100*time.Millisecond)
+		})
+
+		Convey("rejects bytes over the daily limit until the next day", func() {
+			_, ok := admit("big", 1, 100)
+			So(ok, ShouldBeTrue)
+			now = now.Add(10 * time.Second)
+			_, ok = admit("big", 1, 100)
+			So(ok, ShouldBeTrue)
+			now = now.Add(10 * time.Second)
+			wait, ok := admit("big", 1, 100)
+			So(ok, ShouldBeFalse)
+			So(wait, ShouldEqual, 40*time.Second)
+
+			now = now.Add(wait)
+			_, ok = admit("big", 1, 100)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("never admits requests that are larger than the daily limit", func() {
+			wait, ok := admit("big", 1, 251)
+			So(ok, ShouldBeFalse)
+			So(wait, ShouldEqual, 0)
+			_, ok = limits.admit(map[string]requestSize{"big": requestSize{bytes: 250}, "api": requestSize{bytes: 1000}})
+			So(ok, ShouldBeTrue)
+			So(limits.Usage()[1].RejectedRequests, ShouldEqual, 1)
+		})
+
+		Convey("drops the usage of services that didn't post since the previous day", func() {
+			admit("once", 1, 0)
+			now = now.Add(time.Hour)
+			admit("api", 1, 0)
+			usages := limits.Usage()
+			So(len(usages), ShouldEqual, 2)
+			So(usages[0].Service, ShouldEqual, "api")
+			So(usages[1].Service, ShouldEqual, "big")
+		})
+
+		Convey("counts nothing if one of the services is over its limit", func() {
+			admit("api", 10, 0)
+			_, ok := limits.admit(map[string]requestSize{"api": requestSize{messages: 1}, "other": requestSize{messages: 1}})
+			So(ok, ShouldBeFalse)
+
+			usages := limits.Usage()
+			So(len(usages), ShouldEqual, 3)
+			So(usages[0].Service, ShouldEqual, "api")
+			So(usages[0].MessageCount, ShouldEqual, 10)
+			So(usages[0].RejectedRequests, ShouldEqual, 1)
+			So(usages[0].MessagesPerSecondLimit, ShouldEqual, 10)
+			So(usages[1].Service, ShouldEqual, "big")
+			So(usages[1].DailyBytesLimit, ShouldEqual, 250)
+			So(usages[2].Service, ShouldEqual, "other")
+			So(usages[2].MessageCount, ShouldEqual, 0)
+		})
+
+		Convey("resets the usage on a new day", func() {
+			admit("api", 10, 0)
+			now = now.Add(time.Hour)
+			So(limits.Usage()[0].MessageCount, ShouldEqual, 0)
+			So(limits.Usage()[0].DayStart, ShouldEqual, time.Date(2018, 5, 18, 0, 0, 0, 0, time.UTC).Unix())
+		})
+	})
+
+	Convey("LoadRateLimits", t, func() {
+		file, _ := ioutil.TempFile("", "rate-limits")
+		defer os.Remove(file.Name())
+		ioutil.WriteFile(file.Name(), []byte(`{"default": {"messages_per_second": 100}, "services": {"api": {"daily_bytes": 1000}}}`), 0600)
+
+		limits, err := LoadRateLimits(file.Name())
+		So(err, ShouldBeNil)
+		So(limits.limit("other"), ShouldResemble, RateLimit{MessagesPerSecond: 100})
+		So(limits.limit("api"), ShouldResemble, RateLimit{DailyBytes: 1000})
+
+		ioutil.WriteFile(file.Name(), []byte(`{"default": {"messages_per_second": -1}}`), 0600)
+		So(limits.Reload(), ShouldNotBeNil)
+		So(limits.limit("other"), ShouldResemble, RateLimit{MessagesPerSecond: 100})
+	})
+}
@@ -20,6 +20,11 @@ type Store interface {
 	Shutdown()
 }
 
+//levelInfoStore is a Store that knows the statistics of a level without reading all of its messages
+type levelInfoStore interface {
+	levelInfo(service, level string) *LevelInfo
+}
+
 //Reader reads blocks from the given Store levels
 type Reader struct {
 	Stores []Store
@@ -35,18 +40,13 @@ func NewReader(stores ...Store) *Reader {
 //GetServiceLevelMessagesInTimeRange returns the blocks in the timerange
 //if no blocks are found in the first Store level, the next ones are tried in order
 func (r *Reader) GetServiceLevelMessagesInTimeRange(startTime, endTime int64, service, level string) (messages []*PlainMessage) {
-	var block *Block
-	for _, store := range r.Stores {
-		block = store.GetBlock(startTime, endTime, service, level)
-		if block != nil {
-			break
+	for _, block := range r.blocksInTimeRange(startTime, endTime, service, level) {
+		plainMessageStack := block.toPlainMessageStack()
+		plainMessageStack.Flip()
+		for !plainMessageStack.Empty() {
+			messages = append(messages, plainMessageStack.PopMessageContainer().(*PlainMessage))
 		}
 	}
-	plainMessageStack := block.toPlainMessageStack()
-	plainMessageStack.Flip()
-	for !plainMessageStack.Empty() {
-		messages = append(messages, plainMessageStack.PopMessageContainer().(*PlainMessage))
-	}
 	return
 }
 
@@ -54,17 +54,8 @@ func (r *Reader) GetServiceLevelMessagesInTimeRange(startTime, endTime int64, se
 //if no blocks are found in the first Store level, the next ones are tried in order
 func (r *Reader) GetServiceMessagesInTimeRange(startTime, endTime int64, service string) (messages []*ServiceMessage) {
 	stackPerLevel := []*MessageContainerStack{}
-	for _, store := range r.Stores {
-		levels := store.GetLevels(service)
-		for _, level := range levels {
-			block := store.GetBlock(startTime, endTime, service, level)
-			if block != nil {
-				stackPerLevel = append(stackPerLevel, block.toServiceMessageStack())
-			}
-		}
-		if len(stackPerLevel) > 0 {
-			break
-		}
+	for _, block := range r.blocksInTimeRange(startTime, endTime, service, "") {
+		stackPerLevel = append(stackPerLevel, block.toServiceMessageStack())
 	}
 
 	mergedStack := mergeOrderedMessageStacks(stackPerLevel)
@@ -78,30 +69,148 @@ func (r *Reader) GetServiceMessagesInTimeRange(startTime, endTime int64, service
 //if no blocks are found in the first Store level, the next ones are tried in order
 func (r *Reader) GetCompleteMessagesInTimeRange(startTime, endTime int64) (messages []*CompleteMessage) {
 	stackPerServiceAndLevel := []*MessageContainerStack{}
+	for _, block := range r.blocksInTimeRange(startTime, endTime, "", "") {
+		stackPerServiceAndLevel = append(stackPerServiceAndLevel, block.toCompleteMessageStack())
+	}
+
+	mergedStack := mergeOrderedMessageStacks(stackPerServiceAndLevel)
+	for !mergedStack.Empty() {
+		messages = append(messages, mergedStack.PopMessageContainer().(*CompleteMessage))
+	}
+	return
+}
+
+//eachCompleteMessageInTimeRange calls send with the messages of the service and level in the order of their timestamps,
+//without collecting them first. Empty service and level select all of them, it stops at the first error of send.
+func (r *Reader) eachCompleteMessageInTimeRange(startTime, endTime int64, service, level string, send func(message *CompleteMessage) error) error {
+	stackPerServiceAndLevel := []*MessageContainerStack{}
+	for _, block := range r.blocksInTimeRange(startTime, endTime, service, level) {
+		stackPerServiceAndLevel = append(stackPerServiceAndLevel, block.toCompleteMessageStack())
+	}
+
+	mergedStack := mergeOrderedMessageStacks(stackPerServiceAndLevel)
+	for !mergedStack.Empty() {
+		message := mergedStack.PopMessageContainer().(*CompleteMessage)
+		err := send(message)
+		pools.CompleteMessages.Put(message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//blocksInTimeRange returns the blocks of the first Store level that has any in the timerange,
+//an empty service selects all services and an empty level all levels of the selected services
+func (r *Reader) blocksInTimeRange(startTime, endTime int64, service, level string) (blocks []*Block) {
 	for _, store := range r.Stores {
-		services := store.GetServices()
-		for _, service := range services {
-			levels := store.GetLevels(service)
-			for _, level := range levels {
-				block := store.GetBlock(startTime, endTime, service, level)
+		services := []string{service}
+		if service == "" {
+			services = store.GetServices()
+		}
+		for _, s := range services {
+			levels := []string{level}
+			if level == "" {
+				levels = store.GetLevels(s)
+			}
+			for _, l := range levels {
+				block := store.GetBlock(startTime, endTime, s, l)
 				if block != nil {
-					stackPerServiceAndLevel = append(stackPerServiceAndLevel, block.toCompleteMessageStack())
+					blocks = append(blocks, block)
 				}
 			}
 		}
 
-		if len(stackPerServiceAndLevel) > 0 {
-			break
+		if len(blocks) > 0 {
+			return
 		}
 	}
+	return
+}
 
-	mergedStack := mergeOrderedMessageStacks(stackPerServiceAndLevel)
-	for !mergedStack.Empty() {
-		messages = append(messages, mergedStack.PopMessageContainer().(*CompleteMessage))
+//GetTraceMessagesInTimeRange returns the messages of all services and levels that belong to the given trace
+func (r *Reader) GetTraceMessagesInTimeRange(startTime, endTime int64, traceID string) (messages []*CompleteMessage) {
+	for _, message := range r.GetCompleteMessagesInTimeRange(startTime, endTime) {
+		if message.Message.HasField(TraceIDField, traceID) {
+			messages = append(messages, message)
+		} else {
+			pools.CompleteMessages.Put(message)
+		}
+	}
+	return
+}
+
+//GetServiceInfos returns the services of all Store levels together with their levels and message statistics, sorted by name
+func (r *Reader) GetServiceInfos() (services []*ServiceInfo) {
+	knownServices := map[string]bool{}
+	for _, store := range r.Stores {
+		for _, service := range store.GetServices() {
+			knownServices[service] = true
+		}
+	}
+
+	for service := range knownServices {
+		info := &ServiceInfo{Name: service, Levels: r.GetLevelInfos(service)}
+		for _, level := range info.Levels {
+			addLevelStatistics(info, level)
+		}
+		services = append(services, info)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return
+}
+
+//GetLevelInfos returns the levels of the service in all Store levels with their message statistics, sorted by name
+//the statistics of a level are taken from the last Store level that knows it, because the later levels hold the longer history
+func (r *Reader) GetLevelInfos(service string) (levels []*LevelInfo) {
+	storesPerLevel := map[string]Store{}
+	for _, store := range r.Stores {
+		for _, level := range store.GetLevels(service) {
+			storesPerLevel[level] = store
+		}
+	}
+
+	for level, store := range storesPerLevel {
+		if infoStore, ok := store.(levelInfoStore); ok {
+			levels = append(levels, infoStore.levelInfo(service, level))
+			continue
+		}
+		info := &LevelInfo{Name: level}
+		block := store.GetBlock(minInt, maxInt, service, level)
+		if block != nil {
+			for _, message := range block.Messages {
+				addMessageStatistics(info, message.Timestamp)
+			}
+		}
+		levels = append(levels, info)
 	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Name < levels[j].Name })
 	return
 }
 
+func addMessageStatistics(info *LevelInfo, timestamp int64) {
+	if info.MessageCount == 0 || timestamp < info.FirstTimestamp {
+		info.FirstTimestamp = timestamp
+	}
+	if info.MessageCount == 0 || timestamp > info.LastTimestamp {
+		info.LastTimestamp = timestamp
+	}
+	info.MessageCount++
+}
+
+func addLevelStatistics(service *ServiceInfo, level *LevelInfo) {
+	if level.MessageCount == 0 {
+		return
+	}
+	if service.MessageCount == 0 || level.FirstTimestamp < service.FirstTimestamp {
+		service.FirstTimestamp = level.FirstTimestamp
+	}
+	if service.MessageCount == 0 || level.LastTimestamp > service.LastTimestamp {
+		service.LastTimestamp = level.LastTimestamp
+	}
+	service.MessageCount += level.MessageCount
+}
+
 //Shutdown the stores
 func (r *Reader) Shutdown() {
 	for _, store := range r.Stores {
@@ -0,0 +1,91 @@
+package log
+
+import (
+	"sort"
+	"strings"
+)
+
+//maxSearchContext limits the messages before and after a match, so a search can't return whole timelines by accident
+const maxSearchContext = 1000
+
+//SearchMessagesInTimeRange finds the messages that contain search (ignoring case) and groups each match with up to
+//before and after messages of the same service, taken from the merged timeline of all levels of the service.
+//An empty service searches all services, a level restricts the matches but not their context.
+//Matches whose context overlaps share a group, the groups are ordered by their first message.
+func (r *Reader) SearchMessagesInTimeRange(startTime, endTime int64, service, level, search string, before, after int) (groups []*SearchGroup) {
+	stacksPerService := map[string][]*MessageContainerStack{}
+	for _, block := range r.blocksInTimeRange(startTime, endTime, service, "") {
+		stacksPerService[block.Service] = append(stacksPerService[block.Service], block.toCompleteMessageStack())
+	}
+
+	lowerSearch := strings.ToLower(search)
+	for _, stacks := range stacksPerService {
+		timeline := []*CompleteMessage{}
+		mergedStack := mergeOrderedMessageStacks(stacks)
+		for !mergedStack.Empty() {
+			timeline = append(timeline, mergedStack.PopMessageContainer().(*CompleteMessage))
+		}
+		groups = append(groups, searchTimeline(timeline, level, lowerSearch, before, after)...)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i].Messages[0], groups[j].Messages[0]
+		if a.Message.Timestamp != b.Message.Timestamp {
+			return a.Message.Timestamp < b.Message.Timestamp
+		}
+		return a.Service < b.Service
+	})
+	return
+}
+
+//searchTimeline groups the matches in the timeline of one service with their context,
+//messages that are in no group are put back into their pool
+func searchTimeline(timeline []*CompleteMessage, level, lowerSearch string, before, after int) (groups []*SearchGroup) {
+	var group *SearchGroup
+	// index of the last message in the current group
+	groupEnd := -1
+	for i, message := range timeline {
+		if !matchesSearch(message, level, lowerSearch) {
+			continue
+		}
+		start := i - before
+		if start < 0 {
+			start = 0
+		}
+		if group == nil || start > groupEnd+1 {
+			group = &SearchGroup{}
+			groups = append(groups, group)
+		} else {
+			start = groupEnd + 1
+		}
+		end := i + after
+		if end >= len(timeline) {
+			end = len(timeline) - 1
+		}
+		if end > groupEnd {
+			group.Messages = append(group.Messages, timeline[start:end+1]...)
+			groupEnd = end
+		}
+		group.Matches = append(group.Matches, int32(len(group.Messages)-1-(groupEnd-i)))
+	}
+
+	kept := map[*CompleteMessage]bool{}
+	for _, g := range groups {
+		for _, message := range g.Messages {
+			kept[message] = true
+		}
+	}
+	for _, message := range timeline {
+		if !kept[message] {
+			pools.CompleteMessages.Put(message)
+		}
+	}
+	return
+}
+
+func matchesSearch(message *CompleteMessage, level, lowerSearch string) bool {
+	if level != "" && message.Level != level {
+		return false
+	}
+	return strings.Contains(strings.ToLower(message.Message.Text), lowerSearch)
+}
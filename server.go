@@ -1,21 +1,54 @@
 package log
 
 import (
+	"crypto/tls"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
 )
 
+//APIPrefix is the path that the data routes are served under, admin routes like /healthz are served at the root
+const APIPrefix = "/api/v1"
+
 // Server handles incoming blocks
 type Server struct {
 	WriterCollection *WriterCollection
 	Reader           *Reader
+	//MaxDecompressedBodySize limits the size of gzip encoded post bodies after decompression
+	MaxDecompressedBodySize int64
+	//MaxQueuedBlocks is the number of blocks waiting for the writers above which /readyz reports the server as not ready,
+	// 0 disables the check
+	MaxQueuedBlocks int
+	//APIKeys authenticate the requests to the data routes, without keys every request is allowed
+	APIKeys *APIKeys
+	//RequireClientCertificates rejects requests to the data routes without a verified client certificate.
+	// Requests with one may only access the service named by its common name.
+	RequireClientCertificates bool
+	//RateLimits reject the posts of services that are over their limits and track the usage of every service
+	RateLimits *RateLimits
+	//IngestWindow is how long the listeners collect messages into a block per service and level before it is written,
+	// 0 uses a second
+	IngestWindow time.Duration
+	//LokiServiceLabels and LokiLevelLabels are the stream labels that name the service and the level of Loki pushes,
+	// the first one that a stream has is used. Empty slices use the defaults.
+	LokiServiceLabels, LokiLevelLabels []string
+
+	routesOnce  sync.Once
+	routes      *http.ServeMux
+	batcherOnce sync.Once
+	batcher     *batcher
+	listeners   listenerSet
+	tails       tailHub
 }
 
 //NewDefaultServer creates a new Server and initializes its members
@@ -24,25 +57,164 @@ func NewDefaultServer() *Server {
 	fileReader := &FileReader{}
 	reader := NewReader(cache, fileReader)
 	return &Server{
-		Reader:           reader,
-		WriterCollection: NewWriterCollection(cache),
+		Reader:                  reader,
+		WriterCollection:        NewWriterCollection(cache),
+		MaxDecompressedBodySize: defaultMaxDecompressedBodySize,
+		MaxQueuedBlocks:         defaultMaxQueuedBlocks,
+		RateLimits:              NewRateLimits(),
 	}
 }
 
+//ServerConfig holds the settings of StartServerWithConfig
+type ServerConfig struct {
+	//Addr that the server listens on
+	Addr string
+	//APIKeysPath is the JSON file with the api keys, without it requests aren't authenticated.
+	// The file is read again when the process receives SIGHUP.
+	APIKeysPath string
+	//CertFile and KeyFile enable HTTPS, they are read again once they change
+	CertFile, KeyFile string
+	//ClientCAFile requires client certificates signed by one of its CAs on the data routes,
+	// the common name of a certificate is the only service that its requests may access
+	ClientCAFile string
+	//RateLimitsPath is the JSON file with the limits of the services, without it only their usage is tracked.
+	// The file is read again when the process receives SIGHUP.
+	RateLimitsPath string
+	//SyslogUDPAddr and SyslogTCPAddr start syslog listeners on the addresses, the listeners don't authenticate their senders
+	SyslogUDPAddr, SyslogTCPAddr string
+	//GRPCAddr starts the gRPC service on the address, with the same certificates and authentication as the http server
+	GRPCAddr string
+	//LineTCPAddr starts a listener for newline-delimited "service level text" or logfmt lines on the address,
+	// it doesn't authenticate its senders either
+	LineTCPAddr string
+	//LokiServiceLabels and LokiLevelLabels override the stream labels that name the service and the level of Loki pushes
+	LokiServiceLabels, LokiLevelLabels []string
+}
+
 // StartServer starts a new Server
 func StartServer() {
+	StartServerWithConfig(ServerConfig{Addr: ":7654"})
+}
+
+//StartServerWithConfig starts a new Server with the settings of config
+func StartServerWithConfig(config ServerConfig) {
 	os.MkdirAll(pathPrefix, os.ModePerm)
 	s := NewDefaultServer()
+	if config.APIKeysPath != "" {
+		keys, err := LoadAPIKeys(config.APIKeysPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		s.APIKeys = keys
+		go reloadOnHangup("api keys", keys.Reload)
+	}
+	if config.RateLimitsPath != "" {
+		limits, err := LoadRateLimits(config.RateLimitsPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		s.RateLimits = limits
+		go reloadOnHangup("rate limits", limits.Reload)
+	}
+	s.RequireClientCertificates = config.ClientCAFile != ""
+	s.LokiServiceLabels = config.LokiServiceLabels
+	s.LokiLevelLabels = config.LokiLevelLabels
+	if err := s.startListeners(config); err != nil {
+		fmt.Println(err)
+		return
+	}
 	http.Handle("/", s)
-	err := http.ListenAndServe(":7654", nil)
+	var err error
+	if config.CertFile != "" || config.KeyFile != "" {
+		err = s.listenAndServeTLS(config)
+	} else if config.ClientCAFile != "" {
+		err = fmt.Errorf("client certificates require a server certificate")
+	} else {
+		err = http.ListenAndServe(config.Addr, nil)
+	}
 	if err != nil {
 		fmt.Println(err)
 	}
 	s.Shutdown()
 }
 
+//reloadOnHangup calls reload whenever the process receives SIGHUP, if it fails the previous settings stay in use
+func reloadOnHangup(settings string, reload func() error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		if err := reload(); err != nil {
+			fmt.Println("keeping the previous", settings+":", err)
+		}
+	}
+}
+
+//startListeners starts the configured listeners besides the http server
+func (s *Server) startListeners(config ServerConfig) error {
+	if config.SyslogUDPAddr != "" {
+		conn, err := net.ListenPacket("udp", config.SyslogUDPAddr)
+		if err != nil {
+			return err
+		}
+		go logServeError("syslog udp listener", func() error { return s.ServeSyslogUDP(conn) })
+	}
+	if config.SyslogTCPAddr != "" {
+		listener, err := net.Listen("tcp", config.SyslogTCPAddr)
+		if err != nil {
+			return err
+		}
+		go logServeError("syslog tcp listener", func() error { return s.ServeSyslogTCP(listener) })
+	}
+	if config.LineTCPAddr != "" {
+		listener, err := net.Listen("tcp", config.LineTCPAddr)
+		if err != nil {
+			return err
+		}
+		go logServeError("line listener", func() error { return s.ServeLineTCP(listener) })
+	}
+	if config.GRPCAddr != "" {
+		var tlsConfig *tls.Config
+		if config.CertFile != "" || config.KeyFile != "" {
+			var err error
+			if tlsConfig, err = config.serverTLSConfig(); err != nil {
+				return err
+			}
+		}
+		listener, err := net.Listen("tcp", config.GRPCAddr)
+		if err != nil {
+			return err
+		}
+		go logServeError("grpc server", func() error { return s.NewGRPCServer(tlsConfig).Serve(listener) })
+	}
+	return nil
+}
+
+func logServeError(listener string, serve func() error) {
+	if err := serve(); err != nil {
+		fmt.Println(listener+":", err)
+	}
+}
+
+func (s *Server) listenAndServeTLS(config ServerConfig) error {
+	tlsConfig, err := config.serverTLSConfig()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Addr: config.Addr, TLSConfig: tlsConfig}
+	return server.ListenAndServeTLS("", "")
+}
+
 //Shutdown the server and all its components
 func (s *Server) Shutdown() {
+	// the listeners hand their last messages to the batcher before it stops
+	s.listeners.close()
+	// no listener can start the batcher after this, so it is safe to read
+	s.batcherOnce.Do(func() {})
+	if s.batcher != nil {
+		s.batcher.shutdown()
+	}
 	s.WriterCollection.Shutdown()
 	s.Reader.Shutdown()
 }
@@ -54,19 +226,71 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 	}()
+	s.routesOnce.Do(s.registerRoutes)
+	s.routes.ServeHTTP(w, r)
+}
+
+func (s *Server) registerRoutes() {
+	routes := http.NewServeMux()
+	routes.HandleFunc("/healthz", onlyGet(s.handleHealthGet))
+	routes.HandleFunc("/readyz", onlyGet(s.handleReadyGet))
+	routes.HandleFunc("/metrics", onlyGet(s.authorize(ReadScope, s.handleMetricsGet)))
+	routes.HandleFunc(APIPrefix+"/messages", s.handleMessages)
+	routes.HandleFunc(APIPrefix+"/services", onlyGet(timed("services", s.authorize(ReadScope, s.handleServicesGet))))
+	routes.HandleFunc(APIPrefix+"/levels", onlyGet(timed("levels", s.authorize(ReadScope, s.handleLevelsGet))))
+	routes.HandleFunc(APIPrefix+"/histogram", onlyGet(timed("histogram", s.authorize(ReadScope, s.handleHistogramGet))))
+	routes.HandleFunc(APIPrefix+"/search", onlyGet(timed("search", s.authorize(ReadScope, s.handleSearchGet))))
+	routes.HandleFunc(APIPrefix+"/usage", onlyGet(s.authorize(ReadScope, s.handleUsageGet)))
+	routes.HandleFunc("/v1/logs", s.authorize(WriteScope, s.handleOTLPLogsPost))
+	routes.HandleFunc("/loki/api/v1/push", s.authorize(WriteScope, s.handleLokiPush))
+	routes.HandleFunc("/", s.handleLegacyPost)
+	s.routes = routes
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		s.handlePost(w, r)
+		s.authorize(WriteScope, s.handlePost)(w, r)
 	case http.MethodGet:
-		s.handleGet(w, r)
+		defer metrics.queryDuration.observeSince(time.Now(), "messages")
+		s.authorize(ReadScope, s.handleGet)(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+//handleLegacyPost accepts blocks from clients that still post to the root instead of the api prefix
+func (s *Server) handleLegacyPost(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	s.authorize(WriteScope, s.handlePost)(w, r)
+}
+
+func onlyGet(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+//timed observes the duration of the handler as a query to endpoint
+func timed(endpoint string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer metrics.queryDuration.observeSince(time.Now(), endpoint)
+		handler(w, r)
 	}
 }
 
 func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
 	postRequest := &PostRequest{}
-	bytes, err := ioutil.ReadAll(r.Body)
+	bytes, err := readRequestBody(r, s.MaxDecompressedBodySize)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(bodyErrorStatus(err))
 		return
 	}
 
@@ -75,18 +299,58 @@ func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	for _, block := range postRequest.Blocks {
+	status, retryAfter := s.admitBlocks(requestKey(r), postRequest.Blocks)
+	if status != http.StatusOK {
+		rejectBlocks(w, status, retryAfter)
+		return
+	}
+	s.ingest(postRequest.Blocks)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+//rejectBlocks writes the status that admitBlocks returned, with a Retry-After header for 429
+func rejectBlocks(w http.ResponseWriter, status int, retryAfter time.Duration) {
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", strconv.FormatInt(retryAfterSeconds(retryAfter), 10))
+	}
+	w.WriteHeader(status)
+}
+
+//admitBlocks checks the blocks of a post against the key and the rate limits,
+//it returns the http status to reject them with (and how long to wait for 429) or 200.
+//Posts that are larger than the daily limit of a service get 413, retrying them doesn't help.
+func (s *Server) admitBlocks(key *APIKey, blocks []*Block) (int, time.Duration) {
+	for _, block := range blocks {
 		if !block.Valid() {
-			w.WriteHeader(http.StatusBadRequest)
-			return
+			return http.StatusBadRequest, 0
+		}
+		if !key.allows(block.Service, block.Level) {
+			return http.StatusForbidden, 0
+		}
+	}
+	if s.RateLimits != nil {
+		retryAfter, ok := s.RateLimits.admit(requestSizes(blocks))
+		// admit counts every rejection, only requests that are larger than a daily limit come without a wait
+		if !ok && retryAfter == 0 {
+			return http.StatusRequestEntityTooLarge, 0
+		}
+		if !ok {
+			return http.StatusTooManyRequests, retryAfter
 		}
 	}
-	for _, block := range postRequest.Blocks {
+	return http.StatusOK, 0
+}
+
+//ingest hands the accepted blocks to the writers of their service and level
+func (s *Server) ingest(blocks []*Block) {
+	for _, block := range blocks {
+		metrics.blocksAccepted.inc(block.Service, block.Level)
+		metrics.messagesAccepted.add(float64(len(block.Messages)), block.Service, block.Level)
 		storageWriter := s.WriterCollection.GetWriter(block.Service, block.Level)
 		storageWriter.InChannel <- block
 	}
-
-	w.WriteHeader(http.StatusOK)
+	s.tails.publish(blocks)
 }
 
 type getParams struct {
@@ -94,6 +358,9 @@ type getParams struct {
 	endTime   int64
 	service   string
 	level     string
+	traceID   string
+	//key that the request was authorized with, the messages it may not see are left out of the response
+	key *APIKey
 }
 
 func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
@@ -103,8 +370,21 @@ func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	parsedParams.key = requestKey(r)
+	if !parsedParams.key.allowsQuery(parsedParams.service, parsedParams.level) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if acceptsGzip(r) {
+		gzipWriter := newGzipResponseWriter(w)
+		defer gzipWriter.Close()
+		w = gzipWriter
+	}
 
-	if parsedParams.service != "" && parsedParams.level != "" {
+	if parsedParams.traceID != "" {
+		s.handleTraceGet(w, parsedParams)
+	} else if parsedParams.service != "" && parsedParams.level != "" {
 		s.handleServiceLevelGet(w, parsedParams)
 	} else if parsedParams.service != "" && parsedParams.level == "" {
 		s.handleServiceGet(w, parsedParams)
@@ -146,6 +426,7 @@ func (s *Server) handleServiceGet(w http.ResponseWriter, p *getParams) {
 		p.endTime,
 		p.service,
 	)
+	messages = p.key.filterServiceMessages(p.service, messages)
 
 	response := pools.GetServiceResponses.Get().(*GetServiceResponse)
 	response.Reset()
@@ -171,6 +452,19 @@ func (s *Server) handlePlainGet(w http.ResponseWriter, p *getParams) {
 		p.startTime,
 		p.endTime,
 	)
+	s.writeCompleteMessages(w, p.key.filterCompleteMessages(messages))
+}
+
+func (s *Server) handleTraceGet(w http.ResponseWriter, p *getParams) {
+	messages := s.Reader.GetTraceMessagesInTimeRange(
+		p.startTime,
+		p.endTime,
+		p.traceID,
+	)
+	s.writeCompleteMessages(w, p.key.filterCompleteMessages(messages))
+}
+
+func (s *Server) writeCompleteMessages(w http.ResponseWriter, messages []*CompleteMessage) {
 	response := pools.GetResponses.Get().(*GetResponse)
 	response.Reset()
 	response.Messages = messages
@@ -189,6 +483,211 @@ func (s *Server) handlePlainGet(w http.ResponseWriter, p *getParams) {
 	pools.GetResponses.Put(response)
 }
 
+func (s *Server) handleMetricsGet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := s.writeMetrics(w, requestKey(r)); err != nil {
+		fmt.Println(err)
+	}
+}
+
+//writeMetrics writes the metrics of the services and levels that the key allows
+func (s *Server) writeMetrics(w io.Writer, key *APIKey) error {
+	for _, counter := range []*counterVec{metrics.messagesAccepted, metrics.blocksAccepted, metrics.writeErrors, metrics.cacheEvictedMessages, metrics.filesScanned, metrics.rateLimitedRequests,
+		metrics.listenerMessages, metrics.listenerInvalidMessages, metrics.droppedMessages} {
+		if err := counter.writeFor(w, key); err != nil {
+			return err
+		}
+	}
+	for _, histogram := range []*histogramVec{metrics.blockWriteDuration, metrics.queryDuration} {
+		if err := histogram.write(w); err != nil {
+			return err
+		}
+	}
+	if s.WriterCollection == nil {
+		return nil
+	}
+
+	queueLabels := []string{"service", "level"}
+	queueDepths := []gaugeSample{}
+	for _, sample := range s.WriterCollection.queueDepths() {
+		if key.allowsSample(queueLabels, sample.labels) {
+			queueDepths = append(queueDepths, sample)
+		}
+	}
+	err := writeGauge(w, "log_writer_queue_depth", "Blocks waiting to be written to disk per writer.", queueLabels, queueDepths)
+	if err != nil {
+		return err
+	}
+	return writeGauge(w, "log_cache_messages", "Messages held in the cache.",
+		nil, []gaugeSample{gaugeSample{value: float64(s.WriterCollection.getCache().messageCount())}})
+}
+
+func (s *Server) handleServicesGet(w http.ResponseWriter, r *http.Request) {
+	response := &GetServicesResponse{
+		Services: requestKey(r).filterServiceInfos(s.Reader.GetServiceInfos()),
+	}
+	bytes, err := proto.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Write(bytes)
+}
+
+func (s *Server) handleUsageGet(w http.ResponseWriter, r *http.Request) {
+	response := &GetUsageResponse{}
+	if s.RateLimits != nil {
+		key := requestKey(r)
+		for _, usage := range s.RateLimits.Usage() {
+			if key.allowsService(usage.Service) {
+				response.Services = append(response.Services, usage)
+			}
+		}
+	}
+	bytes, err := proto.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Write(bytes)
+}
+
+func (s *Server) handleLevelsGet(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	if service == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	key := requestKey(r)
+	if !key.allowsService(service) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	response := &GetLevelsResponse{
+		Levels: key.filterLevelInfos(service, s.Reader.GetLevelInfos(service)),
+	}
+	bytes, err := proto.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Write(bytes)
+}
+
+func (s *Server) handleHistogramGet(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	p, err := parseParams(params)
+	if err != nil || p.endTime < p.startTime {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	bucketSeconds, err := parseBucketSeconds(params, p)
+	if err != nil || histogramBucketCount(p.startTime, p.endTime, bucketSeconds) > maxHistogramBuckets {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	key := requestKey(r)
+	if !key.allowsQuery(p.service, p.level) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	response := s.Reader.GetHistogram(p.startTime, p.endTime, bucketSeconds, p.service, p.level)
+	response.Series = key.filterHistogramSeries(response.Series)
+	bytes, err := proto.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Write(bytes)
+}
+
+func (s *Server) handleSearchGet(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	p, err := parseParams(params)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	search := params.Get("search")
+	before, beforeErr := parseContextParam(params, "before")
+	after, afterErr := parseContextParam(params, "after")
+	if search == "" || beforeErr != nil || afterErr != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	key := requestKey(r)
+	if !key.allowsQuery(p.service, p.level) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if acceptsGzip(r) {
+		gzipWriter := newGzipResponseWriter(w)
+		defer gzipWriter.Close()
+		w = gzipWriter
+	}
+
+	response := &SearchResponse{
+		Groups: key.filterSearchGroups(s.Reader.SearchMessagesInTimeRange(p.startTime, p.endTime, p.service, p.level, search, before, after)),
+	}
+	bytes, err := proto.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		w.Write(bytes)
+	}
+
+	// put objects back into their pools
+	for _, group := range response.Groups {
+		for _, message := range group.Messages {
+			pools.CompleteMessages.Put(message)
+		}
+	}
+}
+
+//parseContextParam reads the number of context messages, missing params mean no context
+func parseContextParam(params url.Values, name string) (int, error) {
+	param := params.Get(name)
+	if param == "" {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(param)
+	if err == nil && (count < 0 || count > maxSearchContext) {
+		err = fmt.Errorf("%v has to be between 0 and %v", name, maxSearchContext)
+	}
+	return count, err
+}
+
+//parseBucketSeconds reads the bucket size from bucket_seconds,
+//or divides the timerange into the number of buckets given by buckets (default 60)
+func parseBucketSeconds(params url.Values, p *getParams) (int64, error) {
+	if bucketSecondsParam := params.Get("bucket_seconds"); bucketSecondsParam != "" {
+		bucketSeconds, err := strconv.ParseInt(bucketSecondsParam, 10, 64)
+		if err == nil && bucketSeconds < 1 {
+			err = fmt.Errorf("bucket_seconds has to be positive")
+		}
+		return bucketSeconds, err
+	}
+
+	buckets := int64(60)
+	if bucketsParam := params.Get("buckets"); bucketsParam != "" {
+		var err error
+		if buckets, err = strconv.ParseInt(bucketsParam, 10, 64); err != nil {
+			return 0, err
+		}
+		if buckets < 1 {
+			return 0, fmt.Errorf("buckets has to be positive")
+		}
+	}
+	// round up, so the buckets cover the whole timerange
+	bucketSeconds := (p.endTime - p.startTime + buckets) / buckets
+	if bucketSeconds < 1 {
+		bucketSeconds = 1
+	}
+	return bucketSeconds, nil
+}
+
 func parseParams(params url.Values) (p *getParams, err error) {
 	p = &getParams{}
 	startTimeParam := params.Get("from_time")
@@ -205,5 +704,6 @@ func parseParams(params url.Values) (p *getParams, err error) {
 	}
 	p.service = params.Get("service")
 	p.level = params.Get("level")
+	p.traceID = params.Get("trace_id")
 	return
 }
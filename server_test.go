@@ -2,6 +2,7 @@ package log
 
 import (
 	"bytes"
+	"compress/gzip"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -41,10 +42,11 @@ func TestPostEndpoint(t *testing.T) {
 		}
 		postRequest := &PostRequest{Blocks: []*Block{b, b2}}
 		byteArray, _ := proto.Marshal(postRequest)
-		req := httptest.NewRequest("POST", "/", bytes.NewReader(byteArray))
+		req := httptest.NewRequest("POST", APIPrefix+"/messages", bytes.NewReader(byteArray))
 		resp := httptest.NewRecorder()
 
 		s := NewDefaultServer()
+		defer s.Shutdown()
 		s.ServeHTTP(resp, req)
 		So(resp.Code, ShouldEqual, 200)
 		time.Sleep(10 * time.Millisecond)
@@ -104,11 +106,12 @@ func TestGetEndpoint(t *testing.T) {
 		b3.WriteToFile()
 		Convey("given a service and level gets the correct logs", func() {
 
-			url := "/?from_time=5001&to_time=8008&service=test&level=endpoint"
+			url := APIPrefix + "/messages?from_time=5001&to_time=8008&service=test&level=endpoint"
 			req := httptest.NewRequest("GET", url, bytes.NewReader([]byte{}))
 			resp := httptest.NewRecorder()
 
 			s := NewDefaultServer()
+			defer s.Shutdown()
 			s.ServeHTTP(resp, req)
 
 			So(resp.Code, ShouldEqual, 200)
@@ -123,11 +126,12 @@ func TestGetEndpoint(t *testing.T) {
 		})
 
 		Convey("given only a service gets the correct logs", func() {
-			url := "/?from_time=5001&to_time=8008&service=test"
+			url := APIPrefix + "/messages?from_time=5001&to_time=8008&service=test"
 			req := httptest.NewRequest("GET", url, bytes.NewReader([]byte{}))
 			resp := httptest.NewRecorder()
 
 			s := NewDefaultServer()
+			defer s.Shutdown()
 			s.ServeHTTP(resp, req)
 
 			So(resp.Code, ShouldEqual, 200)
@@ -145,11 +149,12 @@ func TestGetEndpoint(t *testing.T) {
 		})
 
 		Convey("given no service or level gets the correct logs", func() {
-			url := "/?from_time=5001&to_time=8008"
+			url := APIPrefix + "/messages?from_time=5001&to_time=8008"
 			req := httptest.NewRequest("GET", url, bytes.NewReader([]byte{}))
 			resp := httptest.NewRecorder()
 
 			s := NewDefaultServer()
+			defer s.Shutdown()
 			s.ServeHTTP(resp, req)
 
 			So(resp.Code, ShouldEqual, 200)
@@ -173,6 +178,205 @@ func TestGetEndpoint(t *testing.T) {
 	os.RemoveAll(pathPrefix)
 }
 
+func TestGzipEndpoints(t *testing.T) {
+	Convey("Gzip encoded endpoints", t, func() {
+		pathPrefix = "test"
+		b := &Block{
+			StartTime: 5002,
+			EndTime:   7005,
+			Service:   "test",
+			Level:     "gzip",
+			Messages: []*Message{
+				&Message{Text: "Foo", Timestamp: 5002},
+				&Message{Text: "Bar", Timestamp: 7005},
+			},
+		}
+		byteArray, _ := proto.Marshal(&PostRequest{Blocks: []*Block{b}})
+		compressed := &bytes.Buffer{}
+		gzipWriter := gzip.NewWriter(compressed)
+		gzipWriter.Write(byteArray)
+		gzipWriter.Close()
+
+		s := NewDefaultServer()
+		defer s.Shutdown()
+
+		Convey("decompresses post bodies", func() {
+			req := httptest.NewRequest("POST", APIPrefix+"/messages", bytes.NewReader(compressed.Bytes()))
+			req.Header.Set("Content-Encoding", "gzip")
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			So(resp.Code, ShouldEqual, 200)
+			time.Sleep(10 * time.Millisecond)
+
+			Convey("and compresses responses", func() {
+				url := APIPrefix + "/messages?from_time=5001&to_time=8008&service=test&level=gzip"
+				req := httptest.NewRequest("GET", url, bytes.NewReader([]byte{}))
+				req.Header.Set("Accept-Encoding", "deflate, gzip")
+				resp := httptest.NewRecorder()
+				s.ServeHTTP(resp, req)
+
+				So(resp.Code, ShouldEqual, 200)
+				So(resp.Header().Get("Content-Encoding"), ShouldEqual, "gzip")
+				gzipReader, err := gzip.NewReader(resp.Body)
+				So(err, ShouldBeNil)
+				byteArray, _ := ioutil.ReadAll(gzipReader)
+				response := &GetServiceLevelResponse{}
+				So(proto.Unmarshal(byteArray, response), ShouldBeNil)
+				So(len(response.Messages), ShouldEqual, 2)
+			})
+		})
+
+		Convey("rejects post bodies that decompress to more than the limit", func() {
+			s.MaxDecompressedBodySize = int64(len(byteArray) - 1)
+			req := httptest.NewRequest("POST", APIPrefix+"/messages", bytes.NewReader(compressed.Bytes()))
+			req.Header.Set("Content-Encoding", "gzip")
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			So(resp.Code, ShouldEqual, 413)
+		})
+
+		Convey("rejects plain post bodies that are larger than the limit", func() {
+			s.MaxDecompressedBodySize = int64(len(byteArray) - 1)
+			req := httptest.NewRequest("POST", APIPrefix+"/messages", bytes.NewReader(byteArray))
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			So(resp.Code, ShouldEqual, 413)
+		})
+
+		Convey("rejects other encodings than gzip", func() {
+			for _, encoding := range []string{"deflate", "br", "gzip, br"} {
+				req := httptest.NewRequest("POST", APIPrefix+"/messages", bytes.NewReader(compressed.Bytes()))
+				req.Header.Set("Content-Encoding", encoding)
+				resp := httptest.NewRecorder()
+				s.ServeHTTP(resp, req)
+				So(resp.Code, ShouldEqual, 415)
+			}
+		})
+	})
+
+	os.RemoveAll(pathPrefix)
+}
+
+func TestServicesEndpoint(t *testing.T) {
+	Convey("Services Endpoint", t, func() {
+		pathPrefix = "test"
+		blocks := []*Block{
+			&Block{StartTime: 5002, EndTime: 5002, Service: "test2", Level: "endpoint", Messages: []*Message{&Message{Text: "Foo", Timestamp: 5002}}},
+			&Block{StartTime: 5003, EndTime: 5003, Service: "test", Level: "endpoint2", Messages: []*Message{&Message{Text: "Foo", Timestamp: 5003}}},
+			&Block{StartTime: 5004, EndTime: 5006, Service: "test", Level: "endpoint", Messages: []*Message{&Message{Text: "Foo", Timestamp: 5004}, &Message{Text: "Bar", Timestamp: 5006}}},
+		}
+		for _, b := range blocks {
+			b.WriteToFile()
+		}
+		s := NewDefaultServer()
+		defer s.Shutdown()
+
+		Convey("lists the services with their levels and message statistics", func() {
+			req := httptest.NewRequest("GET", APIPrefix+"/services", bytes.NewReader([]byte{}))
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+
+			So(resp.Code, ShouldEqual, 200)
+			byteArray, _ := ioutil.ReadAll(resp.Body)
+			response := &GetServicesResponse{}
+			So(proto.Unmarshal(byteArray, response), ShouldBeNil)
+			So(response.Services, ShouldResemble, []*ServiceInfo{
+				&ServiceInfo{
+					Name: "test",
+					Levels: []*LevelInfo{
+						&LevelInfo{Name: "endpoint", MessageCount: 2, FirstTimestamp: 5004, LastTimestamp: 5006},
+						&LevelInfo{Name: "endpoint2", MessageCount: 1, FirstTimestamp: 5003, LastTimestamp: 5003},
+					},
+					MessageCount:   3,
+					FirstTimestamp: 5003,
+					LastTimestamp:  5006,
+				},
+				&ServiceInfo{
+					Name:           "test2",
+					Levels:         []*LevelInfo{&LevelInfo{Name: "endpoint", MessageCount: 1, FirstTimestamp: 5002, LastTimestamp: 5002}},
+					MessageCount:   1,
+					FirstTimestamp: 5002,
+					LastTimestamp:  5002,
+				},
+			})
+		})
+
+		Convey("lists the levels of a service", func() {
+			req := httptest.NewRequest("GET", APIPrefix+"/levels?service=test", bytes.NewReader([]byte{}))
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+
+			So(resp.Code, ShouldEqual, 200)
+			byteArray, _ := ioutil.ReadAll(resp.Body)
+			response := &GetLevelsResponse{}
+			So(proto.Unmarshal(byteArray, response), ShouldBeNil)
+			So(response.Levels, ShouldResemble, []*LevelInfo{
+				&LevelInfo{Name: "endpoint", MessageCount: 2, FirstTimestamp: 5004, LastTimestamp: 5006},
+				&LevelInfo{Name: "endpoint2", MessageCount: 1, FirstTimestamp: 5003, LastTimestamp: 5003},
+			})
+		})
+
+		Convey("requires a service for the levels", func() {
+			req := httptest.NewRequest("GET", APIPrefix+"/levels", bytes.NewReader([]byte{}))
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			So(resp.Code, ShouldEqual, 400)
+		})
+	})
+
+	os.RemoveAll(pathPrefix)
+}
+
+func TestGetEndpointTrace(t *testing.T) {
+	Convey("Get Endpoint with trace id", t, func() {
+		pathPrefix = "test"
+		b := &Block{
+			StartTime: 5002,
+			EndTime:   7005,
+			Service:   "test",
+			Level:     "endpoint",
+			Messages: []*Message{
+				&Message{Text: "Foo", Timestamp: 5002, Fields: map[string]string{TraceIDField: "abc"}},
+				&Message{Text: "Bar", Timestamp: 7005, Fields: map[string]string{TraceIDField: "def"}},
+			},
+		}
+		b2 := &Block{
+			StartTime: 5003,
+			EndTime:   7006,
+			Service:   "test2",
+			Level:     "endpoint",
+			Messages: []*Message{
+				&Message{Text: "Foo2", Timestamp: 5003},
+				&Message{Text: "Bar2", Timestamp: 7006, Fields: map[string]string{TraceIDField: "abc"}},
+			},
+		}
+		b.WriteToFile()
+		b2.WriteToFile()
+
+		url := APIPrefix + "/messages?from_time=5001&to_time=8008&trace_id=abc"
+		req := httptest.NewRequest("GET", url, bytes.NewReader([]byte{}))
+		resp := httptest.NewRecorder()
+
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		s.ServeHTTP(resp, req)
+
+		So(resp.Code, ShouldEqual, 200)
+
+		byteArray, _ := ioutil.ReadAll(resp.Body)
+		response := &GetResponse{}
+		err := proto.Unmarshal(byteArray, response)
+		So(err, ShouldBeNil)
+		So(len(response.Messages), ShouldEqual, 2)
+		So(response.Messages[0].GetLogMessage().Text, ShouldEqual, "Foo")
+		So(response.Messages[0].Service, ShouldEqual, "test")
+		So(response.Messages[1].GetLogMessage().Text, ShouldEqual, "Bar2")
+		So(response.Messages[1].Service, ShouldEqual, "test2")
+	})
+
+	os.RemoveAll(pathPrefix)
+}
+
 func TestGetEndpointCache(t *testing.T) {
 	Convey("Get Endpoint Caching", t, func() {
 		pathPrefix = "test"
@@ -189,17 +393,18 @@ func TestGetEndpointCache(t *testing.T) {
 		}
 		postRequest := &PostRequest{Blocks: []*Block{b}}
 		byteArray, _ := proto.Marshal(postRequest)
-		req := httptest.NewRequest("POST", "/", bytes.NewReader(byteArray))
+		req := httptest.NewRequest("POST", APIPrefix+"/messages", bytes.NewReader(byteArray))
 		resp := httptest.NewRecorder()
 
 		s := NewDefaultServer()
+		defer s.Shutdown()
 		s.ServeHTTP(resp, req)
 		So(resp.Code, ShouldEqual, 200)
 		time.Sleep(10 * time.Millisecond)
 		// Remove from disk
 		os.RemoveAll(pathPrefix)
 
-		url := "/?from_time=5001&to_time=12000&service=test&level=endpoint"
+		url := APIPrefix + "/messages?from_time=5001&to_time=12000&service=test&level=endpoint"
 		getReq := httptest.NewRequest("GET", url, bytes.NewReader([]byte{}))
 		getResp := httptest.NewRecorder()
 
@@ -224,3 +429,371 @@ func TestGetEndpointCache(t *testing.T) {
 	})
 	os.RemoveAll(pathPrefix)
 }
+
+func TestHistogramEndpoint(t *testing.T) {
+	Convey("Histogram Endpoint", t, func() {
+		pathPrefix = "test"
+		blocks := []*Block{
+			&Block{StartTime: 5002, EndTime: 5025, Service: "test", Level: "error", Messages: []*Message{
+				&Message{Text: "Foo", Timestamp: 5002},
+				&Message{Text: "Bar", Timestamp: 5009},
+				&Message{Text: "Baz", Timestamp: 5025},
+			}},
+			&Block{StartTime: 5011, EndTime: 5011, Service: "test", Level: "standard", Messages: []*Message{&Message{Text: "Foo", Timestamp: 5011}}},
+			&Block{StartTime: 5003, EndTime: 5003, Service: "test2", Level: "standard", Messages: []*Message{&Message{Text: "Foo", Timestamp: 5003}}},
+		}
+		for _, b := range blocks {
+			b.WriteToFile()
+		}
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		get := func(url string) (*GetHistogramResponse, int) {
+			req := httptest.NewRequest("GET", url, bytes.NewReader([]byte{}))
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			byteArray, _ := ioutil.ReadAll(resp.Body)
+			response := &GetHistogramResponse{}
+			So(proto.Unmarshal(byteArray, response), ShouldBeNil)
+			return response, resp.Code
+		}
+
+		Convey("counts the messages per bucket, service and level", func() {
+			response, code := get(APIPrefix + "/histogram?from_time=5001&to_time=5024&bucket_seconds=10")
+			So(code, ShouldEqual, 200)
+			So(response.StartTime, ShouldEqual, 5000)
+			So(response.BucketSeconds, ShouldEqual, 10)
+			So(response.Series, ShouldResemble, []*HistogramSeries{
+				&HistogramSeries{Service: "test", Level: "error", Counts: []int64{2, 0, 0}},
+				&HistogramSeries{Service: "test", Level: "standard", Counts: []int64{0, 1, 0}},
+				&HistogramSeries{Service: "test2", Level: "standard", Counts: []int64{1, 0, 0}},
+			})
+		})
+
+		Convey("restricts the histogram to a service and level", func() {
+			response, code := get(APIPrefix + "/histogram?from_time=5000&to_time=5029&buckets=3&service=test&level=error")
+			So(code, ShouldEqual, 200)
+			So(response.BucketSeconds, ShouldEqual, 10)
+			So(response.Series, ShouldResemble, []*HistogramSeries{
+				&HistogramSeries{Service: "test", Level: "error", Counts: []int64{2, 0, 1}},
+			})
+		})
+
+		Convey("rejects too many buckets", func() {
+			_, code := get(APIPrefix + "/histogram?from_time=0&to_time=100000&bucket_seconds=1")
+			So(code, ShouldEqual, 400)
+			_, code = get(APIPrefix + "/histogram?from_time=5000&to_time=5029&bucket_seconds=0")
+			So(code, ShouldEqual, 400)
+		})
+	})
+
+	os.RemoveAll(pathPrefix)
+}
+
+func TestSearchEndpoint(t *testing.T) {
+	Convey("Search Endpoint", t, func() {
+		pathPrefix = "test"
+		blocks := []*Block{
+			&Block{StartTime: 5001, EndTime: 5009, Service: "test", Level: "standard", Messages: []*Message{
+				&Message{Text: "request 1", Timestamp: 5001},
+				&Message{Text: "request 2", Timestamp: 5002},
+				&Message{Text: "request 3", Timestamp: 5004},
+				&Message{Text: "request 4", Timestamp: 5006},
+				&Message{Text: "request 5", Timestamp: 5007},
+				&Message{Text: "request 6", Timestamp: 5009},
+			}},
+			&Block{StartTime: 5003, EndTime: 5008, Service: "test", Level: "error", Messages: []*Message{
+				&Message{Text: "Request failed", Timestamp: 5003},
+				&Message{Text: "request FAILED again", Timestamp: 5008},
+			}},
+			&Block{StartTime: 5005, EndTime: 5005, Service: "test2", Level: "error", Messages: []*Message{
+				&Message{Text: "failed too", Timestamp: 5005},
+			}},
+		}
+		for _, b := range blocks {
+			b.WriteToFile()
+		}
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		search := func(url string) (*SearchResponse, int) {
+			req := httptest.NewRequest("GET", url, bytes.NewReader([]byte{}))
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			byteArray, _ := ioutil.ReadAll(resp.Body)
+			response := &SearchResponse{}
+			So(proto.Unmarshal(byteArray, response), ShouldBeNil)
+			return response, resp.Code
+		}
+		texts := func(group *SearchGroup) (texts []string) {
+			for _, message := range group.Messages {
+				texts = append(texts, message.Message.Text)
+			}
+			return
+		}
+
+		Convey("groups matches with the messages around them in the service timeline", func() {
+			response, code := search(APIPrefix + "/search?from_time=5000&to_time=5010&service=test&search=failed&before=1&after=1")
+			So(code, ShouldEqual, 200)
+			So(len(response.Groups), ShouldEqual, 2)
+			So(texts(response.Groups[0]), ShouldResemble, []string{"request 2", "Request failed", "request 3"})
+			So(response.Groups[0].Matches, ShouldResemble, []int32{1})
+			So(texts(response.Groups[1]), ShouldResemble, []string{"request 5", "request FAILED again", "request 6"})
+			So(response.Groups[1].Messages[1].Level, ShouldEqual, "error")
+		})
+
+		Convey("merges overlapping context", func() {
+			response, _ := search(APIPrefix + "/search?from_time=5000&to_time=5010&service=test&search=failed&after=3")
+			So(len(response.Groups), ShouldEqual, 1)
+			So(texts(response.Groups[0]), ShouldResemble, []string{"Request failed", "request 3", "request 4", "request 5", "request FAILED again", "request 6"})
+			So(response.Groups[0].Matches, ShouldResemble, []int32{0, 4})
+		})
+
+		Convey("searches all services and restricts matches to a level", func() {
+			response, _ := search(APIPrefix + "/search?from_time=5000&to_time=5010&search=failed")
+			So(len(response.Groups), ShouldEqual, 3)
+			So(response.Groups[1].Messages[0].Service, ShouldEqual, "test2")
+
+			response, _ = search(APIPrefix + "/search?from_time=5000&to_time=5010&service=test&level=standard&search=request%204&before=1")
+			So(len(response.Groups), ShouldEqual, 1)
+			So(texts(response.Groups[0]), ShouldResemble, []string{"request 3", "request 4"})
+		})
+
+		Convey("requires a search", func() {
+			_, code := search(APIPrefix + "/search?from_time=5000&to_time=5010")
+			So(code, ShouldEqual, 400)
+			_, code = search(APIPrefix + "/search?from_time=5000&to_time=5010&search=failed&before=-1")
+			So(code, ShouldEqual, 400)
+		})
+	})
+
+	os.RemoveAll(pathPrefix)
+}
+
+func TestRoutes(t *testing.T) {
+	Convey("Routes", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		serve := func(method, path string, body []byte) int {
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, httptest.NewRequest(method, path, bytes.NewReader(body)))
+			return resp.Code
+		}
+
+		Convey("posts to the root are still accepted", func() {
+			request := &PostRequest{Blocks: []*Block{
+				&Block{StartTime: 5002, EndTime: 5002, Service: "test", Level: "legacy", Messages: []*Message{
+					&Message{Text: "Foo", Timestamp: 5002},
+				}},
+			}}
+			byteArray, _ := proto.Marshal(request)
+			So(serve("POST", "/", byteArray), ShouldEqual, 200)
+		})
+
+		Convey("queries are only answered under the api prefix", func() {
+			So(serve("GET", "/?from_time=5001&to_time=8008", nil), ShouldEqual, 404)
+			So(serve("GET", "/services", nil), ShouldEqual, 404)
+			So(serve("GET", APIPrefix+"/services", nil), ShouldEqual, 200)
+		})
+
+		Convey("unsupported methods are rejected", func() {
+			So(serve("DELETE", APIPrefix+"/messages", nil), ShouldEqual, 405)
+			So(serve("POST", APIPrefix+"/services", nil), ShouldEqual, 405)
+			So(serve("POST", "/healthz", nil), ShouldEqual, 405)
+		})
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	os.RemoveAll(pathPrefix)
+}
+
+func TestAuthentication(t *testing.T) {
+	Convey("Authentication", t, func() {
+		pathPrefix = "test"
+		blocks := []*Block{
+			&Block{StartTime: 5001, EndTime: 5001, Service: "api", Level: "error", Messages: []*Message{&Message{Text: "api error", Timestamp: 5001}}},
+			&Block{StartTime: 5002, EndTime: 5002, Service: "api", Level: "standard", Messages: []*Message{&Message{Text: "api standard", Timestamp: 5002}}},
+			&Block{StartTime: 5003, EndTime: 5003, Service: "db", Level: "error", Messages: []*Message{&Message{Text: "db error", Timestamp: 5003}}},
+		}
+		for _, b := range blocks {
+			b.WriteToFile()
+		}
+		file, _ := ioutil.TempFile("", "api-keys")
+		defer os.Remove(file.Name())
+		ioutil.WriteFile(file.Name(), []byte(`{"keys": [
+			{"name": "all", "token": "all", "scopes": ["read", "write"]},
+			{"name": "api errors", "token": "api-errors", "scopes": ["read", "write"], "services": ["api"], "levels": ["error"]}
+		]}`), 0600)
+		keys, err := LoadAPIKeys(file.Name())
+		So(err, ShouldBeNil)
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		s.APIKeys = keys
+
+		serve := func(method, path, token string, body []byte) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, bytes.NewReader(body))
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			return resp
+		}
+		post := func(token, service, level string) int {
+			request := &PostRequest{Blocks: []*Block{
+				&Block{StartTime: 6000, EndTime: 6000, Service: service, Level: level, Messages: []*Message{&Message{Text: "Foo", Timestamp: 6000}}},
+			}}
+			byteArray, _ := proto.Marshal(request)
+			return serve("POST", APIPrefix+"/messages", token, byteArray).Code
+		}
+
+		Convey("rejects requests without a known token", func() {
+			resp := serve("GET", APIPrefix+"/services", "", nil)
+			So(resp.Code, ShouldEqual, 401)
+			So(resp.Header().Get("WWW-Authenticate"), ShouldStartWith, "Bearer")
+			So(serve("GET", APIPrefix+"/services", "unknown", nil).Code, ShouldEqual, 401)
+			So(post("", "api", "error"), ShouldEqual, 401)
+		})
+
+		Convey("leaves the admin routes open", func() {
+			So(serve("GET", "/healthz", "", nil).Code, ShouldEqual, 200)
+		})
+
+		Convey("only lists the allowed services and levels in the metrics", func() {
+			So(serve("GET", "/metrics", "", nil).Code, ShouldEqual, 401)
+			So(post("all", "db", "error"), ShouldEqual, 200)
+			So(post("all", "api", "error"), ShouldEqual, 200)
+
+			resp := serve("GET", "/metrics", "api-errors", nil)
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Body.String(), ShouldContainSubstring, "log_messages_accepted_total{service=\"api\",level=\"error\"}")
+			So(resp.Body.String(), ShouldNotContainSubstring, "service=\"db\"")
+			So(serve("GET", "/metrics", "all", nil).Body.String(), ShouldContainSubstring, "service=\"db\"")
+
+			time.Sleep(10 * time.Millisecond)
+			os.RemoveAll(pathPrefix)
+		})
+
+		Convey("only accepts posts for the allowed services and levels", func() {
+			So(post("api-errors", "api", "error"), ShouldEqual, 200)
+			So(post("api-errors", "api", "standard"), ShouldEqual, 403)
+			So(post("api-errors", "db", "error"), ShouldEqual, 403)
+			So(post("all", "db", "error"), ShouldEqual, 200)
+
+			// the posted blocks would show up in the statistics of the other conveys
+			time.Sleep(10 * time.Millisecond)
+			os.RemoveAll(pathPrefix)
+		})
+
+		Convey("only answers queries with the allowed services and levels", func() {
+			So(serve("GET", APIPrefix+"/messages?from_time=5000&to_time=5010&service=db", "api-errors", nil).Code, ShouldEqual, 403)
+			So(serve("GET", APIPrefix+"/messages?from_time=5000&to_time=5010&service=api&level=standard", "api-errors", nil).Code, ShouldEqual, 403)
+			So(serve("GET", APIPrefix+"/levels?service=db", "api-errors", nil).Code, ShouldEqual, 403)
+
+			resp := serve("GET", APIPrefix+"/messages?from_time=5000&to_time=5010", "api-errors", nil)
+			So(resp.Code, ShouldEqual, 200)
+			response := &GetResponse{}
+			proto.Unmarshal(resp.Body.Bytes(), response)
+			So(len(response.Messages), ShouldEqual, 1)
+			So(response.Messages[0].Message.Text, ShouldEqual, "api error")
+
+			resp = serve("GET", APIPrefix+"/services", "api-errors", nil)
+			services := &GetServicesResponse{}
+			proto.Unmarshal(resp.Body.Bytes(), services)
+			So(len(services.Services), ShouldEqual, 1)
+			So(services.Services[0].Name, ShouldEqual, "api")
+			So(len(services.Services[0].Levels), ShouldEqual, 1)
+			So(services.Services[0].MessageCount, ShouldEqual, 1)
+
+			resp = serve("GET", APIPrefix+"/messages?from_time=5000&to_time=5010", "all", nil)
+			response = &GetResponse{}
+			proto.Unmarshal(resp.Body.Bytes(), response)
+			So(len(response.Messages), ShouldEqual, 3)
+		})
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	os.RemoveAll(pathPrefix)
+}
+
+func TestRateLimitedPosts(t *testing.T) {
+	Convey("Rate limited posts", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		s.RateLimits.defaultLimit = RateLimit{MessagesPerSecond: 2}
+		post := func() *httptest.ResponseRecorder {
+			request := &PostRequest{Blocks: []*Block{
+				&Block{StartTime: 6000, EndTime: 6001, Service: "limited", Level: "error", Messages: []*Message{
+					&Message{Text: "Foo", Timestamp: 6000},
+					&Message{Text: "Bar", Timestamp: 6001},
+				}},
+			}}
+			byteArray, _ := proto.Marshal(request)
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, httptest.NewRequest("POST", APIPrefix+"/messages", bytes.NewReader(byteArray)))
+			return resp
+		}
+
+		So(post().Code, ShouldEqual, 200)
+		resp := post()
+		So(resp.Code, ShouldEqual, 429)
+		So(resp.Header().Get("Retry-After"), ShouldEqual, "1")
+
+		resp = httptest.NewRecorder()
+		s.ServeHTTP(resp, httptest.NewRequest("GET", APIPrefix+"/usage", nil))
+		So(resp.Code, ShouldEqual, 200)
+		response := &GetUsageResponse{}
+		So(proto.Unmarshal(resp.Body.Bytes(), response), ShouldBeNil)
+		So(len(response.Services), ShouldEqual, 1)
+		So(response.Services[0].MessageCount, ShouldEqual, 2)
+		So(response.Services[0].RejectedRequests, ShouldEqual, 1)
+		So(response.Services[0].MessagesPerSecondLimit, ShouldEqual, 2)
+
+		s.RateLimits.defaultLimit = RateLimit{DailyBytes: 10}
+		resp = post()
+		So(resp.Code, ShouldEqual, 413)
+		So(resp.Header().Get("Retry-After"), ShouldEqual, "")
+
+		resp = httptest.NewRecorder()
+		s.ServeHTTP(resp, httptest.NewRequest("GET", APIPrefix+"/usage", nil))
+		response = &GetUsageResponse{}
+		So(proto.Unmarshal(resp.Body.Bytes(), response), ShouldBeNil)
+		So(response.Services[0].MessageCount, ShouldEqual, 2)
+		So(response.Services[0].RejectedRequests, ShouldEqual, 2)
+		So(metrics.rateLimitedRequests.value("limited"), ShouldBeGreaterThanOrEqualTo, 2)
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	os.RemoveAll(pathPrefix)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	Convey("Metrics Endpoint", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		request := &PostRequest{Blocks: []*Block{
+			&Block{StartTime: 5002, EndTime: 5003, Service: "metrics", Level: "endpoint", Messages: []*Message{
+				&Message{Text: "Foo", Timestamp: 5002},
+				&Message{Text: "Bar", Timestamp: 5003},
+			}},
+		}}
+		byteArray, _ := proto.Marshal(request)
+		resp := httptest.NewRecorder()
+		s.ServeHTTP(resp, httptest.NewRequest("POST", APIPrefix+"/messages", bytes.NewReader(byteArray)))
+		So(resp.Code, ShouldEqual, 200)
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", APIPrefix+"/services", nil))
+
+		resp = httptest.NewRecorder()
+		s.ServeHTTP(resp, httptest.NewRequest("GET", "/metrics", nil))
+		So(resp.Code, ShouldEqual, 200)
+		body := resp.Body.String()
+		So(body, ShouldContainSubstring, "log_messages_accepted_total{service=\"metrics\",level=\"endpoint\"} 2\n")
+		So(body, ShouldContainSubstring, "log_blocks_accepted_total{service=\"metrics\",level=\"endpoint\"} 1\n")
+		So(body, ShouldContainSubstring, "log_query_duration_seconds_count{endpoint=\"services\"}")
+		So(body, ShouldContainSubstring, "# TYPE log_writer_queue_depth gauge\n")
+		So(body, ShouldContainSubstring, "# TYPE log_cache_messages gauge\n")
+	})
+
+	os.RemoveAll(pathPrefix)
+}
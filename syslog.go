@@ -0,0 +1,360 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//defaultSyslogService is the service of syslog messages without an APP-NAME or tag
+	defaultSyslogService = "syslog"
+)
+
+//Field names under which the syslog listeners store the syslog header in Message.Fields,
+//structured data is stored as "SD-ID.PARAM-NAME"
+const (
+	SyslogHostField     = "host"
+	SyslogFacilityField = "facility"
+	SyslogSeverityField = "severity"
+	SyslogProcIDField   = "proc_id"
+	SyslogMsgIDField    = "msg_id"
+)
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var syslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+//syslogMessage is a parsed RFC 5424 or RFC 3164 message, empty strings are missing values
+type syslogMessage struct {
+	facility, severity int
+	timestamp          time.Time
+	hostname           string
+	appName            string
+	procID             string
+	msgID              string
+	structuredData     map[string]string
+	text               string
+}
+
+//ServeSyslogUDP reads a syslog message from every datagram of conn until reading fails
+func (s *Server) ServeSyslogUDP(conn net.PacketConn) error {
+	if !s.listeners.open(conn) {
+		return nil
+	}
+	defer s.listeners.done(conn)
+	buffer := make([]byte, maxListenerMessageSize)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if s.listeners.isClosed() {
+				return nil
+			}
+			return err
+		}
+		s.receiveSyslog("syslog_udp", buffer[:n])
+	}
+}
+
+//ServeSyslogTCP reads syslog messages from the connections of listener until accepting fails.
+//Every message is either octet-counted or terminated by a newline (RFC 6587).
+func (s *Server) ServeSyslogTCP(listener net.Listener) error {
+	return s.acceptConnections(listener, s.serveSyslogConn)
+}
+
+func (s *Server) serveSyslogConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReaderSize(conn, maxListenerMessageSize)
+	for {
+		frame, err := readSyslogFrame(reader)
+		if len(frame) > 0 {
+			s.receiveSyslog("syslog_tcp", frame)
+		}
+		if err != nil {
+			if err != io.EOF && !s.listeners.isClosed() {
+				metrics.listenerInvalidMessages.inc("syslog_tcp")
+			}
+			return
+		}
+	}
+}
+
+//readSyslogFrame reads the next message of the stream, messages that start with a digit are octet-counted
+func readSyslogFrame(reader *bufio.Reader) ([]byte, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		length, err := readOctetCount(reader)
+		if err != nil {
+			return nil, err
+		}
+		frame := make([]byte, length)
+		_, err = io.ReadFull(reader, frame)
+		return frame, err
+	}
+	return readLine(reader)
+}
+
+//maxOctetCountDigits is enough for every count up to maxListenerMessageSize
+const maxOctetCountDigits = 6
+
+//readOctetCount reads the digits up to the space that ends the octet count,
+//without buffering more than maxOctetCountDigits of them
+func readOctetCount(reader *bufio.Reader) (int, error) {
+	length := 0
+	for digits := 0; ; digits++ {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c == ' ' && digits > 0 {
+			break
+		}
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid octet count")
+		}
+		if digits == maxOctetCountDigits {
+			return 0, errMessageTooLarge
+		}
+		length = length*10 + int(c-'0')
+	}
+	if length > maxListenerMessageSize {
+		return 0, errMessageTooLarge
+	}
+	return length, nil
+}
+
+//receiveSyslog parses the message and adds it to the block of its service and level
+func (s *Server) receiveSyslog(listener string, frame []byte) {
+	metrics.listenerMessages.inc(listener)
+	parsed, err := parseSyslog(string(frame), time.Now())
+	if err != nil {
+		metrics.listenerInvalidMessages.inc(listener)
+		return
+	}
+	service, level, message := parsed.message()
+	s.listenerBatcher().add(service, level, message)
+}
+
+//parseSyslog parses RFC 5424 messages and falls back to RFC 3164 for everything else.
+//Missing timestamps are filled in with the time the message was received.
+func parseSyslog(line string, received time.Time) (*syslogMessage, error) {
+	line = strings.TrimRight(line, "\r\n\x00")
+	priority, rest, err := parseSyslogPriority(line)
+	if err != nil {
+		return nil, err
+	}
+	m := &syslogMessage{facility: priority / 8, severity: priority % 8, timestamp: received}
+	if strings.HasPrefix(rest, "1 ") {
+		return m, m.parseRFC5424(rest[len("1 "):])
+	}
+	m.parseRFC3164(rest)
+	return m, nil
+}
+
+//parseSyslogPriority parses the <PRI> at the start of the line, without one the message gets user.notice like RFC 3164 requires
+func parseSyslogPriority(line string) (int, string, error) {
+	if !strings.HasPrefix(line, "<") {
+		return 13, line, nil
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return 0, "", fmt.Errorf("invalid priority in %q", line)
+	}
+	priority, err := strconv.ParseUint(line[1:end], 10, 8)
+	if err != nil || priority > 191 {
+		return 0, "", fmt.Errorf("invalid priority in %q", line)
+	}
+	return int(priority), line[end+1:], nil
+}
+
+//parseRFC5424 parses everything after the version: TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func (m *syslogMessage) parseRFC5424(rest string) error {
+	parts := strings.SplitN(rest, " ", 6)
+	if len(parts) < 6 {
+		return fmt.Errorf("syslog header is incomplete")
+	}
+	if parts[0] != "-" {
+		timestamp, err := time.Parse(time.RFC3339, parts[0])
+		if err != nil {
+			return err
+		}
+		m.timestamp = timestamp
+	}
+	m.hostname = syslogValue(parts[1])
+	m.appName = syslogValue(parts[2])
+	m.procID = syslogValue(parts[3])
+	m.msgID = syslogValue(parts[4])
+
+	data, text, err := parseStructuredData(parts[5])
+	if err != nil {
+		return err
+	}
+	if text != "" && text[0] != ' ' {
+		return fmt.Errorf("structured data is not followed by a space")
+	}
+	m.structuredData = data
+	m.text = strings.TrimPrefix(strings.TrimPrefix(text, " "), "\xef\xbb\xbf")
+	return nil
+}
+
+//parseRFC3164 parses the loosely defined BSD format: TIMESTAMP HOSTNAME TAG: MSG.
+//Every part is optional, what can't be parsed becomes part of the text.
+func (m *syslogMessage) parseRFC3164(rest string) {
+	content := rest
+	if timestamp, after, ok := parseBSDTimestamp(rest, m.timestamp); ok {
+		m.timestamp = timestamp
+		content = after
+		if space := strings.IndexByte(content, ' '); space > 0 && !strings.ContainsAny(content[:space], ":[") {
+			m.hostname = content[:space]
+			content = content[space+1:]
+		}
+	}
+
+	if colon := strings.IndexByte(content, ':'); colon > 0 && colon <= 48 && !strings.Contains(content[:colon], " ") {
+		tag := content[:colon]
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			m.procID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		m.appName = tag
+		content = strings.TrimPrefix(content[colon+1:], " ")
+	}
+	m.text = content
+}
+
+//parseBSDTimestamp parses "Mmm dd hh:mm:ss" in the year and location of received,
+//or the RFC 3339 timestamps that many daemons send instead
+func parseBSDTimestamp(rest string, received time.Time) (time.Time, string, bool) {
+	layout := "Jan _2 15:04:05"
+	if len(rest) > len(layout) && rest[len(layout)] == ' ' {
+		timestamp, err := time.ParseInLocation(layout, rest[:len(layout)], received.Location())
+		if err == nil {
+			timestamp = time.Date(received.Year(), timestamp.Month(), timestamp.Day(),
+				timestamp.Hour(), timestamp.Minute(), timestamp.Second(), 0, received.Location())
+			// messages from the end of december that arrive in january
+			if timestamp.After(received.Add(24 * time.Hour)) {
+				timestamp = timestamp.AddDate(-1, 0, 0)
+			}
+			return timestamp, rest[len(layout)+1:], true
+		}
+	}
+	if space := strings.IndexByte(rest, ' '); space > 0 {
+		if timestamp, err := time.Parse(time.RFC3339, rest[:space]); err == nil {
+			return timestamp, rest[space+1:], true
+		}
+	}
+	return time.Time{}, rest, false
+}
+
+//parseStructuredData parses the SD-ELEMENTs at the start of s and returns what follows them
+func parseStructuredData(s string) (map[string]string, string, error) {
+	if strings.HasPrefix(s, "-") {
+		return nil, s[1:], nil
+	}
+	if !strings.HasPrefix(s, "[") {
+		return nil, "", fmt.Errorf("structured data is missing")
+	}
+	data := map[string]string{}
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		end := strings.IndexAny(s, " ]")
+		if end <= 0 {
+			return nil, "", fmt.Errorf("structured data element without id")
+		}
+		id := s[:end]
+		s = s[end:]
+		for {
+			if s == "" {
+				return nil, "", fmt.Errorf("structured data element %v is not closed", id)
+			}
+			if s[0] == ']' {
+				s = s[1:]
+				break
+			}
+			equals := strings.IndexByte(s, '=')
+			if s[0] != ' ' || equals < 2 || !strings.HasPrefix(s[equals+1:], `"`) {
+				return nil, "", fmt.Errorf("invalid parameter in structured data element %v", id)
+			}
+			name := s[1:equals]
+			value, n, err := parseParamValue(s[equals+2:])
+			if err != nil {
+				return nil, "", fmt.Errorf("parameter %v of structured data element %v: %v", name, id, err)
+			}
+			data[id+"."+name] = value
+			s = s[equals+2+n:]
+		}
+	}
+	return data, s, nil
+}
+
+//parseParamValue reads up to the closing quote of a parameter value and returns the unescaped value and the number of bytes read
+func parseParamValue(s string) (string, int, error) {
+	value := bytes.Buffer{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+			value.WriteByte(s[i+1])
+			i++
+			continue
+		}
+		if c == '"' {
+			return value.String(), i + 1, nil
+		}
+		value.WriteByte(c)
+	}
+	return "", 0, fmt.Errorf("value is not closed")
+}
+
+func syslogValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+//message converts the syslog message, the APP-NAME is the service and the severity is mapped to the levels of the client.
+//Messages without an APP-NAME that is a valid service name get the default service.
+func (m *syslogMessage) message() (service, level string, message *Message) {
+	service = m.appName
+	if !ValidName(service) {
+		service = defaultSyslogService
+	}
+	fields := map[string]string{
+		SyslogFacilityField: syslogFacilities[m.facility],
+		SyslogSeverityField: syslogSeverities[m.severity],
+	}
+	for key, value := range map[string]string{SyslogHostField: m.hostname, SyslogProcIDField: m.procID, SyslogMsgIDField: m.msgID} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	for key, value := range m.structuredData {
+		fields[key] = value
+	}
+	return service, syslogLevel(m.severity), &Message{Text: m.text, Timestamp: m.timestamp.Unix(), Fields: fields}
+}
+
+//syslogLevel maps emerg to err to error, warning to warning and everything else to standard,
+//the severity itself is kept in the fields
+func syslogLevel(severity int) string {
+	switch {
+	case severity <= 3:
+		return "error"
+	case severity == 4:
+		return "warning"
+	default:
+		return "standard"
+	}
+}
@@ -0,0 +1,173 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseSyslog(t *testing.T) {
+	Convey("parseSyslog", t, func() {
+		received := time.Date(2018, 1, 2, 10, 0, 0, 0, time.UTC)
+
+		Convey("parses RFC 5424 messages with structured data", func() {
+			m, err := parseSyslog(`<165>1 2018-01-02T09:59:58.123Z host1 api 42 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication\]"][meta x="y"] `+"\xef\xbb\xbf"+`Started`, received)
+			So(err, ShouldBeNil)
+			service, level, message := m.message()
+			So(service, ShouldEqual, "api")
+			So(level, ShouldEqual, "standard")
+			So(message.Text, ShouldEqual, "Started")
+			So(message.Timestamp, ShouldEqual, received.Add(-2*time.Second).Unix())
+			So(message.Fields, ShouldResemble, map[string]string{
+				SyslogFacilityField:             "local4",
+				SyslogSeverityField:             "notice",
+				SyslogHostField:                 "host1",
+				SyslogProcIDField:               "42",
+				SyslogMsgIDField:                "ID47",
+				"exampleSDID@32473.iut":         "3",
+				"exampleSDID@32473.eventSource": `App"lication]`,
+				"meta.x":                        "y",
+			})
+		})
+
+		Convey("fills in nil values", func() {
+			m, err := parseSyslog("<11>1 - - - - - -", received)
+			So(err, ShouldBeNil)
+			service, level, message := m.message()
+			So(service, ShouldEqual, defaultSyslogService)
+			So(level, ShouldEqual, "error")
+			So(message.Text, ShouldEqual, "")
+			So(message.Timestamp, ShouldEqual, received.Unix())
+			So(message.Fields, ShouldResemble, map[string]string{SyslogFacilityField: "user", SyslogSeverityField: "err"})
+		})
+
+		Convey("rejects invalid RFC 5424 messages", func() {
+			for _, line := range []string{
+				"<999>1 - - - - - -",
+				"<13>1 - host app",
+				"<13>1 yesterday host app - - - Foo",
+				"<13>1 - host app - - [id a=\"b\" Foo",
+				"<13>1 - host app - - [id a=b] Foo",
+				"<13>1 - host app - - Foo",
+			} {
+				_, err := parseSyslog(line, received)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("parses RFC 3164 messages", func() {
+			m, err := parseSyslog("<28>Jan  2 09:30:00 router sshd[123]: Failed password\n", received)
+			So(err, ShouldBeNil)
+			service, level, message := m.message()
+			So(service, ShouldEqual, "sshd")
+			So(level, ShouldEqual, "warning")
+			So(message.Text, ShouldEqual, "Failed password")
+			So(message.Timestamp, ShouldEqual, received.Add(-30*time.Minute).Unix())
+			So(message.Fields[SyslogHostField], ShouldEqual, "router")
+			So(message.Fields[SyslogProcIDField], ShouldEqual, "123")
+			So(message.Fields[SyslogFacilityField], ShouldEqual, "daemon")
+		})
+
+		Convey("puts RFC 3164 timestamps from december into the previous year", func() {
+			m, _ := parseSyslog("<13>Dec 31 23:59:59 cron: Foo", received)
+			So(m.timestamp, ShouldResemble, time.Date(2017, 12, 31, 23, 59, 59, 0, time.UTC))
+			So(m.hostname, ShouldEqual, "")
+			So(m.appName, ShouldEqual, "cron")
+		})
+
+		Convey("keeps everything it can't parse as text", func() {
+			m, err := parseSyslog("just some text: with a colon", received)
+			So(err, ShouldBeNil)
+			service, level, message := m.message()
+			So(service, ShouldEqual, defaultSyslogService)
+			So(level, ShouldEqual, "standard")
+			So(message.Text, ShouldEqual, "just some text: with a colon")
+			So(message.Timestamp, ShouldEqual, received.Unix())
+		})
+
+		Convey("doesn't use APP-NAMEs that aren't valid service names", func() {
+			for _, line := range []string{"<13>1 - h ../../tmp/x - - - hi", "<13>1 - h .. - - - hi", "<13>Oct 11 22:14:15 host a/b: hi"} {
+				m, err := parseSyslog(line, received)
+				So(err, ShouldBeNil)
+				service, _, _ := m.message()
+				So(service, ShouldEqual, defaultSyslogService)
+			}
+		})
+	})
+}
+
+func TestReadSyslogFrame(t *testing.T) {
+	Convey("readSyslogFrame", t, func() {
+		reader := bufio.NewReader(strings.NewReader("11 <13>1 Foo\nBar<13>Baz\r\n<13>Last"))
+		frames := []string{}
+		for {
+			frame, err := readSyslogFrame(reader)
+			frames = append(frames, string(frame))
+			if err != nil {
+				break
+			}
+		}
+		So(frames, ShouldResemble, []string{"<13>1 Foo\nB", "ar<13>Baz", "<13>Last"})
+
+		_, err := readSyslogFrame(bufio.NewReader(strings.NewReader(fmt.Sprintf("%v <13>", maxListenerMessageSize+1))))
+		So(err, ShouldEqual, errMessageTooLarge)
+
+		_, err = readSyslogFrame(bufio.NewReader(strings.NewReader("1" + strings.Repeat("0", 100))))
+		So(err, ShouldEqual, errMessageTooLarge)
+		_, err = readSyslogFrame(bufio.NewReader(strings.NewReader("12x <13>")))
+		So(err, ShouldNotBeNil)
+		So(err, ShouldNotEqual, errMessageTooLarge)
+	})
+}
+
+func TestSyslogListeners(t *testing.T) {
+	Convey("Syslog listeners", t, func() {
+		pathPrefix = "test"
+		s := NewDefaultServer()
+		s.IngestWindow = 10 * time.Millisecond
+
+		udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer udpConn.Close()
+		go s.ServeSyslogUDP(udpConn)
+		tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer tcpListener.Close()
+		go s.ServeSyslogTCP(tcpListener)
+
+		udp, err := net.Dial("udp", udpConn.LocalAddr().String())
+		So(err, ShouldBeNil)
+		fmt.Fprint(udp, "<14>1 1970-01-01T00:16:40Z host app - - - Over UDP")
+		udp.Close()
+
+		tcp, err := net.Dial("tcp", tcpListener.Addr().String())
+		So(err, ShouldBeNil)
+		fmt.Fprint(tcp, "<14>1 1970-01-01T00:16:41Z host app - - - Newline\n")
+		counted := "<14>1 1970-01-01T00:16:39Z host app - - - Counted\nover two lines"
+		fmt.Fprintf(tcp, "%v %v", len(counted), counted)
+		fmt.Fprint(tcp, "<11>1 1970-01-01T00:16:41Z host app - - - Broken\n")
+		tcp.Close()
+
+		time.Sleep(100 * time.Millisecond)
+		s.Shutdown()
+
+		messages := s.Reader.GetServiceLevelMessagesInTimeRange(0, 2000, "app", "standard")
+		texts := []string{}
+		for _, message := range messages {
+			texts = append(texts, message.Message.Text)
+		}
+		So(texts, ShouldContain, "Over UDP")
+		So(texts, ShouldContain, "Newline")
+		So(texts, ShouldContain, "Counted\nover two lines")
+		errors := s.Reader.GetServiceLevelMessagesInTimeRange(0, 2000, "app", "error")
+		So(len(errors), ShouldEqual, 1)
+		So(errors[0].Message.Text, ShouldEqual, "Broken")
+	})
+	os.RemoveAll(pathPrefix)
+}
@@ -0,0 +1,72 @@
+package log
+
+import "sync"
+
+//tailBufferSize is the number of blocks a tail may fall behind before it is dropped
+const tailBufferSize = 100
+
+//tailHub hands the blocks that the server accepts to the running tails, the zero value is ready to use
+type tailHub struct {
+	mutex sync.Mutex
+	tails map[*tail]bool
+}
+
+//tail receives the accepted blocks of a service and level, empty ones match every service or level
+type tail struct {
+	service, level string
+	key            *APIKey
+	blocks         chan *Block
+	// closed once the tail fell behind and was removed from the hub
+	dropped chan struct{}
+}
+
+func (h *tailHub) subscribe(service, level string, key *APIKey) *tail {
+	t := &tail{
+		service: service,
+		level:   level,
+		key:     key,
+		blocks:  make(chan *Block, tailBufferSize),
+		dropped: make(chan struct{}),
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.tails == nil {
+		h.tails = map[*tail]bool{}
+	}
+	h.tails[t] = true
+	return t
+}
+
+func (h *tailHub) unsubscribe(t *tail) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.tails, t)
+}
+
+//publish never blocks, tails that can't keep up are dropped instead of slowing down the posts
+func (h *tailHub) publish(blocks []*Block) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for t := range h.tails {
+		for _, block := range blocks {
+			if !t.matches(block) {
+				continue
+			}
+			select {
+			case t.blocks <- block:
+			default:
+				delete(h.tails, t)
+				close(t.dropped)
+			}
+			if !h.tails[t] {
+				break
+			}
+		}
+	}
+}
+
+func (t *tail) matches(block *Block) bool {
+	return (t.service == "" || t.service == block.Service) &&
+		(t.level == "" || t.level == block.Level) &&
+		t.key.allows(block.Service, block.Level)
+}
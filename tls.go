@@ -0,0 +1,194 @@
+package log
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+//KeyPair is a certificate with its key, both are read again once their files change,
+//so renewed certificates are used without a restart
+type KeyPair struct {
+	certFile, keyFile string
+	mutex             sync.Mutex
+	files             *watchedFiles
+	certificate       *tls.Certificate
+}
+
+//LoadKeyPair reads the PEM encoded certificate and key
+func LoadKeyPair(certFile, keyFile string) (*KeyPair, error) {
+	p := &KeyPair{certFile: certFile, keyFile: keyFile, files: newWatchedFiles(certFile, keyFile)}
+	if _, err := p.Certificate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//Certificate returns the current certificate, if the changed files can't be read the previous one is kept
+func (p *KeyPair) Certificate() (*tls.Certificate, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if changed := p.files.changed(); !changed && p.certificate != nil {
+		return p.certificate, nil
+	}
+	certificate, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+	if err != nil {
+		if p.certificate == nil {
+			return nil, err
+		}
+		fmt.Println("keeping the previous certificate:", err)
+		return p.certificate, nil
+	}
+	p.certificate = &certificate
+	return p.certificate, nil
+}
+
+//LoadCertPool reads the PEM encoded CA certificates in the file
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bytes) {
+		return nil, fmt.Errorf("%v contains no PEM encoded certificates", file)
+	}
+	return pool, nil
+}
+
+//ClientTLSConfig trusts the CAs in caFile (the system CAs if it is empty)
+//and presents the client certificate of certFile and keyFile if they are given
+func ClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := LoadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		keyPair, err := LoadKeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return keyPair.Certificate()
+		}
+	}
+	return config, nil
+}
+
+//ClientTLSTransport is an http transport that uses the ClientTLSConfig,
+//without any of the files it is nil so the default transport is used
+func ClientTLSTransport(caFile, certFile, keyFile string) (http.RoundTripper, error) {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	config, err := ClientTLSConfig(caFile, certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     config,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}, nil
+}
+
+//serverTLSConfig serves the certificate of the config and verifies client certificates against the client CAs if they are given,
+//the files are read again once they change
+func (c ServerConfig) serverTLSConfig() (*tls.Config, error) {
+	keyPair, err := LoadKeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	var clientCAs *certPoolFile
+	if c.ClientCAFile != "" {
+		if clientCAs, err = loadCertPoolFile(c.ClientCAFile); err != nil {
+			return nil, err
+		}
+	}
+
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return keyPair.Certificate()
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: getCertificate,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: getCertificate}
+			if clientCAs != nil {
+				// probes of the admin routes don't have certificates, the data routes reject requests without one
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+				config.ClientCAs = clientCAs.pool()
+			}
+			return config, nil
+		},
+	}, nil
+}
+
+//certPoolFile is a CA file that is read again once it changes
+type certPoolFile struct {
+	file    string
+	mutex   sync.Mutex
+	files   *watchedFiles
+	current *x509.CertPool
+}
+
+func loadCertPoolFile(file string) (*certPoolFile, error) {
+	f := &certPoolFile{file: file, files: newWatchedFiles(file)}
+	f.files.changed()
+	pool, err := LoadCertPool(file)
+	if err != nil {
+		return nil, err
+	}
+	f.current = pool
+	return f, nil
+}
+
+func (f *certPoolFile) pool() *x509.CertPool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.files.changed() {
+		pool, err := LoadCertPool(f.file)
+		if err != nil {
+			fmt.Println("keeping the previous client CAs:", err)
+		} else {
+			f.current = pool
+		}
+	}
+	return f.current
+}
+
+//watchedFiles remembers the modification times of files to notice when they change
+type watchedFiles struct {
+	paths    []string
+	modTimes []time.Time
+}
+
+func newWatchedFiles(paths ...string) *watchedFiles {
+	return &watchedFiles{paths: paths, modTimes: make([]time.Time, len(paths))}
+}
+
+//changed reports if any of the files was modified since the last call, it is true on the first call.
+//Files that can't be read count as unchanged, so a file that is being replaced doesn't discard the current one.
+func (f *watchedFiles) changed() bool {
+	changed := false
+	for i, path := range f.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(f.modTimes[i]) {
+			f.modTimes[i] = info.ModTime()
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,156 @@
+package log
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+//testCertificate creates a certificate signed by parent (self signed without one) and writes it with its key into dir
+func testCertificate(dir, name, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	ioutil.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	certificate, _ := x509.ParseCertificate(der)
+	return certificate, key
+}
+
+func TestKeyPair(t *testing.T) {
+	Convey("KeyPair", t, func() {
+		dir, _ := ioutil.TempDir("", "certificates")
+		defer os.RemoveAll(dir)
+		certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+
+		_, err := LoadKeyPair(certFile, keyFile)
+		So(err, ShouldNotBeNil)
+
+		testCertificate(dir, "server", "first", nil, nil)
+		keyPair, err := LoadKeyPair(certFile, keyFile)
+		So(err, ShouldBeNil)
+		first, _ := keyPair.Certificate()
+
+		Convey("reloads changed files", func() {
+			testCertificate(dir, "server", "second", nil, nil)
+			later := time.Now().Add(time.Minute)
+			os.Chtimes(certFile, later, later)
+			second, err := keyPair.Certificate()
+			So(err, ShouldBeNil)
+			So(second, ShouldNotEqual, first)
+			parsed, _ := x509.ParseCertificate(second.Certificate[0])
+			So(parsed.Subject.CommonName, ShouldEqual, "second")
+		})
+
+		Convey("keeps the certificate while the files are broken", func() {
+			ioutil.WriteFile(certFile, []byte("broken"), 0600)
+			later := time.Now().Add(time.Minute)
+			os.Chtimes(certFile, later, later)
+			current, err := keyPair.Certificate()
+			So(err, ShouldBeNil)
+			So(current, ShouldEqual, first)
+		})
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	Convey("Mutual TLS", t, func() {
+		pathPrefix = "test"
+		dir, _ := ioutil.TempDir("", "certificates")
+		defer os.RemoveAll(dir)
+		ca, caKey := testCertificate(dir, "ca", "log test ca", nil, nil)
+		testCertificate(dir, "server", "localhost", ca, caKey)
+		testCertificate(dir, "api", "api", ca, caKey)
+		path := func(name string) string { return filepath.Join(dir, name) }
+
+		config := ServerConfig{CertFile: path("server.crt"), KeyFile: path("server.key"), ClientCAFile: path("ca.crt")}
+		tlsConfig, err := config.serverTLSConfig()
+		So(err, ShouldBeNil)
+		s := NewDefaultServer()
+		defer s.Shutdown()
+		s.RequireClientCertificates = true
+		server := httptest.NewUnstartedServer(s)
+		server.TLS = tlsConfig
+		server.StartTLS()
+		defer server.Close()
+
+		newClient := func(certFile, keyFile string) *http.Client {
+			transport, err := ClientTLSTransport(path("ca.crt"), certFile, keyFile)
+			So(err, ShouldBeNil)
+			return &http.Client{Transport: transport}
+		}
+		post := func(client *http.Client, service string) int {
+			request := &PostRequest{Blocks: []*Block{
+				&Block{StartTime: 6000, EndTime: 6000, Service: service, Level: "error", Messages: []*Message{&Message{Text: "Foo", Timestamp: 6000}}},
+			}}
+			byteArray, _ := proto.Marshal(request)
+			resp, err := client.Post(server.URL+APIPrefix+"/messages", "application/proto", bytes.NewReader(byteArray))
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		Convey("only lets certificates access the service of their common name", func() {
+			client := newClient(path("api.crt"), path("api.key"))
+			So(post(client, "api"), ShouldEqual, 200)
+			So(post(client, "db"), ShouldEqual, 403)
+		})
+
+		Convey("rejects data requests without a certificate", func() {
+			client := newClient("", "")
+			So(post(client, "api"), ShouldEqual, 401)
+
+			resp, err := client.Get(server.URL + "/healthz")
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, 200)
+		})
+
+		Convey("rejects certificates of other CAs", func() {
+			otherDir, _ := ioutil.TempDir("", "certificates")
+			defer os.RemoveAll(otherDir)
+			otherCA, otherKey := testCertificate(otherDir, "ca", "other ca", nil, nil)
+			testCertificate(otherDir, "api", "api", otherCA, otherKey)
+			client := newClient(filepath.Join(otherDir, "api.crt"), filepath.Join(otherDir, "api.key"))
+
+			request := &PostRequest{}
+			byteArray, _ := proto.Marshal(request)
+			_, err := client.Post(server.URL+APIPrefix+"/messages", "application/proto", bytes.NewReader(byteArray))
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	os.RemoveAll(pathPrefix)
+}
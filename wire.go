@@ -0,0 +1,152 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+//protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("protobuf message is truncated")
+
+//maxWireDepth limits how deep messages may be embedded, the decoders recurse for every level
+const maxWireDepth = 64
+
+var errTooDeep = fmt.Errorf("protobuf messages are nested deeper than %v levels", maxWireDepth)
+
+//wireReader reads the fields of protobuf messages of other protocols without generated code for them,
+//the callers only pick the fields they need and skip everything else
+type wireReader struct {
+	data []byte
+	// depth of the message, 0 for the outermost one
+	depth int
+}
+
+func (r *wireReader) done() bool {
+	return len(r.data) == 0
+}
+
+//next reads the key of the next field
+func (r *wireReader) next() (field uint64, wireType int, err error) {
+	key, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return key >> 3, int(key & 7), nil
+}
+
+func (r *wireReader) varint() (uint64, error) {
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.data = r.data[n:]
+	return value, nil
+}
+
+func (r *wireReader) fixed64() (uint64, error) {
+	if len(r.data) < 8 {
+		return 0, errTruncated
+	}
+	value := binary.LittleEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return value, nil
+}
+
+func (r *wireReader) fixed32() (uint32, error) {
+	if len(r.data) < 4 {
+		return 0, errTruncated
+	}
+	value := binary.LittleEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return value, nil
+}
+
+func (r *wireReader) double() (float64, error) {
+	bits, err := r.fixed64()
+	return math.Float64frombits(bits), err
+}
+
+//bytes reads a length delimited field, the returned slice shares the memory of the message
+func (r *wireReader) bytes() ([]byte, error) {
+	length, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.data)) {
+		return nil, errTruncated
+	}
+	value := r.data[:length]
+	r.data = r.data[length:]
+	return value, nil
+}
+
+func (r *wireReader) string() (string, error) {
+	value, err := r.bytes()
+	return string(value), err
+}
+
+//message reads a length delimited field as a reader of the embedded message
+func (r *wireReader) message() (*wireReader, error) {
+	if r.depth >= maxWireDepth {
+		return nil, errTooDeep
+	}
+	value, err := r.bytes()
+	return &wireReader{data: value, depth: r.depth + 1}, err
+}
+
+//skip reads over a field that the caller doesn't need
+func (r *wireReader) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		_, err = r.fixed32()
+	default:
+		err = fmt.Errorf("unsupported protobuf wire type %v", wireType)
+	}
+	return err
+}
+
+//decodeFields hands the fields of the message to decodeField,
+//which reads the fields it knows and returns false for the ones to skip
+func decodeFields(r *wireReader, decodeField func(r *wireReader, field uint64, wireType int) (bool, error)) error {
+	for !r.done() {
+		field, wireType, err := r.next()
+		if err != nil {
+			return err
+		}
+		decoded, err := decodeField(r, field, wireType)
+		if err != nil {
+			return err
+		}
+		if !decoded {
+			if err := r.skip(wireType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//decodeEmbeddedMessage reads an embedded message and decodes its fields like decodeFields
+func decodeEmbeddedMessage(r *wireReader, decodeField func(r *wireReader, field uint64, wireType int) (bool, error)) error {
+	message, err := r.message()
+	if err != nil {
+		return err
+	}
+	return decodeFields(message, decodeField)
+}
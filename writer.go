@@ -2,6 +2,7 @@ package log
 
 import (
 	"fmt"
+	"time"
 )
 
 // Writer is responsible for writing blocks to disk for one service and level combination
@@ -11,6 +12,7 @@ type Writer struct {
 	cache           *Cache
 	InChannel       chan *Block
 	shutdownChannel chan struct{}
+	done            chan struct{}
 }
 
 // NewWriter creates a newWriter
@@ -21,6 +23,7 @@ func NewWriter(service, level string, cache *Cache) *Writer {
 		cache:           cache,
 		InChannel:       make(chan *Block, 1),
 		shutdownChannel: make(chan struct{}, 1),
+		done:            make(chan struct{}),
 	}
 	w.Run()
 	return w
@@ -36,24 +39,37 @@ func (w *Writer) Run() {
 	go w.listen()
 }
 
-//Shutdown the writer
+//Shutdown the writer once it has written the blocks that are already queued
 func (w *Writer) Shutdown() {
 	w.shutdownChannel <- struct{}{}
+	<-w.done
 }
 
 func (w *Writer) listen() {
+	defer close(w.done)
 	for {
 		select {
 		case block := <-w.InChannel:
 			w.handleNewBlock(block)
+		case <-w.shutdownChannel:
+			for {
+				select {
+				case block := <-w.InChannel:
+					w.handleNewBlock(block)
+				default:
+					return
+				}
+			}
 		}
-
 	}
 }
 
 func (w *Writer) handleNewBlock(block *Block) {
+	start := time.Now()
 	err := block.WriteToFile()
+	metrics.blockWriteDuration.observeSince(start)
 	if err != nil {
+		metrics.writeErrors.inc(w.Service, w.Level)
 		fmt.Println(err)
 	} else {
 		w.cache.AddBlock(block)
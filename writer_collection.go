@@ -1,6 +1,7 @@
 package log
 
 import (
+	"sort"
 	"sync"
 )
 
@@ -53,6 +54,22 @@ func (c *WriterCollection) Shutdown() {
 	}
 }
 
+//queueDepths returns the number of blocks waiting in the InChannel of every writer
+func (c *WriterCollection) queueDepths() (samples []gaugeSample) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, writer := range c.writers {
+		samples = append(samples, gaugeSample{
+			labels: []string{writer.Service, writer.Level},
+			value:  float64(len(writer.InChannel)),
+		})
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return WriterKeyFor(samples[i].labels[0], samples[i].labels[1]) < WriterKeyFor(samples[j].labels[0], samples[j].labels[1])
+	})
+	return
+}
+
 func (c *WriterCollection) getCache() *Cache {
 	return c.cache
 }